@@ -53,7 +53,7 @@ to assist developers in writing, debugging, and understanding code directly from
 			return nil
 		}
 		if cmd.Flag("version").Changed {
-			fmt.Println(version.Version)
+			fmt.Println(version.Info())
 			return nil
 		}
 
@@ -210,7 +210,7 @@ func setupSubscriber[T any](
 	ctx context.Context,
 	wg *sync.WaitGroup,
 	name string,
-	subscriber func(context.Context) <-chan pubsub.Event[T],
+	subscriber func(context.Context, ...pubsub.SubscribeOption) <-chan pubsub.Event[T],
 	outputCh chan<- tea.Msg,
 ) {
 	wg.Add(1)
@@ -255,6 +255,9 @@ func setupSubscriptions(app *app.App, parentCtx context.Context) (chan tea.Msg,
 	setupSubscriber(ctx, &wg, "logging", logging.Subscribe, ch)
 	setupSubscriber(ctx, &wg, "sessions", app.Sessions.Subscribe, ch)
 	setupSubscriber(ctx, &wg, "messages", app.Messages.Subscribe, ch)
+	// deltas are best effort: one dropped by a slow UI leaves the streamed
+	// text behind until the agent's next periodic save of the message
+	setupSubscriber(ctx, &wg, "messageDeltas", app.Messages.SubscribeDeltas, ch)
 	setupSubscriber(ctx, &wg, "permissions", app.Permissions.Subscribe, ch)
 	setupSubscriber(ctx, &wg, "coderAgent", app.CoderAgent.Subscribe, ch)
 
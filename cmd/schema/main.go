@@ -186,6 +186,22 @@ func generateSchema() map[string]any {
 					"description": "Whether the provider is disabled",
 					"default":     false,
 				},
+				"timeout": map[string]any{
+					"type":        "string",
+					"description": "How long to wait on a request, retries included, as a duration such as 90s or 5m (0s for no limit)",
+					"default":     config.DefaultProviderTimeout.String(),
+				},
+				"maxRetries": map[string]any{
+					"type":        "integer",
+					"description": "How many times a rate limited or failed request is retried (0 for no retries)",
+					"default":     config.DefaultProviderMaxRetries,
+					"minimum":     0,
+				},
+				"disableAfterFailures": map[string]any{
+					"type":        "integer",
+					"description": "Disable the provider for the rest of the run after this many consecutive failed requests, 0 to never disable it",
+					"minimum":     0,
+				},
 			},
 		},
 	}
@@ -232,6 +248,13 @@ func generateSchema() map[string]any {
 					"description": "Reasoning effort for models that support it (OpenAI, Anthropic)",
 					"enum":        []string{"low", "medium", "high"},
 				},
+				"contextPaths": map[string]any{
+					"type":        "array",
+					"description": "Context paths for this agent, replacing the global contextPaths when set",
+					"items": map[string]any{
+						"type": "string",
+					},
+				},
 			},
 			"required": []string{"model"},
 		},
@@ -271,6 +294,110 @@ func generateSchema() map[string]any {
 		"agent": agentSchema["additionalProperties"],
 	}
 
+	schema["properties"].(map[string]any)["autoCompactThreshold"] = map[string]any{
+		"type":             "number",
+		"description":      "Fraction of the model's context window a session may fill before it is compacted",
+		"default":          0.8,
+		"exclusiveMinimum": 0,
+		"maximum":          1,
+	}
+
+	schema["properties"].(map[string]any)["maxConcurrentRequests"] = map[string]any{
+		"type":        "integer",
+		"description": "Maximum number of requests to LLM providers in flight at once across all sessions (0 for no limit)",
+		"default":     0,
+		"minimum":     0,
+	}
+
+	schema["properties"].(map[string]any)["version"] = map[string]any{
+		"type":        "integer",
+		"description": "Schema version of the config file; older files are migrated when loaded",
+		"default":     config.CurrentConfigVersion,
+		"minimum":     0,
+	}
+
+	schema["properties"].(map[string]any)["memoryFile"] = map[string]any{
+		"type":        "string",
+		"description": "Writable file where project-specific facts are remembered across sessions",
+	}
+
+	// Add task routing
+	schema["properties"].(map[string]any)["taskRouting"] = map[string]any{
+		"type":        "object",
+		"description": "Maps task categories (e.g. refactor, explain, fix) to the agent that handles them",
+		"additionalProperties": map[string]any{
+			"type":        "string",
+			"description": "Agent name",
+		},
+	}
+
+	// Add permission policy
+	schema["properties"].(map[string]any)["permissions"] = map[string]any{
+		"type":        "object",
+		"description": "Default permission policy applied to every session",
+		"properties": map[string]any{
+			"autoApproveTools": map[string]any{
+				"type":        "array",
+				"description": "Tools that are approved without prompting",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+			"deny": map[string]any{
+				"type":        "array",
+				"description": "Rules that always reject matching requests",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"tool": map[string]any{
+							"type":        "string",
+							"description": "Tool name to deny",
+						},
+						"path": map[string]any{
+							"type":        "string",
+							"description": "Directory or glob pattern to deny",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// Add session templates
+	schema["properties"].(map[string]any)["templates"] = map[string]any{
+		"type":        "object",
+		"description": "Named templates that seed new sessions with predefined messages",
+		"additionalProperties": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"title": map[string]any{
+					"type":        "string",
+					"description": "Title of sessions created from the template",
+				},
+				"messages": map[string]any{
+					"type":        "array",
+					"description": "Messages seeded into the session",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"role": map[string]any{
+								"type":        "string",
+								"description": "Message role",
+								"enum":        []string{"user", "assistant"},
+							},
+							"content": map[string]any{
+								"type":        "string",
+								"description": "Message text",
+							},
+						},
+						"required": []string{"role", "content"},
+					},
+				},
+			},
+			"required": []string{"messages"},
+		},
+	}
+
 	// Add LSP configuration
 	schema["properties"].(map[string]any)["lsp"] = map[string]any{
 		"type":        "object",
@@ -14,9 +14,12 @@ func (app *App) initLSPClients(ctx context.Context) {
 	cfg := config.Get()
 
 	// Initialize LSP clients
-	for name, clientConfig := range cfg.LSP {
+	for _, server := range cfg.LSPServers() {
+		if server.Disabled {
+			continue
+		}
 		// Start each client initialization in its own goroutine
-		go app.createAndStartLSPClient(ctx, name, clientConfig.Command, clientConfig.Args...)
+		go app.createAndStartLSPClient(ctx, server.Name, server.Command, server.Args...)
 	}
 	logging.Info("LSP clients initialization started in background")
 }
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/logging"
+)
+
+// knownAgents are the agents the application runs, in the order their
+// defaults are filled in.
+var knownAgents = []AgentName{AgentCoder, AgentSummarizer, AgentTask, AgentTitle}
+
+// titleMaxTokens is enough for a short session title.
+const titleMaxTokens = 80
+
+// providerAgentModels are the models each agent uses by default, by provider.
+// They seed the agents when the config is loaded, replace an agent's model
+// that can't be used, and fill in agents missing from the config using the
+// coder agent's provider. Providers not listed reuse the coder's model for
+// every agent.
+var providerAgentModels = map[models.ModelProvider]map[AgentName]models.ModelID{
+	models.ProviderCopilot:    agentModels(models.CopilotGPT4o, models.CopilotGPT4o, models.CopilotGPT4o),
+	models.ProviderAnthropic:  agentModels(models.Claude4Sonnet, models.Claude4Sonnet, models.Claude4Sonnet),
+	models.ProviderOpenAI:     agentModels(models.GPT41, models.GPT41Mini, models.GPT41Mini),
+	models.ProviderGemini:     agentModels(models.Gemini25, models.Gemini25Flash, models.Gemini25Flash),
+	models.ProviderGROQ:       agentModels(models.QWENQwq, models.QWENQwq, models.QWENQwq),
+	models.ProviderOpenRouter: agentModels(models.OpenRouterClaude37Sonnet, models.OpenRouterClaude37Sonnet, models.OpenRouterClaude35Haiku),
+	models.ProviderXAI:        agentModels(models.XAIGrok3Beta, models.XAIGrok3Beta, models.XAiGrok3MiniFastBeta),
+	models.ProviderBedrock:    agentModels(models.BedrockClaude37Sonnet, models.BedrockClaude37Sonnet, models.BedrockClaude37Sonnet),
+	models.ProviderAzure:      agentModels(models.AzureGPT41, models.AzureGPT41Mini, models.AzureGPT41Mini),
+	models.ProviderVertexAI:   agentModels(models.VertexAIGemini25, models.VertexAIGemini25Flash, models.VertexAIGemini25Flash),
+}
+
+// agentModels maps the known agents to models: main serves the coder and
+// summarizer.
+func agentModels(main, task, title models.ModelID) map[AgentName]models.ModelID {
+	return map[AgentName]models.ModelID{
+		AgentCoder:      main,
+		AgentSummarizer: main,
+		AgentTask:       task,
+		AgentTitle:      title,
+	}
+}
+
+// AgentOrDefault returns the configured agent, or when the config has none
+// with a model, a default on the same provider as the coder agent so features
+// like titles and summaries keep working. The zero Agent is returned when
+// there is no coder agent to derive a default from.
+func (c *Config) AgentOrDefault(name AgentName) Agent {
+	if agent, ok := c.Agents[name]; ok && agent.Model != "" {
+		return agent
+	}
+
+	coder := c.Agents[AgentCoder]
+	coderModel, ok := models.SupportedModels[coder.Model]
+	if !ok {
+		return Agent{}
+	}
+	if name == AgentCoder {
+		return coder
+	}
+
+	modelID, ok := providerAgentModels[coderModel.Provider][name]
+	if !ok {
+		modelID = coder.Model
+	}
+
+	agent := Agent{Model: modelID, MaxTokens: MaxTokensFallbackDefault}
+	if name == AgentTitle {
+		agent.MaxTokens = titleMaxTokens
+	} else if model := models.SupportedModels[modelID]; model.DefaultMaxTokens > 0 {
+		agent.MaxTokens = model.DefaultMaxTokens
+	}
+	return agent
+}
+
+// applyDefaultAgents fills in the known agents missing from the config.
+func applyDefaultAgents() {
+	if cfg.Agents == nil {
+		cfg.Agents = make(map[AgentName]Agent)
+	}
+	for _, name := range knownAgents {
+		if agent, ok := cfg.Agents[name]; ok && agent.Model != "" {
+			continue
+		}
+		if agent := cfg.AgentOrDefault(name); agent.Model != "" {
+			logging.Info("agent not configured, using default", "agent", name, "model", agent.Model)
+			cfg.Agents[name] = agent
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentOrDefault(t *testing.T) {
+	original := cfg
+	t.Cleanup(func() { cfg = original })
+
+	cfg = &Config{
+		Agents: map[AgentName]Agent{
+			AgentCoder: {Model: models.GPT41, MaxTokens: 4000},
+			AgentTask:  {Model: models.GPT4o, MaxTokens: 1000},
+		},
+	}
+
+	assert.Equal(t, Agent{Model: models.GPT4o, MaxTokens: 1000}, cfg.AgentOrDefault(AgentTask))
+	assert.Equal(t, Agent{Model: models.GPT41Mini, MaxTokens: titleMaxTokens}, cfg.AgentOrDefault(AgentTitle))
+	summarizer := cfg.AgentOrDefault(AgentSummarizer)
+	assert.Equal(t, models.GPT41, summarizer.Model)
+	assert.Positive(t, summarizer.MaxTokens)
+
+	applyDefaultAgents()
+	assert.Len(t, cfg.Agents, 4)
+	assert.Equal(t, models.GPT4o, cfg.Agents[AgentTask].Model, "configured agents are kept")
+	assert.Equal(t, models.GPT41Mini, cfg.Agents[AgentTitle].Model)
+
+	t.Run("without a coder agent", func(t *testing.T) {
+		c := &Config{Agents: map[AgentName]Agent{}}
+		assert.Equal(t, Agent{}, c.AgentOrDefault(AgentTitle))
+	})
+}
+
+func TestSetDefaultModelForAgent(t *testing.T) {
+	original := cfg
+	t.Cleanup(func() { cfg = original })
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("OPENROUTER_API_KEY", "sk-test")
+
+	cfg = &Config{Agents: map[AgentName]Agent{}}
+	for _, name := range knownAgents {
+		require.True(t, setDefaultModelForAgent(name))
+		assert.Equal(t, providerAgentModels[models.ProviderOpenRouter][name], cfg.Agents[name].Model, "agent %s", name)
+	}
+	assert.Equal(t, int64(titleMaxTokens), cfg.Agents[AgentTitle].MaxTokens)
+}
@@ -8,7 +8,10 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/opencode-ai/opencode/internal/llm/models"
 	"github.com/opencode-ai/opencode/internal/logging"
@@ -48,12 +51,23 @@ type Agent struct {
 	Model           models.ModelID `json:"model"`
 	MaxTokens       int64          `json:"maxTokens"`
 	ReasoningEffort string         `json:"reasoningEffort"` // For openai models low,medium,heigh
+	// ContextPaths replaces the global context paths for this agent when set.
+	ContextPaths []string `json:"contextPaths,omitempty"`
 }
 
 // Provider defines configuration for an LLM provider.
 type Provider struct {
 	APIKey   string `json:"apiKey"`
 	Disabled bool   `json:"disabled"`
+	// Timeout bounds a whole request to the provider, retries included.
+	// Nil uses DefaultProviderTimeout; zero means no limit.
+	Timeout *time.Duration `json:"timeout,omitempty"`
+	// MaxRetries is how many times a rate limited or failed request is
+	// retried. Nil uses DefaultProviderMaxRetries; zero turns retries off.
+	MaxRetries *int `json:"maxRetries,omitempty"`
+	// DisableAfterFailures disables the provider for the rest of the run once
+	// this many requests in a row have failed. Zero never disables it.
+	DisableAfterFailures int `json:"disableAfterFailures,omitempty"`
 }
 
 // Data defines storage configuration.
@@ -78,31 +92,100 @@ type TUIConfig struct {
 type ShellConfig struct {
 	Path string   `json:"path,omitempty"`
 	Args []string `json:"args,omitempty"`
+	// BannedCommands are the commands the bash tool refuses to run.
+	BannedCommands []string `json:"bannedCommands,omitempty"`
+	// AllowedReadOnly are the commands the bash tool runs without asking.
+	AllowedReadOnly []string `json:"allowedReadOnly,omitempty"`
+}
+
+// PermissionsConfig defines the default permission policy seeded into the
+// permission service at startup.
+type PermissionsConfig struct {
+	AutoApproveTools []string             `json:"autoApproveTools,omitempty"`
+	Deny             []PermissionDenyRule `json:"deny,omitempty"`
+}
+
+// PermissionDenyRule blocks a tool, a path, or a tool on a path. Path may be a
+// directory or a glob pattern.
+type PermissionDenyRule struct {
+	Tool string `json:"tool,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+// SessionTemplate seeds a new session with predefined messages, such as
+// shared instructions and project context.
+type SessionTemplate struct {
+	Title    string            `json:"title,omitempty"`
+	Messages []TemplateMessage `json:"messages"`
+}
+
+// TemplateMessage is a message seeded by a session template. Role is either
+// "user" or "assistant".
+type TemplateMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// builtinToolNames lists the tools the agents ship with, sorted. It mirrors
+// the names of the registered tools, which live in packages that depend on
+// config; a test checks the two stay in sync.
+var builtinToolNames = []string{
+	"agent",
+	"bash",
+	"diagnostics",
+	"edit",
+	"fetch",
+	"glob",
+	"grep",
+	"ls",
+	"patch",
+	"sourcegraph",
+	"view",
+	"write",
 }
 
 // Config is the main configuration structure for the application.
 type Config struct {
-	Data         Data                              `json:"data"`
-	WorkingDir   string                            `json:"wd,omitempty"`
-	MCPServers   map[string]MCPServer              `json:"mcpServers,omitempty"`
-	Providers    map[models.ModelProvider]Provider `json:"providers,omitempty"`
-	LSP          map[string]LSPConfig              `json:"lsp,omitempty"`
-	Agents       map[AgentName]Agent               `json:"agents,omitempty"`
-	Debug        bool                              `json:"debug,omitempty"`
-	DebugLSP     bool                              `json:"debugLSP,omitempty"`
-	ContextPaths []string                          `json:"contextPaths,omitempty"`
-	TUI          TUIConfig                         `json:"tui"`
-	Shell        ShellConfig                       `json:"shell,omitempty"`
-	AutoCompact  bool                              `json:"autoCompact,omitempty"`
+	// Version is the schema version of the config file; older files are
+	// migrated to CurrentConfigVersion when they are read.
+	Version              int                               `json:"version,omitempty"`
+	Data                 Data                              `json:"data"`
+	WorkingDir           string                            `json:"wd,omitempty"`
+	MCPServers           map[string]MCPServer              `json:"mcpServers,omitempty"`
+	Providers            map[models.ModelProvider]Provider `json:"providers,omitempty"`
+	LSP                  map[string]LSPConfig              `json:"lsp,omitempty"`
+	Agents               map[AgentName]Agent               `json:"agents,omitempty"`
+	Debug                bool                              `json:"debug,omitempty"`
+	DebugLSP             bool                              `json:"debugLSP,omitempty"`
+	ContextPaths         []string                          `json:"contextPaths,omitempty"`
+	TUI                  TUIConfig                         `json:"tui"`
+	Shell                ShellConfig                       `json:"shell,omitempty"`
+	AutoCompact          bool                              `json:"autoCompact,omitempty"`
+	AutoCompactThreshold float64                           `json:"autoCompactThreshold,omitempty"`
+	TaskRouting          map[string]AgentName              `json:"taskRouting,omitempty"`
+	MemoryFile           string                            `json:"memoryFile,omitempty"`
+	Permissions          PermissionsConfig                 `json:"permissions,omitempty"`
+	Templates            map[string]SessionTemplate        `json:"templates,omitempty"`
+	// MaxConcurrentRequests caps the requests to LLM providers in flight at
+	// once across all sessions; zero means no limit.
+	MaxConcurrentRequests int `json:"maxConcurrentRequests,omitempty"`
+
+	// credentialSources records where each provider's credential was found
+	credentialSources map[models.ModelProvider]string
+	// requestLimiter enforces MaxConcurrentRequests
+	requestLimiter *RequestLimiter
 }
 
 // Application constants
 const (
 	defaultDataDirectory = ".opencode"
+	dataDirEnv           = "OPENCODE_DATA_DIR"
 	defaultLogLevel      = "info"
 	appName              = "opencode"
 
 	MaxTokensFallbackDefault = 4096
+
+	DefaultAutoCompactThreshold = 0.8
 )
 
 var defaultContextPaths = []string{
@@ -140,26 +223,27 @@ func Load(workingDir string, debug bool) (*Config, error) {
 	configureViper()
 	setDefaults(debug)
 
-	// Read global config
-	if err := readConfig(viper.ReadInConfig()); err != nil {
+	// Read the home config and merge the project config over it
+	if err := loadAndMerge(workingDir); err != nil {
 		return cfg, err
 	}
 
-	// Load and merge local config
-	mergeLocalConfig(workingDir)
-
+	// Note where credentials come from before environment keys become defaults
+	cfg.credentialSources = detectCredentialSources()
 	setProviderDefaults()
 
 	// Apply configuration to the struct
 	if err := viper.Unmarshal(cfg); err != nil {
 		return cfg, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	resolveDataDirectory()
+	cfg.requestLimiter = NewRequestLimiter(cfg.MaxConcurrentRequests)
 
-	applyDefaultValues()
 	defaultLevel := slog.LevelInfo
 	if cfg.Debug {
 		defaultLevel = slog.LevelDebug
 	}
+	logging.SetLevel(defaultLevel)
 	if os.Getenv("OPENCODE_DEV_DEBUG") == "true" {
 		loggingFile := fmt.Sprintf("%s/%s", cfg.Data.Directory, "debug.log")
 		messagesPath := fmt.Sprintf("%s/%s", cfg.Data.Directory, "messages")
@@ -186,18 +270,17 @@ func Load(workingDir string, debug bool) (*Config, error) {
 			return cfg, fmt.Errorf("failed to open log file: %w", err)
 		}
 		// Configure logger
-		logger := slog.New(slog.NewTextHandler(sloggingFileWriter, &slog.HandlerOptions{
-			Level: defaultLevel,
-		}))
+		logger := slog.New(slog.NewTextHandler(sloggingFileWriter, logging.HandlerOptions()))
 		slog.SetDefault(logger)
 	} else {
 		// Configure logger
-		logger := slog.New(slog.NewTextHandler(logging.NewWriter(), &slog.HandlerOptions{
-			Level: defaultLevel,
-		}))
+		logger := slog.New(slog.NewTextHandler(logging.NewWriter(), logging.HandlerOptions()))
 		slog.SetDefault(logger)
 	}
 
+	warnCredentialConflicts()
+	applyDefaultValues()
+
 	// Validate configuration
 	if err := Validate(); err != nil {
 		return cfg, fmt.Errorf("config validation failed: %w", err)
@@ -210,7 +293,7 @@ func Load(workingDir string, debug bool) (*Config, error) {
 	// Override the max tokens for title agent
 	cfg.Agents[AgentTitle] = Agent{
 		Model:     cfg.Agents[AgentTitle].Model,
-		MaxTokens: 80,
+		MaxTokens: titleMaxTokens,
 	}
 	return cfg, nil
 }
@@ -226,12 +309,25 @@ func configureViper() {
 	viper.AutomaticEnv()
 }
 
+// resolveDataDirectory applies the OPENCODE_DATA_DIR override, which takes
+// precedence over data.directory in the config files, and makes the data
+// directory absolute, resolving a relative one against the working directory.
+func resolveDataDirectory() {
+	if dir := os.Getenv(dataDirEnv); dir != "" {
+		cfg.Data.Directory = dir
+	}
+	if !filepath.IsAbs(cfg.Data.Directory) {
+		cfg.Data.Directory = filepath.Join(cfg.WorkingDir, cfg.Data.Directory)
+	}
+}
+
 // setDefaults configures default values for configuration options.
 func setDefaults(debug bool) {
 	viper.SetDefault("data.directory", defaultDataDirectory)
 	viper.SetDefault("contextPaths", defaultContextPaths)
 	viper.SetDefault("tui.theme", "opencode")
 	viper.SetDefault("autoCompact", true)
+	viper.SetDefault("autoCompactThreshold", DefaultAutoCompactThreshold)
 
 	// Set default shell from environment or fallback to /bin/bash
 	shellPath := os.Getenv("SHELL")
@@ -240,6 +336,8 @@ func setDefaults(debug bool) {
 	}
 	viper.SetDefault("shell.path", shellPath)
 	viper.SetDefault("shell.args", []string{"-l"})
+	viper.SetDefault("shell.bannedCommands", defaultBannedCommands)
+	viper.SetDefault("shell.allowedReadOnly", defaultReadOnlyCommands)
 
 	if debug {
 		viper.SetDefault("debug", true)
@@ -297,91 +395,61 @@ func setProviderDefaults() {
 
 	// copilot configuration
 	if key := viper.GetString("providers.copilot.apiKey"); strings.TrimSpace(key) != "" {
-		viper.SetDefault("agents.coder.model", models.CopilotGPT4o)
-		viper.SetDefault("agents.summarizer.model", models.CopilotGPT4o)
-		viper.SetDefault("agents.task.model", models.CopilotGPT4o)
-		viper.SetDefault("agents.title.model", models.CopilotGPT4o)
+		setDefaultAgentModels(models.ProviderCopilot)
 		return
 	}
 
 	// Anthropic configuration
 	if key := viper.GetString("providers.anthropic.apiKey"); strings.TrimSpace(key) != "" {
-		viper.SetDefault("agents.coder.model", models.Claude4Sonnet)
-		viper.SetDefault("agents.summarizer.model", models.Claude4Sonnet)
-		viper.SetDefault("agents.task.model", models.Claude4Sonnet)
-		viper.SetDefault("agents.title.model", models.Claude4Sonnet)
+		setDefaultAgentModels(models.ProviderAnthropic)
 		return
 	}
 
 	// OpenAI configuration
 	if key := viper.GetString("providers.openai.apiKey"); strings.TrimSpace(key) != "" {
-		viper.SetDefault("agents.coder.model", models.GPT41)
-		viper.SetDefault("agents.summarizer.model", models.GPT41)
-		viper.SetDefault("agents.task.model", models.GPT41Mini)
-		viper.SetDefault("agents.title.model", models.GPT41Mini)
+		setDefaultAgentModels(models.ProviderOpenAI)
 		return
 	}
 
 	// Google Gemini configuration
 	if key := viper.GetString("providers.gemini.apiKey"); strings.TrimSpace(key) != "" {
-		viper.SetDefault("agents.coder.model", models.Gemini25)
-		viper.SetDefault("agents.summarizer.model", models.Gemini25)
-		viper.SetDefault("agents.task.model", models.Gemini25Flash)
-		viper.SetDefault("agents.title.model", models.Gemini25Flash)
+		setDefaultAgentModels(models.ProviderGemini)
 		return
 	}
 
 	// Groq configuration
 	if key := viper.GetString("providers.groq.apiKey"); strings.TrimSpace(key) != "" {
-		viper.SetDefault("agents.coder.model", models.QWENQwq)
-		viper.SetDefault("agents.summarizer.model", models.QWENQwq)
-		viper.SetDefault("agents.task.model", models.QWENQwq)
-		viper.SetDefault("agents.title.model", models.QWENQwq)
+		setDefaultAgentModels(models.ProviderGROQ)
 		return
 	}
 
 	// OpenRouter configuration
 	if key := viper.GetString("providers.openrouter.apiKey"); strings.TrimSpace(key) != "" {
-		viper.SetDefault("agents.coder.model", models.OpenRouterClaude37Sonnet)
-		viper.SetDefault("agents.summarizer.model", models.OpenRouterClaude37Sonnet)
-		viper.SetDefault("agents.task.model", models.OpenRouterClaude37Sonnet)
-		viper.SetDefault("agents.title.model", models.OpenRouterClaude35Haiku)
+		setDefaultAgentModels(models.ProviderOpenRouter)
 		return
 	}
 
 	// XAI configuration
 	if key := viper.GetString("providers.xai.apiKey"); strings.TrimSpace(key) != "" {
-		viper.SetDefault("agents.coder.model", models.XAIGrok3Beta)
-		viper.SetDefault("agents.summarizer.model", models.XAIGrok3Beta)
-		viper.SetDefault("agents.task.model", models.XAIGrok3Beta)
-		viper.SetDefault("agents.title.model", models.XAiGrok3MiniFastBeta)
+		setDefaultAgentModels(models.ProviderXAI)
 		return
 	}
 
 	// AWS Bedrock configuration
 	if hasAWSCredentials() {
-		viper.SetDefault("agents.coder.model", models.BedrockClaude37Sonnet)
-		viper.SetDefault("agents.summarizer.model", models.BedrockClaude37Sonnet)
-		viper.SetDefault("agents.task.model", models.BedrockClaude37Sonnet)
-		viper.SetDefault("agents.title.model", models.BedrockClaude37Sonnet)
+		setDefaultAgentModels(models.ProviderBedrock)
 		return
 	}
 
 	// Azure OpenAI configuration
 	if os.Getenv("AZURE_OPENAI_ENDPOINT") != "" {
-		viper.SetDefault("agents.coder.model", models.AzureGPT41)
-		viper.SetDefault("agents.summarizer.model", models.AzureGPT41)
-		viper.SetDefault("agents.task.model", models.AzureGPT41Mini)
-		viper.SetDefault("agents.title.model", models.AzureGPT41Mini)
+		setDefaultAgentModels(models.ProviderAzure)
 		return
 	}
 
 	// Google Cloud VertexAI configuration
 	if hasVertexAICredentials() {
-		viper.SetDefault("agents.coder.model", models.VertexAIGemini25)
-		viper.SetDefault("agents.summarizer.model", models.VertexAIGemini25)
-		viper.SetDefault("agents.task.model", models.VertexAIGemini25Flash)
-		viper.SetDefault("agents.title.model", models.VertexAIGemini25Flash)
+		setDefaultAgentModels(models.ProviderVertexAI)
 		return
 	}
 }
@@ -447,17 +515,39 @@ func readConfig(err error) error {
 	return fmt.Errorf("failed to read config: %w", err)
 }
 
+// loadAndMerge reads the home config and then merges the project config in
+// workingDir over it. Project values take precedence key by key: objects such
+// as providers and agents are merged, so a project only needs to set the
+// fields it changes, while scalars and lists are replaced outright. Either
+// file may be missing.
+func loadAndMerge(workingDir string) error {
+	if err := readConfig(readMigratedConfig(viper.GetViper())); err != nil {
+		return err
+	}
+	return mergeLocalConfig(workingDir)
+}
+
 // mergeLocalConfig loads and merges configuration from the local directory.
-func mergeLocalConfig(workingDir string) {
+func mergeLocalConfig(workingDir string) error {
 	local := viper.New()
 	local.SetConfigName(fmt.Sprintf(".%s", appName))
 	local.SetConfigType("json")
 	local.AddConfigPath(workingDir)
 
-	// Merge local config if it exists
-	if err := local.ReadInConfig(); err == nil {
-		viper.MergeConfigMap(local.AllSettings())
+	if err := readMigratedConfig(local); err != nil {
+		if err := readConfig(err); err != nil {
+			return fmt.Errorf("project config: %w", err)
+		}
+		return nil
 	}
+	return viper.MergeConfigMap(local.AllSettings())
+}
+
+// EffectiveSettings returns the settings the configuration was built from:
+// the home and project config files merged, together with environment
+// overrides and defaults. Keys are lowercase.
+func EffectiveSettings() map[string]any {
+	return viper.AllSettings()
 }
 
 // applyDefaultValues sets default values for configuration fields that need processing.
@@ -469,6 +559,8 @@ func applyDefaultValues() {
 			cfg.MCPServers[k] = v
 		}
 	}
+
+	applyDefaultAgents()
 }
 
 // It validates model IDs and providers, ensuring they are supported.
@@ -534,77 +626,142 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 	}
 
 	// Validate max tokens
-	if agent.MaxTokens <= 0 {
+	maxTokens := agent.MaxTokens
+	if maxTokens <= 0 {
 		logging.Warn("invalid max tokens, setting to default",
 			"agent", name,
 			"model", agent.Model,
 			"max_tokens", agent.MaxTokens)
 
-		// Update the agent with default max tokens
-		updatedAgent := cfg.Agents[name]
 		if model.DefaultMaxTokens > 0 {
-			updatedAgent.MaxTokens = model.DefaultMaxTokens
+			maxTokens = model.DefaultMaxTokens
 		} else {
-			updatedAgent.MaxTokens = MaxTokensFallbackDefault
+			maxTokens = MaxTokensFallbackDefault
 		}
-		cfg.Agents[name] = updatedAgent
-	} else if model.ContextWindow > 0 && agent.MaxTokens > model.ContextWindow/2 {
-		// Ensure max tokens doesn't exceed half the context window (reasonable limit)
-		logging.Warn("max tokens exceeds half the context window, adjusting",
+	}
+	if limit := model.OutputTokenLimit(); limit > 0 && maxTokens > limit {
+		// Providers reject requests asking for more than the model can produce
+		logging.Warn("max tokens exceeds the model's output limit, clamping",
 			"agent", name,
 			"model", agent.Model,
-			"max_tokens", agent.MaxTokens,
-			"context_window", model.ContextWindow)
-
-		// Update the agent with adjusted max tokens
+			"max_tokens", maxTokens,
+			"output_limit", limit)
+		maxTokens = limit
+	}
+	if maxTokens != agent.MaxTokens {
 		updatedAgent := cfg.Agents[name]
-		updatedAgent.MaxTokens = model.ContextWindow / 2
+		updatedAgent.MaxTokens = maxTokens
 		cfg.Agents[name] = updatedAgent
 	}
 
-	// Validate reasoning effort for models that support reasoning
-	if model.CanReason && provider == models.ProviderOpenAI || provider == models.ProviderLocal {
-		if agent.ReasoningEffort == "" {
-			// Set default reasoning effort for models that support it
-			logging.Info("setting default reasoning effort for model that supports reasoning",
-				"agent", name,
-				"model", agent.Model)
-
-			// Update the agent with default reasoning effort
+	// Validate reasoning effort against what the model accepts
+	if agent.ReasoningEffort != "" {
+		if !model.Supports(models.CapabilityReasoning) {
+			return fmt.Errorf("agent %s: model %s does not support reasoning, remove reasoningEffort", name, agent.Model)
+		}
+		if !model.SupportsReasoningEffort(agent.ReasoningEffort) {
+			return fmt.Errorf("agent %s: invalid reasoning effort %q for model %s, expected one of %s",
+				name, agent.ReasoningEffort, agent.Model, strings.Join(model.ReasoningEfforts(), ", "))
+		}
+		if effort := strings.ToLower(agent.ReasoningEffort); effort != agent.ReasoningEffort {
 			updatedAgent := cfg.Agents[name]
-			updatedAgent.ReasoningEffort = "medium"
+			updatedAgent.ReasoningEffort = effort
 			cfg.Agents[name] = updatedAgent
-		} else {
-			// Check if reasoning effort is valid (low, medium, high)
-			effort := strings.ToLower(agent.ReasoningEffort)
-			if effort != "low" && effort != "medium" && effort != "high" {
-				logging.Warn("invalid reasoning effort, setting to medium",
-					"agent", name,
-					"model", agent.Model,
-					"reasoning_effort", agent.ReasoningEffort)
-
-				// Update the agent with valid reasoning effort
-				updatedAgent := cfg.Agents[name]
-				updatedAgent.ReasoningEffort = "medium"
-				cfg.Agents[name] = updatedAgent
-			}
 		}
-	} else if !model.CanReason && agent.ReasoningEffort != "" {
-		// Model doesn't support reasoning but reasoning effort is set
-		logging.Warn("model doesn't support reasoning but reasoning effort is set, ignoring",
+	} else if model.CanReason && provider == models.ProviderOpenAI || provider == models.ProviderLocal {
+		// Set default reasoning effort for models that support it
+		logging.Info("setting default reasoning effort for model that supports reasoning",
 			"agent", name,
-			"model", agent.Model,
-			"reasoning_effort", agent.ReasoningEffort)
+			"model", agent.Model)
 
-		// Update the agent to remove reasoning effort
 		updatedAgent := cfg.Agents[name]
-		updatedAgent.ReasoningEffort = ""
+		updatedAgent.ReasoningEffort = "medium"
 		cfg.Agents[name] = updatedAgent
 	}
 
 	return nil
 }
 
+// validateTaskRouting ensures every task route points at a configured agent.
+func validateTaskRouting(cfg *Config) error {
+	for task, agentName := range cfg.TaskRouting {
+		if _, ok := cfg.Agents[agentName]; !ok {
+			return fmt.Errorf("task routing for %q references unknown agent %s", task, agentName)
+		}
+	}
+	return nil
+}
+
+// validatePermissions ensures the permission policy only references known
+// tools and that every deny rule matches something.
+func validatePermissions(cfg *Config) error {
+	for _, tool := range cfg.Permissions.AutoApproveTools {
+		if !isKnownTool(cfg, tool) {
+			return fmt.Errorf("permissions auto-approve references unknown tool %s", tool)
+		}
+	}
+	for i, rule := range cfg.Permissions.Deny {
+		if rule.Tool == "" && rule.Path == "" {
+			return fmt.Errorf("permissions deny rule %d must set a tool or a path", i)
+		}
+		if rule.Tool != "" && !isKnownTool(cfg, rule.Tool) {
+			return fmt.Errorf("permissions deny rule references unknown tool %s", rule.Tool)
+		}
+	}
+	return nil
+}
+
+// isKnownTool reports whether name is a builtin tool or a tool exposed by one
+// of the configured MCP servers, which are named <server>_<tool>.
+func isKnownTool(cfg *Config, name string) bool {
+	if slices.Contains(builtinToolNames, name) {
+		return true
+	}
+	for server := range cfg.MCPServers {
+		if strings.HasPrefix(name, server+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTemplates ensures every session template seeds at least one
+// message and only uses roles the providers understand.
+func validateTemplates(cfg *Config) error {
+	for name, template := range cfg.Templates {
+		if len(template.Messages) == 0 {
+			return fmt.Errorf("template %s has no messages", name)
+		}
+		for i, msg := range template.Messages {
+			if msg.Role != "user" && msg.Role != "assistant" {
+				return fmt.Errorf("template %s message %d has invalid role %q, expected user or assistant", name, i, msg.Role)
+			}
+			if strings.TrimSpace(msg.Content) == "" {
+				return fmt.Errorf("template %s message %d is empty", name, i)
+			}
+		}
+	}
+	return nil
+}
+
+// validateAutoCompact ensures the compaction threshold is a usable fraction of
+// the context window.
+func validateAutoCompact(cfg *Config) error {
+	if cfg.AutoCompactThreshold <= 0 || cfg.AutoCompactThreshold > 1 {
+		return fmt.Errorf("autoCompactThreshold must be greater than 0 and at most 1, got %v", cfg.AutoCompactThreshold)
+	}
+	return nil
+}
+
+// validateRequestLimit rejects a negative request limit, which would
+// otherwise silently mean no limit.
+func validateRequestLimit(cfg *Config) error {
+	if cfg.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("maxConcurrentRequests must be 0 (no limit) or more, got %d", cfg.MaxConcurrentRequests)
+	}
+	return nil
+}
+
 // Validate checks if the configuration is valid and applies defaults where needed.
 func Validate() error {
 	if cfg == nil {
@@ -628,15 +785,45 @@ func Validate() error {
 		}
 	}
 
-	// Validate LSP configurations
-	for language, lspConfig := range cfg.LSP {
-		if lspConfig.Command == "" && !lspConfig.Disabled {
-			logging.Warn("LSP configuration has no command, marking as disabled", "language", language)
-			lspConfig.Disabled = true
-			cfg.LSP[language] = lspConfig
-		}
+	// Validate provider timeouts and retries
+	if err := validateProviders(cfg); err != nil {
+		return err
+	}
+
+	// Validate task routing
+	if err := validateTaskRouting(cfg); err != nil {
+		return err
+	}
+
+	// Validate permission policy
+	if err := validatePermissions(cfg); err != nil {
+		return err
 	}
 
+	// Validate session templates
+	if err := validateTemplates(cfg); err != nil {
+		return err
+	}
+
+	// Validate auto compaction
+	if err := validateAutoCompact(cfg); err != nil {
+		return err
+	}
+
+	// Validate the concurrent request limit
+	if err := validateRequestLimit(cfg); err != nil {
+		return err
+	}
+
+	// Warn about context paths that can't be found
+	validateContextPaths(cfg)
+
+	// Warn about contradictory shell command lists
+	validateShell(cfg)
+
+	// Disable LSP servers that can't be started
+	validateLSP(cfg)
+
 	return nil
 }
 
@@ -667,153 +854,65 @@ func getProviderAPIKey(provider models.ModelProvider) string {
 	return ""
 }
 
+// setDefaultAgentModels defaults every agent's model to the one it uses on
+// provider.
+func setDefaultAgentModels(provider models.ModelProvider) {
+	for agent, model := range providerAgentModels[provider] {
+		viper.SetDefault(fmt.Sprintf("agents.%s.model", agent), model)
+	}
+}
+
 // setDefaultModelForAgent sets a default model for an agent based on available providers
 func setDefaultModelForAgent(agent AgentName) bool {
-	if hasCopilotCredentials() {
-		maxTokens := int64(5000)
-		if agent == AgentTitle {
-			maxTokens = 80
-		}
-
-		cfg.Agents[agent] = Agent{
-			Model:     models.CopilotGPT4o,
-			MaxTokens: maxTokens,
-		}
-		return true
-	}
 	// Check providers in order of preference
-	if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
-		maxTokens := int64(5000)
-		if agent == AgentTitle {
-			maxTokens = 80
-		}
-		cfg.Agents[agent] = Agent{
-			Model:     models.Claude37Sonnet,
-			MaxTokens: maxTokens,
-		}
-		return true
+	switch {
+	case hasCopilotCredentials():
+		return setAgentProviderDefault(agent, models.ProviderCopilot)
+	case os.Getenv("ANTHROPIC_API_KEY") != "":
+		return setAgentProviderDefault(agent, models.ProviderAnthropic)
+	case os.Getenv("OPENAI_API_KEY") != "":
+		return setAgentProviderDefault(agent, models.ProviderOpenAI)
+	case os.Getenv("OPENROUTER_API_KEY") != "":
+		return setAgentProviderDefault(agent, models.ProviderOpenRouter)
+	case os.Getenv("GEMINI_API_KEY") != "":
+		return setAgentProviderDefault(agent, models.ProviderGemini)
+	case os.Getenv("GROQ_API_KEY") != "":
+		return setAgentProviderDefault(agent, models.ProviderGROQ)
+	case hasAWSCredentials():
+		return setAgentProviderDefault(agent, models.ProviderBedrock)
+	case hasVertexAICredentials():
+		return setAgentProviderDefault(agent, models.ProviderVertexAI)
 	}
+	return false
+}
 
-	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
-		var model models.ModelID
-		maxTokens := int64(5000)
-		reasoningEffort := ""
-
-		switch agent {
-		case AgentTitle:
-			model = models.GPT41Mini
-			maxTokens = 80
-		case AgentTask:
-			model = models.GPT41Mini
-		default:
-			model = models.GPT41
-		}
-
-		// Check if model supports reasoning
-		if modelInfo, ok := models.SupportedModels[model]; ok && modelInfo.CanReason {
-			reasoningEffort = "medium"
-		}
-
-		cfg.Agents[agent] = Agent{
-			Model:           model,
-			MaxTokens:       maxTokens,
-			ReasoningEffort: reasoningEffort,
-		}
-		return true
+// setAgentProviderDefault sets agent to the model it uses by default on
+// provider.
+func setAgentProviderDefault(agent AgentName, provider models.ModelProvider) bool {
+	model, ok := providerAgentModels[provider][agent]
+	if !ok {
+		return false
 	}
 
-	if apiKey := os.Getenv("OPENROUTER_API_KEY"); apiKey != "" {
-		var model models.ModelID
-		maxTokens := int64(5000)
-		reasoningEffort := ""
-
-		switch agent {
-		case AgentTitle:
-			model = models.OpenRouterClaude35Haiku
-			maxTokens = 80
-		case AgentTask:
-			model = models.OpenRouterClaude37Sonnet
-		default:
-			model = models.OpenRouterClaude37Sonnet
-		}
+	maxTokens := int64(5000)
+	if agent == AgentTitle {
+		maxTokens = titleMaxTokens
+	}
 
-		// Check if model supports reasoning
+	// Check if model supports reasoning
+	reasoningEffort := ""
+	if provider == models.ProviderOpenAI || provider == models.ProviderOpenRouter {
 		if modelInfo, ok := models.SupportedModels[model]; ok && modelInfo.CanReason {
 			reasoningEffort = "medium"
 		}
-
-		cfg.Agents[agent] = Agent{
-			Model:           model,
-			MaxTokens:       maxTokens,
-			ReasoningEffort: reasoningEffort,
-		}
-		return true
-	}
-
-	if apiKey := os.Getenv("GEMINI_API_KEY"); apiKey != "" {
-		var model models.ModelID
-		maxTokens := int64(5000)
-
-		if agent == AgentTitle {
-			model = models.Gemini25Flash
-			maxTokens = 80
-		} else {
-			model = models.Gemini25
-		}
-
-		cfg.Agents[agent] = Agent{
-			Model:     model,
-			MaxTokens: maxTokens,
-		}
-		return true
-	}
-
-	if apiKey := os.Getenv("GROQ_API_KEY"); apiKey != "" {
-		maxTokens := int64(5000)
-		if agent == AgentTitle {
-			maxTokens = 80
-		}
-
-		cfg.Agents[agent] = Agent{
-			Model:     models.QWENQwq,
-			MaxTokens: maxTokens,
-		}
-		return true
 	}
 
-	if hasAWSCredentials() {
-		maxTokens := int64(5000)
-		if agent == AgentTitle {
-			maxTokens = 80
-		}
-
-		cfg.Agents[agent] = Agent{
-			Model:           models.BedrockClaude37Sonnet,
-			MaxTokens:       maxTokens,
-			ReasoningEffort: "medium", // Claude models support reasoning
-		}
-		return true
-	}
-
-	if hasVertexAICredentials() {
-		var model models.ModelID
-		maxTokens := int64(5000)
-
-		if agent == AgentTitle {
-			model = models.VertexAIGemini25Flash
-			maxTokens = 80
-		} else {
-			model = models.VertexAIGemini25
-		}
-
-		cfg.Agents[agent] = Agent{
-			Model:     model,
-			MaxTokens: maxTokens,
-		}
-		return true
+	cfg.Agents[agent] = Agent{
+		Model:           model,
+		MaxTokens:       maxTokens,
+		ReasoningEffort: reasoningEffort,
 	}
-
-	return false
+	return true
 }
 
 func updateCfgFile(updateCfg func(config *Config)) error {
@@ -838,7 +937,10 @@ func updateCfgFile(updateCfg func(config *Config)) error {
 		if err != nil {
 			return fmt.Errorf("failed to read config file: %w", err)
 		}
-		configData = data
+		configData, err = migrateConfig(data)
+		if err != nil {
+			return fmt.Errorf("failed to migrate config file: %w", err)
+		}
 	}
 
 	// Parse the JSON
@@ -848,6 +950,7 @@ func updateCfgFile(updateCfg func(config *Config)) error {
 	}
 
 	updateCfg(userCfg)
+	userCfg.Version = max(userCfg.Version, CurrentConfigVersion)
 
 	// Write the updated config back to file
 	updatedData, err := json.MarshalIndent(userCfg, "", "  ")
@@ -863,19 +966,77 @@ func updateCfgFile(updateCfg func(config *Config)) error {
 }
 
 // Get returns the current configuration.
-// It's safe to call this function multiple times.
+// It's safe to call this function multiple times. The configuration it points
+// to may change while it's being read; callers that only read it should prefer
+// Snapshot.
 func Get() *Config {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
 	return cfg
 }
 
+// cfgMu guards the cfg pointer, which Reload swaps, and the fields that change
+// in place after loading: the working directory, agents and theme.
+var cfgMu sync.RWMutex
+
 // WorkingDirectory returns the current working directory from the configuration.
 func WorkingDirectory() string {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
 	if cfg == nil {
 		panic("config not loaded")
 	}
 	return cfg.WorkingDir
 }
 
+// SetWorkingDirectory points the configuration at another directory, which
+// must exist, so one process can work on several projects. Relative paths
+// resolved afterwards, such as tool paths, permission requests and context
+// files, use the new directory. Config files are not reloaded.
+func SetWorkingDirectory(dir string) error {
+	if cfg == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid working directory: %w", err)
+	}
+	info, err := os.Stat(absDir)
+	if err != nil {
+		return fmt.Errorf("invalid working directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid working directory %s: not a directory", absDir)
+	}
+
+	cfgMu.Lock()
+	defer cfgMu.Unlock()
+	cfg.WorkingDir = absDir
+	return nil
+}
+
+// ResolvePath returns p unchanged if it is absolute, and otherwise joined to
+// the working directory.
+func ResolvePath(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(WorkingDirectory(), p)
+}
+
+// AgentForTask returns the agent configured to handle the given task category,
+// falling back to the coder agent when no route is configured.
+func AgentForTask(task string) AgentName {
+	if cfg == nil {
+		panic("config not loaded")
+	}
+	task = strings.ToLower(strings.TrimSpace(task))
+	if agentName, ok := cfg.TaskRouting[task]; ok && agentName != "" {
+		return agentName
+	}
+	return AgentCoder
+}
+
 func UpdateAgentModel(agentName AgentName, modelID models.ModelID) error {
 	if cfg == nil {
 		panic("config not loaded")
@@ -893,18 +1054,27 @@ func UpdateAgentModel(agentName AgentName, modelID models.ModelID) error {
 		maxTokens = model.DefaultMaxTokens
 	}
 
+	// Keep the reasoning effort only if the new model accepts it
+	reasoningEffort := existingAgentCfg.ReasoningEffort
+	if !model.SupportsReasoningEffort(reasoningEffort) {
+		reasoningEffort = ""
+	}
+
 	newAgentCfg := Agent{
 		Model:           modelID,
 		MaxTokens:       maxTokens,
-		ReasoningEffort: existingAgentCfg.ReasoningEffort,
+		ReasoningEffort: reasoningEffort,
+		ContextPaths:    existingAgentCfg.ContextPaths,
 	}
+	cfgMu.Lock()
 	cfg.Agents[agentName] = newAgentCfg
-
 	if err := validateAgent(cfg, agentName, newAgentCfg); err != nil {
 		// revert config update on failure
 		cfg.Agents[agentName] = existingAgentCfg
+		cfgMu.Unlock()
 		return fmt.Errorf("failed to update agent model: %w", err)
 	}
+	cfgMu.Unlock()
 
 	return updateCfgFile(func(config *Config) {
 		if config.Agents == nil {
@@ -921,7 +1091,9 @@ func UpdateTheme(themeName string) error {
 	}
 
 	// Update the in-memory config
+	cfgMu.Lock()
 	cfg.TUI.Theme = themeName
+	cfgMu.Unlock()
 
 	// Update the file config
 	return updateCfgFile(func(config *Config) {
@@ -935,7 +1107,11 @@ func LoadGitHubToken() (string, error) {
 	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
 		return token, nil
 	}
+	return loadStoredGitHubToken()
+}
 
+// loadStoredGitHubToken reads the token saved by a GitHub Copilot login.
+func loadStoredGitHubToken() (string, error) {
 	// Get config directory
 	var configDir string
 	if xdgConfig := os.Getenv("XDG_CONFIG_HOME"); xdgConfig != "" {
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentForTask(t *testing.T) {
+	original := cfg
+	t.Cleanup(func() { cfg = original })
+
+	cfg = &Config{
+		Agents: map[AgentName]Agent{
+			AgentCoder: {},
+			AgentTask:  {},
+		},
+		TaskRouting: map[string]AgentName{
+			"explain": AgentTask,
+		},
+	}
+
+	t.Run("routes configured tasks", func(t *testing.T) {
+		assert.Equal(t, AgentTask, AgentForTask("explain"))
+		assert.Equal(t, AgentTask, AgentForTask(" Explain "))
+	})
+
+	t.Run("falls back to the coder agent", func(t *testing.T) {
+		assert.Equal(t, AgentCoder, AgentForTask("refactor"))
+		assert.Equal(t, AgentCoder, AgentForTask(""))
+	})
+
+	t.Run("validates referenced agents", func(t *testing.T) {
+		assert.NoError(t, validateTaskRouting(cfg))
+
+		cfg.TaskRouting["fix"] = AgentName("missing")
+		assert.Error(t, validateTaskRouting(cfg))
+	})
+}
+
+func TestValidatePermissions(t *testing.T) {
+	t.Parallel()
+
+	valid := &Config{
+		MCPServers: map[string]MCPServer{"github": {}},
+		Permissions: PermissionsConfig{
+			AutoApproveTools: []string{"view", "github_search"},
+			Deny: []PermissionDenyRule{
+				{Tool: "bash"},
+				{Path: "/etc"},
+			},
+		},
+	}
+	assert.NoError(t, validatePermissions(valid))
+
+	invalid := []PermissionsConfig{
+		{AutoApproveTools: []string{"shell"}},
+		{Deny: []PermissionDenyRule{{Tool: "gitlab_search"}}},
+		{Deny: []PermissionDenyRule{{}}},
+	}
+	for _, permissions := range invalid {
+		assert.Error(t, validatePermissions(&Config{Permissions: permissions}))
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	t.Parallel()
+
+	valid := &Config{Templates: map[string]SessionTemplate{
+		"review": {Messages: []TemplateMessage{
+			{Role: "user", Content: "Review this."},
+			{Role: "assistant", Content: "Sure."},
+		}},
+	}}
+	assert.NoError(t, validateTemplates(valid))
+
+	invalid := []SessionTemplate{
+		{},
+		{Messages: []TemplateMessage{{Role: "system", Content: "Be terse."}}},
+		{Messages: []TemplateMessage{{Role: "user", Content: "  "}}},
+	}
+	for _, template := range invalid {
+		assert.Error(t, validateTemplates(&Config{Templates: map[string]SessionTemplate{"t": template}}))
+	}
+}
+
+func TestValidateAgentMaxTokens(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		model     models.ModelID
+		maxTokens int64
+		want      int64
+	}{
+		{"clamps to the output limit", models.Claude35Sonnet, 100000, 8192},
+		{"keeps a value within the limit", models.Claude35Sonnet, 2000, 2000},
+		{"defaults zero to the model default", models.Claude35Sonnet, 0, 5000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Providers: map[models.ModelProvider]Provider{
+					models.ProviderAnthropic: {APIKey: "key"},
+				},
+				Agents: map[AgentName]Agent{
+					AgentCoder: {Model: tt.model, MaxTokens: tt.maxTokens},
+				},
+			}
+			require.NoError(t, validateAgent(cfg, AgentCoder, cfg.Agents[AgentCoder]))
+			assert.Equal(t, tt.want, cfg.Agents[AgentCoder].MaxTokens)
+		})
+	}
+}
+
+func TestValidateAgentReasoningEffort(t *testing.T) {
+	t.Parallel()
+
+	validate := func(model models.ModelID, effort string) (Agent, error) {
+		cfg := &Config{
+			Providers: map[models.ModelProvider]Provider{
+				models.ProviderOpenAI:    {APIKey: "key"},
+				models.ProviderAnthropic: {APIKey: "key"},
+			},
+			Agents: map[AgentName]Agent{
+				AgentCoder: {Model: model, MaxTokens: 1000, ReasoningEffort: effort},
+			},
+		}
+		err := validateAgent(cfg, AgentCoder, cfg.Agents[AgentCoder])
+		return cfg.Agents[AgentCoder], err
+	}
+
+	agent, err := validate(models.O3, "High")
+	require.NoError(t, err)
+	assert.Equal(t, "high", agent.ReasoningEffort)
+
+	agent, err = validate(models.O3, "")
+	require.NoError(t, err)
+	assert.Equal(t, "medium", agent.ReasoningEffort, "OpenAI reasoning models default to medium")
+
+	_, err = validate(models.O3, "extreme")
+	assert.ErrorContains(t, err, "low, medium, high")
+
+	_, err = validate(models.GPT41, "low")
+	assert.ErrorContains(t, err, "does not support reasoning")
+
+	agent, err = validate(models.GPT41, "")
+	require.NoError(t, err)
+	assert.Empty(t, agent.ReasoningEffort)
+}
+
+func TestValidateAutoCompact(t *testing.T) {
+	t.Parallel()
+
+	for _, threshold := range []float64{0.8, 1, 0.01} {
+		assert.NoError(t, validateAutoCompact(&Config{AutoCompactThreshold: threshold}), threshold)
+	}
+	for _, threshold := range []float64{0, -0.5, 1.2} {
+		assert.Error(t, validateAutoCompact(&Config{AutoCompactThreshold: threshold}), threshold)
+	}
+}
+
+func TestLoadAndMerge(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	project := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".opencode.json"), []byte(`{
+		"debug": true,
+		"contextPaths": ["a.md", "b.md"],
+		"providers": {
+			"anthropic": {"apiKey": "home-key", "disabled": true},
+			"openai": {"apiKey": "openai-key"}
+		},
+		"agents": {"coder": {"model": "claude-3.7-sonnet", "maxTokens": 1000}}
+	}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(project, ".opencode.json"), []byte(`{
+		"contextPaths": ["c.md"],
+		"providers": {"anthropic": {"disabled": false}},
+		"agents": {"coder": {"maxTokens": 2000}}
+	}`), 0o644))
+
+	viper.Reset()
+	configureViper()
+	require.NoError(t, loadAndMerge(project))
+
+	var merged Config
+	require.NoError(t, viper.Unmarshal(&merged))
+	assert.Equal(t, Provider{APIKey: "home-key"}, merged.Providers[models.ProviderAnthropic], "re-enabled by the project, key kept from home")
+	assert.Equal(t, Provider{APIKey: "openai-key"}, merged.Providers[models.ProviderOpenAI])
+	assert.Equal(t, Agent{Model: models.Claude37Sonnet, MaxTokens: 2000}, merged.Agents[AgentCoder])
+	assert.Equal(t, []string{"c.md"}, merged.ContextPaths, "lists are replaced")
+	assert.True(t, merged.Debug)
+	assert.Contains(t, EffectiveSettings(), "providers")
+
+	t.Run("invalid project config", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(project, ".opencode.json"), []byte(`{"debug": `), 0o644))
+		viper.Reset()
+		configureViper()
+		assert.Error(t, loadAndMerge(project))
+	})
+}
+
+func TestSetWorkingDirectory(t *testing.T) {
+	original := cfg
+	t.Cleanup(func() { cfg = original })
+
+	first, second := t.TempDir(), t.TempDir()
+	cfg = &Config{WorkingDir: first}
+	assert.Equal(t, filepath.Join(first, "main.go"), ResolvePath("main.go"))
+	assert.Equal(t, "/abs/main.go", ResolvePath("/abs/main.go"))
+
+	require.NoError(t, SetWorkingDirectory(second))
+	assert.Equal(t, second, WorkingDirectory())
+	assert.Equal(t, filepath.Join(second, "main.go"), ResolvePath("main.go"))
+
+	file := filepath.Join(second, "file.txt")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+	assert.Error(t, SetWorkingDirectory(file))
+	assert.Error(t, SetWorkingDirectory(filepath.Join(second, "missing")))
+	assert.Equal(t, second, WorkingDirectory(), "a rejected directory leaves the old one in place")
+}
+
+func TestDataDirectory(t *testing.T) {
+	original := cfg
+	t.Cleanup(func() { cfg = original })
+	t.Cleanup(viper.Reset)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	project := t.TempDir()
+	elsewhere := t.TempDir()
+
+	load := func(t *testing.T, configured string) string {
+		t.Helper()
+		data := `{}`
+		if configured != "" {
+			data = `{"data": {"directory": "` + configured + `"}}`
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(project, ".opencode.json"), []byte(data), 0o644))
+		cfg = nil
+		viper.Reset()
+		loaded, err := Load(project, false)
+		require.NoError(t, err)
+		return loaded.Data.Directory
+	}
+
+	t.Run("default", func(t *testing.T) {
+		assert.Equal(t, filepath.Join(project, ".opencode"), load(t, ""))
+	})
+	t.Run("config", func(t *testing.T) {
+		assert.Equal(t, filepath.Join(project, "state"), load(t, "state"))
+		assert.Equal(t, elsewhere, load(t, elsewhere))
+	})
+	t.Run("env overrides config", func(t *testing.T) {
+		t.Setenv(dataDirEnv, elsewhere)
+		assert.Equal(t, elsewhere, load(t, "state"))
+	})
+	t.Run("relative env", func(t *testing.T) {
+		t.Setenv(dataDirEnv, "env-state")
+		assert.Equal(t, filepath.Join(project, "env-state"), load(t, "state"))
+	})
+}
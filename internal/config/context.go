@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/opencode-ai/opencode/internal/logging"
+)
+
+// ResolveContextFiles looks up the configured context paths under workingDir,
+// in the order they are configured. It returns the files that were found and
+// the configured paths that don't exist, so callers can tell the user a
+// context file they expected was not picked up. Paths ending in "/" are
+// directories whose files are all included, in lexical order. A file matched
+// by more than one path, ignoring case, is only returned once.
+func (c *Config) ResolveContextFiles(workingDir string) (found []string, missing []string) {
+	return resolveContextFiles(workingDir, c.ContextPaths)
+}
+
+func resolveContextFiles(workingDir string, paths []string) (found []string, missing []string) {
+	seen := make(map[string]bool)
+	add := func(path string) {
+		key := strings.ToLower(path)
+		if !seen[key] {
+			seen[key] = true
+			found = append(found, path)
+		}
+	}
+
+	for _, p := range paths {
+		fullPath := filepath.Join(workingDir, p)
+		if strings.HasSuffix(p, "/") {
+			info, err := os.Stat(fullPath)
+			if err != nil || !info.IsDir() {
+				missing = append(missing, p)
+				continue
+			}
+			filepath.WalkDir(fullPath, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return nil
+				}
+				if !d.IsDir() {
+					add(path)
+				}
+				return nil
+			})
+			continue
+		}
+
+		info, err := os.Stat(fullPath)
+		if err != nil || info.IsDir() {
+			missing = append(missing, p)
+			continue
+		}
+		add(fullPath)
+	}
+	return found, missing
+}
+
+// LoadContext concatenates the resolved context files, in configured order,
+// each headed by the path it came from. The result is cut off at maxBytes with
+// a note saying so; zero or less means no limit. Files that can't be read are
+// skipped with a warning.
+func (c *Config) LoadContext(workingDir string, maxBytes int) (string, error) {
+	return loadContext(workingDir, c.ContextPaths, maxBytes)
+}
+
+// LoadContextFor is LoadContext with the named agent's context paths.
+func (c *Config) LoadContextFor(name AgentName, workingDir string, maxBytes int) (string, error) {
+	return loadContext(workingDir, c.ContextPathsFor(name), maxBytes)
+}
+
+// ContextPathsFor returns the context paths for the named agent: its own when
+// it sets any, otherwise the global ones.
+func (c *Config) ContextPathsFor(name AgentName) []string {
+	if paths := c.Agents[name].ContextPaths; len(paths) > 0 {
+		return paths
+	}
+	return c.ContextPaths
+}
+
+func loadContext(workingDir string, paths []string, maxBytes int) (string, error) {
+	if _, err := os.Stat(workingDir); err != nil {
+		return "", fmt.Errorf("failed to load context: %w", err)
+	}
+
+	found, _ := resolveContextFiles(workingDir, paths)
+	sections := make([]string, 0, len(found))
+	for _, path := range found {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			logging.Warn("skipping unreadable context file", "path", path, "error", err)
+			continue
+		}
+		sections = append(sections, "# From:"+path+"\n"+string(content))
+	}
+
+	context := strings.Join(sections, "\n")
+	if maxBytes <= 0 || len(context) <= maxBytes {
+		return context, nil
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(context[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s\n\n[context truncated: %d of %d bytes included]", context[:cut], cut, len(context)), nil
+}
+
+// validateContextPaths warns about context paths that can never be found.
+// Context paths are looked up under the working directory, so an absolute
+// path outside it can't match anything.
+func validateContextPaths(cfg *Config) {
+	for _, p := range cfg.ContextPaths {
+		if outsideWorkingDir(cfg.WorkingDir, p) {
+			logging.Warn("context path is outside the working directory and will never be found", "path", p)
+		}
+	}
+	for name, agent := range cfg.Agents {
+		for _, p := range agent.ContextPaths {
+			if outsideWorkingDir(cfg.WorkingDir, p) {
+				logging.Warn("context path is outside the working directory and will never be found", "agent", name, "path", p)
+			}
+		}
+	}
+}
+
+// outsideWorkingDir reports whether p is an absolute path that isn't under
+// workingDir.
+func outsideWorkingDir(workingDir, p string) bool {
+	if !filepath.IsAbs(p) {
+		return false
+	}
+	rel, err := filepath.Rel(workingDir, p)
+	return err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
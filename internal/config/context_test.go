@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveContextFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"CLAUDE.md", "rules/b.md", "rules/a.md", "rules/nested/c.md"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(name), 0o644))
+	}
+
+	c := &Config{ContextPaths: []string{
+		"rules/",
+		"opencode.md",
+		"CLAUDE.md",
+		"missing/",
+		"rules/a.md",
+		"CLAUDE.md",
+	}}
+	found, missing := c.ResolveContextFiles(dir)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "rules/a.md"),
+		filepath.Join(dir, "rules/b.md"),
+		filepath.Join(dir, "rules/nested/c.md"),
+		filepath.Join(dir, "CLAUDE.md"),
+	}, found)
+	assert.Equal(t, []string{"opencode.md", "missing/"}, missing)
+}
+
+func TestLoadContext(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "rules"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "opencode.md"), []byte("use tabs"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rules", "go.md"), []byte("wrap errors"), 0o644))
+	// a dangling link is listed by the directory walk but can't be read
+	require.NoError(t, os.Symlink(filepath.Join(dir, "gone.md"), filepath.Join(dir, "rules", "broken.md")))
+
+	c := &Config{ContextPaths: []string{"opencode.md", "rules/"}}
+
+	content, err := c.LoadContext(dir, 0)
+	require.NoError(t, err)
+	want := "# From:" + filepath.Join(dir, "opencode.md") + "\nuse tabs\n" +
+		"# From:" + filepath.Join(dir, "rules", "go.md") + "\nwrap errors"
+	assert.Equal(t, want, content)
+
+	truncated, err := c.LoadContext(dir, 20)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(truncated, want[:20]))
+	assert.Contains(t, truncated, fmt.Sprintf("[context truncated: 20 of %d bytes included]", len(want)))
+
+	_, err = c.LoadContext(filepath.Join(dir, "nope"), 0)
+	assert.Error(t, err)
+}
+
+func TestContextPathsFor(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"CLAUDE.md", "SUMMARY.md"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644))
+	}
+
+	c := &Config{
+		ContextPaths: []string{"CLAUDE.md"},
+		Agents: map[AgentName]Agent{
+			AgentCoder:      {},
+			AgentSummarizer: {ContextPaths: []string{"SUMMARY.md"}},
+		},
+	}
+	assert.Equal(t, []string{"CLAUDE.md"}, c.ContextPathsFor(AgentCoder))
+	assert.Equal(t, []string{"CLAUDE.md"}, c.ContextPathsFor(AgentTitle), "unconfigured agents inherit")
+	assert.Equal(t, []string{"SUMMARY.md"}, c.ContextPathsFor(AgentSummarizer))
+
+	coder, err := c.LoadContextFor(AgentCoder, dir, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "# From:"+filepath.Join(dir, "CLAUDE.md")+"\nCLAUDE.md", coder)
+
+	summarizer, err := c.LoadContextFor(AgentSummarizer, dir, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "# From:"+filepath.Join(dir, "SUMMARY.md")+"\nSUMMARY.md", summarizer)
+}
+
+func TestOutsideWorkingDir(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(string(filepath.Separator), "work", "project")
+	assert.False(t, outsideWorkingDir(dir, "CLAUDE.md"))
+	assert.False(t, outsideWorkingDir(dir, "../CLAUDE.md"), "relative paths are joined to the working directory")
+	assert.False(t, outsideWorkingDir(dir, filepath.Join(dir, "docs", "rules.md")))
+	assert.True(t, outsideWorkingDir(dir, filepath.Join(string(filepath.Separator), "etc", "rules.md")))
+	assert.True(t, outsideWorkingDir(dir, filepath.Join(string(filepath.Separator), "work", "project-other", "rules.md")))
+	assert.True(t, outsideWorkingDir(dir, filepath.Join(string(filepath.Separator), "work")))
+}
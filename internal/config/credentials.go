@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/spf13/viper"
+)
+
+// Where a provider's credential came from, as reported by CredentialSources.
+const (
+	CredentialSourceConfig  = "config"
+	CredentialSourceEnv     = "env"
+	CredentialSourceKeyring = "keyring"
+	CredentialSourceNone    = "none"
+)
+
+// providerEnvKeys maps providers to the environment variable holding their API
+// key.
+var providerEnvKeys = map[models.ModelProvider]string{
+	models.ProviderAnthropic:  "ANTHROPIC_API_KEY",
+	models.ProviderOpenAI:     "OPENAI_API_KEY",
+	models.ProviderGemini:     "GEMINI_API_KEY",
+	models.ProviderGROQ:       "GROQ_API_KEY",
+	models.ProviderOpenRouter: "OPENROUTER_API_KEY",
+	models.ProviderXAI:        "XAI_API_KEY",
+	models.ProviderAzure:      "AZURE_OPENAI_API_KEY",
+	models.ProviderCopilot:    "GITHUB_TOKEN",
+}
+
+// CredentialSources reports, per provider, where the credential in use came
+// from. An apiKey in the config file takes precedence over the environment,
+// which in turn takes precedence over a stored login (the GitHub Copilot
+// token), so the source given is the one that wins. Providers without a
+// credential are reported as "none".
+func (c *Config) CredentialSources() map[models.ModelProvider]string {
+	return maps.Clone(c.credentialSources)
+}
+
+// detectCredentialSources must run before the environment keys are applied as
+// viper defaults, so that only keys written in a config file count as config.
+func detectCredentialSources() map[models.ModelProvider]string {
+	sources := make(map[models.ModelProvider]string)
+	for provider, envKey := range providerEnvKeys {
+		inConfig := viper.GetString(fmt.Sprintf("providers.%s.apiKey", provider)) != ""
+		inEnv := os.Getenv(envKey) != ""
+		stored := false
+		if provider == models.ProviderCopilot {
+			token, _ := loadStoredGitHubToken()
+			stored = token != ""
+		}
+		sources[provider] = credentialSource(inConfig, inEnv, stored)
+	}
+
+	sources[models.ProviderBedrock] = CredentialSourceNone
+	if hasAWSCredentials() {
+		sources[models.ProviderBedrock] = CredentialSourceEnv
+	}
+	sources[models.ProviderVertexAI] = CredentialSourceNone
+	if hasVertexAICredentials() {
+		sources[models.ProviderVertexAI] = CredentialSourceEnv
+	}
+	return sources
+}
+
+func credentialSource(inConfig, inEnv, stored bool) string {
+	switch {
+	case inConfig:
+		return CredentialSourceConfig
+	case inEnv:
+		return CredentialSourceEnv
+	case stored:
+		return CredentialSourceKeyring
+	default:
+		return CredentialSourceNone
+	}
+}
+
+// warnCredentialConflicts logs every provider whose API key is set both in the
+// config file and in the environment, since only the config key is used.
+func warnCredentialConflicts() {
+	for provider, source := range cfg.credentialSources {
+		envKey, ok := providerEnvKeys[provider]
+		if source == CredentialSourceConfig && ok && os.Getenv(envKey) != "" {
+			logging.Warn("credential set in both config and environment, using the config apiKey",
+				"provider", provider,
+				"env", envKey)
+		}
+	}
+}
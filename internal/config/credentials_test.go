@@ -0,0 +1,47 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectCredentialSources(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	for _, envKey := range providerEnvKeys {
+		t.Setenv(envKey, "")
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	hosts := filepath.Join(home, ".config", "github-copilot", "hosts.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(hosts), 0o755))
+	require.NoError(t, os.WriteFile(hosts, []byte(`{"github.com": {"oauth_token": "stored"}}`), 0o600))
+
+	viper.Reset()
+	viper.Set("providers.anthropic.apiKey", "from-config")
+	viper.Set("providers.openai.apiKey", "from-config")
+	t.Setenv("ANTHROPIC_API_KEY", "from-env")
+	t.Setenv("GEMINI_API_KEY", "from-env")
+
+	sources := detectCredentialSources()
+	assert.Equal(t, CredentialSourceConfig, sources[models.ProviderAnthropic], "config wins over env")
+	assert.Equal(t, CredentialSourceConfig, sources[models.ProviderOpenAI])
+	assert.Equal(t, CredentialSourceEnv, sources[models.ProviderGemini])
+	assert.Equal(t, CredentialSourceKeyring, sources[models.ProviderCopilot])
+	assert.Equal(t, CredentialSourceNone, sources[models.ProviderGROQ])
+
+	t.Setenv("GITHUB_TOKEN", "from-env")
+	assert.Equal(t, CredentialSourceEnv, detectCredentialSources()[models.ProviderCopilot])
+
+	c := &Config{credentialSources: sources}
+	got := c.CredentialSources()
+	got[models.ProviderGROQ] = CredentialSourceEnv
+	assert.Equal(t, CredentialSourceNone, c.CredentialSources()[models.ProviderGROQ], "callers get a copy")
+}
@@ -0,0 +1,5 @@
+package config
+
+// BuiltinToolNames exposes builtinToolNames to the external tests, which can
+// import the packages that register the tools.
+var BuiltinToolNames = builtinToolNames
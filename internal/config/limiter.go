@@ -0,0 +1,56 @@
+package config
+
+import "context"
+
+// RequestLimiter bounds how many requests to LLM providers are in flight at
+// once across every agent and session, so many sub-sessions running together
+// don't trip provider rate limits. A nil RequestLimiter doesn't limit.
+type RequestLimiter struct {
+	slots chan struct{}
+}
+
+// NewRequestLimiter returns a limiter allowing max concurrent requests, or
+// nil, which doesn't limit, when max is zero or less.
+func NewRequestLimiter(max int) *RequestLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &RequestLimiter{slots: make(chan struct{}, max)}
+}
+
+// Acquire waits for a free slot, returning ctx's error if ctx is done first.
+// Each successful Acquire must be paired with a Release.
+func (l *RequestLimiter) Acquire(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (l *RequestLimiter) Release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
+
+// Limit returns the number of concurrent requests allowed, or zero when
+// there is no limit.
+func (l *RequestLimiter) Limit() int {
+	if l == nil {
+		return 0
+	}
+	return cap(l.slots)
+}
+
+// RequestLimiter returns the limiter shared by every request made with this
+// configuration, created when it was loaded from maxConcurrentRequests.
+func (c *Config) RequestLimiter() *RequestLimiter {
+	return c.requestLimiter
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLimiter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("blocks beyond the limit", func(t *testing.T) {
+		l := NewRequestLimiter(2)
+		require.NoError(t, l.Acquire(context.Background()))
+		require.NoError(t, l.Acquire(context.Background()))
+
+		var acquired atomic.Bool
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if l.Acquire(context.Background()) == nil {
+				acquired.Store(true)
+			}
+		}()
+		time.Sleep(20 * time.Millisecond)
+		assert.False(t, acquired.Load(), "a third request waits for a slot")
+
+		l.Release()
+		<-done
+		assert.True(t, acquired.Load())
+	})
+
+	t.Run("gives up when the context is done", func(t *testing.T) {
+		l := NewRequestLimiter(1)
+		require.NoError(t, l.Acquire(context.Background()))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, l.Acquire(ctx), context.DeadlineExceeded)
+	})
+
+	t.Run("zero means no limit", func(t *testing.T) {
+		l := NewRequestLimiter(0)
+		assert.Nil(t, l)
+		assert.Zero(t, l.Limit())
+		for range 100 {
+			require.NoError(t, l.Acquire(context.Background()))
+		}
+		l.Release()
+	})
+}
+
+func TestLoadRequestLimiter(t *testing.T) {
+	original := cfg
+	t.Cleanup(func() { cfg = original })
+	t.Cleanup(viper.Reset)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	project := t.TempDir()
+
+	load := func(t *testing.T, data string) (*Config, error) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(filepath.Join(project, ".opencode.json"), []byte(data), 0o644))
+		cfg = nil
+		viper.Reset()
+		return Load(project, false)
+	}
+
+	loaded, err := load(t, `{"maxConcurrentRequests": 3}`)
+	require.NoError(t, err)
+	assert.Equal(t, 3, loaded.RequestLimiter().Limit())
+	snapshot := Snapshot()
+	assert.Same(t, loaded.RequestLimiter(), snapshot.RequestLimiter(), "snapshots share the limiter")
+
+	loaded, err = load(t, `{}`)
+	require.NoError(t, err)
+	assert.Nil(t, loaded.RequestLimiter())
+
+	_, err = load(t, `{"maxConcurrentRequests": -1}`)
+	assert.ErrorContains(t, err, "maxConcurrentRequests")
+}
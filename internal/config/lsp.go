@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/opencode-ai/opencode/internal/logging"
+)
+
+// LSPServer is a configured language server and the name it is configured
+// under.
+type LSPServer struct {
+	Name string
+	LSPConfig
+}
+
+// LSPServers returns the configured language servers sorted by name.
+func (c *Config) LSPServers() []LSPServer {
+	servers := make([]LSPServer, 0, len(c.LSP))
+	for name, lsp := range c.LSP {
+		servers = append(servers, LSPServer{Name: name, LSPConfig: lsp})
+	}
+	slices.SortFunc(servers, func(a, b LSPServer) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return servers
+}
+
+// validateLSP disables every enabled language server whose command can't be
+// found, warning about it, so one missing server doesn't stop opencode from
+// starting or leave a client that never connects.
+func validateLSP(cfg *Config) {
+	for _, server := range cfg.LSPServers() {
+		if server.Disabled {
+			continue
+		}
+		if server.Command == "" {
+			logging.Warn("lsp has no command, marking as disabled", "lsp", server.Name)
+		} else if _, err := exec.LookPath(server.Command); err != nil {
+			logging.Warn("lsp command not found, marking as disabled", "lsp", server.Name, "command", server.Command, "error", err)
+		} else {
+			continue
+		}
+		server.Disabled = true
+		cfg.LSP[server.Name] = server.LSPConfig
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLSPServers(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{LSP: map[string]LSPConfig{
+		"typescript": {Command: "typescript-language-server"},
+		"go":         {Command: "gopls"},
+		"rust":       {Command: "rust-analyzer", Disabled: true},
+	}}
+	var names []string
+	for _, server := range c.LSPServers() {
+		names = append(names, server.Name)
+	}
+	assert.Equal(t, []string{"go", "rust", "typescript"}, names)
+	assert.Equal(t, "gopls", c.LSPServers()[0].Command)
+	assert.Empty(t, (&Config{}).LSPServers())
+}
+
+func TestValidateLSP(t *testing.T) {
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "gopls"), []byte("#!/bin/sh\n"), 0o755))
+	t.Setenv("PATH", bin)
+
+	c := &Config{LSP: map[string]LSPConfig{
+		"go":         {Command: "gopls"},
+		"python":     {},
+		"rust":       {Command: "rust-analyzer", Disabled: true},
+		"typescript": {Command: "typescript-language-server", Args: []string{"--stdio"}},
+	}}
+	validateLSP(c)
+
+	assert.False(t, c.LSP["go"].Disabled)
+	assert.True(t, c.LSP["python"].Disabled)
+	assert.True(t, c.LSP["rust"].Disabled)
+	assert.Equal(t, LSPConfig{Command: "typescript-language-server", Args: []string{"--stdio"}, Disabled: true}, c.LSP["typescript"])
+}
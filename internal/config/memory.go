@@ -0,0 +1,96 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// memoryFactPrefix marks each fact in the memory file as a markdown list item
+const memoryFactPrefix = "- "
+
+var memoryMu sync.Mutex
+
+// memoryFilePath returns the absolute path of the project memory file, or an
+// empty string when no memory file is configured.
+func memoryFilePath() string {
+	if cfg.MemoryFile == "" {
+		return ""
+	}
+	return ResolvePath(cfg.MemoryFile)
+}
+
+// AppendMemory records a fact in the project memory file, one fact per line.
+func AppendMemory(fact string) error {
+	if cfg == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	path := memoryFilePath()
+	if path == "" {
+		return fmt.Errorf("memory file not configured")
+	}
+
+	fact = strings.Join(strings.Fields(fact), " ")
+	if fact == "" {
+		return fmt.Errorf("memory fact is empty")
+	}
+
+	memoryMu.Lock()
+	defer memoryMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create memory directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open memory file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(memoryFactPrefix + fact + "\n"); err != nil {
+		return fmt.Errorf("failed to write memory file: %w", err)
+	}
+	return nil
+}
+
+// LoadMemory returns the facts recorded in the project memory file in the
+// order they were added. A missing or unconfigured file yields no facts.
+func LoadMemory() ([]string, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config not loaded")
+	}
+	path := memoryFilePath()
+	if path == "" {
+		return nil, nil
+	}
+
+	memoryMu.Lock()
+	defer memoryMu.Unlock()
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open memory file: %w", err)
+	}
+	defer f.Close()
+
+	var facts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		line = strings.TrimSpace(strings.TrimPrefix(line, strings.TrimSpace(memoryFactPrefix)))
+		if line == "" {
+			continue
+		}
+		facts = append(facts, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read memory file: %w", err)
+	}
+	return facts, nil
+}
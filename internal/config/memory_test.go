@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRoundTrip(t *testing.T) {
+	original := cfg
+	t.Cleanup(func() { cfg = original })
+
+	tmpDir := t.TempDir()
+	cfg = &Config{
+		WorkingDir: tmpDir,
+		MemoryFile: "notes/memory.md",
+	}
+
+	facts, err := LoadMemory()
+	require.NoError(t, err)
+	assert.Empty(t, facts)
+
+	require.NoError(t, AppendMemory("Tests run with `go test ./...`"))
+	require.NoError(t, AppendMemory("The TUI uses\nbubbletea"))
+	assert.Error(t, AppendMemory("   "))
+
+	facts, err = LoadMemory()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"Tests run with `go test ./...`",
+		"The TUI uses bubbletea",
+	}, facts)
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "notes", "memory.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "- Tests run with `go test ./...`\n- The TUI uses bubbletea\n", string(data))
+}
+
+func TestMemoryConcurrentAppend(t *testing.T) {
+	original := cfg
+	t.Cleanup(func() { cfg = original })
+
+	cfg = &Config{MemoryFile: filepath.Join(t.TempDir(), "memory.md")}
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, AppendMemory(fmt.Sprintf("fact %d", i)))
+		}()
+	}
+	wg.Wait()
+
+	facts, err := LoadMemory()
+	require.NoError(t, err)
+	assert.Len(t, facts, 50)
+}
+
+func TestMemoryNotConfigured(t *testing.T) {
+	original := cfg
+	t.Cleanup(func() { cfg = original })
+
+	cfg = &Config{WorkingDir: t.TempDir()}
+
+	assert.Error(t, AppendMemory("something"))
+	facts, err := LoadMemory()
+	require.NoError(t, err)
+	assert.Empty(t, facts)
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/spf13/viper"
+)
+
+// CurrentConfigVersion is the config schema version this build reads and
+// writes. Files without a version are version 0.
+const CurrentConfigVersion = 1
+
+// configMigration upgrades a decoded config document by one version, in place.
+type configMigration func(doc map[string]any) error
+
+// configMigrations holds the migration from each version to the next, keyed by
+// the version it upgrades from.
+var configMigrations = map[int]configMigration{
+	0: migrateConfigV0,
+}
+
+// migrateConfigV0 moves the top-level theme, which predates the tui section,
+// to tui.theme. A theme already set under tui wins.
+func migrateConfigV0(doc map[string]any) error {
+	theme, ok := doc["theme"]
+	if !ok {
+		return nil
+	}
+	delete(doc, "theme")
+
+	tui, ok := doc["tui"].(map[string]any)
+	if !ok {
+		if doc["tui"] != nil {
+			return fmt.Errorf("tui must be an object")
+		}
+		tui = make(map[string]any)
+		doc["tui"] = tui
+	}
+	if _, ok := tui["theme"]; !ok {
+		tui["theme"] = theme
+	}
+	return nil
+}
+
+// migrateConfig upgrades a config file's contents to CurrentConfigVersion so
+// it can be unmarshalled into Config. Documents already at the current version
+// are returned unchanged. Versions newer than this build knows are loaded
+// best-effort with a warning, since their fields may not mean what this build
+// expects.
+func migrateConfig(raw []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return raw, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	version := 0
+	if v, ok := doc["version"]; ok {
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("config version must be a number")
+		}
+		i, err := n.Int64()
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("invalid config version %s", n)
+		}
+		version = int(i)
+	}
+
+	switch {
+	case version == CurrentConfigVersion:
+		return raw, nil
+	case version > CurrentConfigVersion:
+		logging.Warn("config was written by a newer version of opencode, loading it best-effort",
+			"version", version, "supported", CurrentConfigVersion)
+		return raw, nil
+	}
+
+	for ; version < CurrentConfigVersion; version++ {
+		migrate, ok := configMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from config version %d", version)
+		}
+		if err := migrate(doc); err != nil {
+			return nil, fmt.Errorf("failed to migrate config from version %d: %w", version, err)
+		}
+	}
+	doc["version"] = CurrentConfigVersion
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal migrated config: %w", err)
+	}
+	return migrated, nil
+}
+
+// readMigratedConfig reads the config file v finds, upgrading it to the
+// current schema before v holds its settings.
+func readMigratedConfig(v *viper.Viper) error {
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+	raw, err := os.ReadFile(v.ConfigFileUsed())
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	migrated, err := migrateConfig(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", v.ConfigFileUsed(), err)
+	}
+	return v.ReadConfig(bytes.NewReader(migrated))
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	t.Run("v0 to v1", func(t *testing.T) {
+		migrated, err := migrateConfig([]byte(`{"theme": "dracula", "debug": true, "autoCompactThreshold": 0.75}`))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"version": 1,
+			"tui": {"theme": "dracula"},
+			"debug": true,
+			"autoCompactThreshold": 0.75
+		}`, string(migrated))
+	})
+
+	t.Run("v0 keeps a theme set under tui", func(t *testing.T) {
+		migrated, err := migrateConfig([]byte(`{"theme": "dracula", "tui": {"theme": "tokyonight"}}`))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"version": 1, "tui": {"theme": "tokyonight"}}`, string(migrated))
+	})
+
+	t.Run("current version is unchanged", func(t *testing.T) {
+		raw := []byte(`{"version": 1, "theme": "dracula"}`)
+		migrated, err := migrateConfig(raw)
+		require.NoError(t, err)
+		assert.Equal(t, raw, migrated)
+	})
+
+	t.Run("future version loads as is", func(t *testing.T) {
+		raw := []byte(`{"version": 99, "somethingNew": true}`)
+		migrated, err := migrateConfig(raw)
+		require.NoError(t, err)
+		assert.Equal(t, raw, migrated)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, raw := range []string{`{"version": "1"}`, `{"version": -1}`, `{"theme": "x", "tui": 3}`, `{`} {
+			_, err := migrateConfig([]byte(raw))
+			assert.Error(t, err, raw)
+		}
+	})
+}
+
+func TestLoadMigratesConfig(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	project := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".opencode.json"), []byte(`{"theme": "dracula"}`), 0o644))
+
+	viper.Reset()
+	configureViper()
+	require.NoError(t, loadAndMerge(project))
+
+	var loaded Config
+	require.NoError(t, viper.Unmarshal(&loaded))
+	assert.Equal(t, CurrentConfigVersion, loaded.Version)
+	assert.Equal(t, "dracula", loaded.TUI.Theme)
+	assert.Equal(t, filepath.Join(home, ".opencode.json"), viper.ConfigFileUsed())
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+)
+
+const (
+	// DefaultProviderTimeout bounds a whole request to a provider, retries
+	// included, when the provider doesn't configure a timeout.
+	DefaultProviderTimeout = 10 * time.Minute
+	// DefaultProviderMaxRetries is how many times a failed request is retried
+	// when the provider doesn't configure a limit.
+	DefaultProviderMaxRetries = 8
+)
+
+// UnmarshalJSON reads the timeout as a duration string such as "90s", the
+// way it is written in config files.
+func (p *Provider) UnmarshalJSON(data []byte) error {
+	type provider Provider
+	aux := struct {
+		*provider
+		Timeout *string `json:"timeout,omitempty"`
+	}{provider: (*provider)(p)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	p.Timeout = nil
+	if aux.Timeout != nil {
+		timeout, err := time.ParseDuration(*aux.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid provider timeout: %w", err)
+		}
+		p.Timeout = &timeout
+	}
+	return nil
+}
+
+// MarshalJSON writes the timeout as a duration string.
+func (p Provider) MarshalJSON() ([]byte, error) {
+	type provider Provider
+	aux := struct {
+		provider
+		Timeout *string `json:"timeout,omitempty"`
+	}{provider: provider(p)}
+	if p.Timeout != nil {
+		timeout := p.Timeout.String()
+		aux.Timeout = &timeout
+	}
+	return json.Marshal(aux)
+}
+
+// TimeoutOrDefault returns the configured timeout, or DefaultProviderTimeout
+// when none is set. Zero means requests aren't time limited.
+func (p Provider) TimeoutOrDefault() time.Duration {
+	if p.Timeout == nil {
+		return DefaultProviderTimeout
+	}
+	return *p.Timeout
+}
+
+// MaxRetriesOrDefault returns the configured retry limit, or
+// DefaultProviderMaxRetries when none is set. Zero means no retries.
+func (p Provider) MaxRetriesOrDefault() int {
+	if p.MaxRetries == nil {
+		return DefaultProviderMaxRetries
+	}
+	return *p.MaxRetries
+}
+
+// validateProviders ensures provider timeouts, retry limits and failure limits
+// aren't negative.
+func validateProviders(cfg *Config) error {
+	for name, provider := range cfg.Providers {
+		if provider.TimeoutOrDefault() < 0 {
+			return fmt.Errorf("provider %s timeout must not be negative, got %s", name, provider.TimeoutOrDefault())
+		}
+		if provider.MaxRetriesOrDefault() < 0 {
+			return fmt.Errorf("provider %s maxRetries must not be negative, got %d", name, provider.MaxRetriesOrDefault())
+		}
+		if provider.DisableAfterFailures < 0 {
+			return fmt.Errorf("provider %s disableAfterFailures must not be negative, got %d", name, provider.DisableAfterFailures)
+		}
+	}
+	return nil
+}
+
+// providerFailures counts each provider's consecutive failed requests. It is
+// guarded by cfgMu.
+var providerFailures = make(map[models.ModelProvider]int)
+
+// RecordProviderResult records whether a request to provider failed. A nil
+// err resets the provider's count of consecutive failures. Once the count
+// reaches the provider's disableAfterFailures, the provider is disabled for
+// the rest of the run, without changing the config file. It reports whether
+// this call disabled the provider.
+func RecordProviderResult(provider models.ModelProvider, err error) bool {
+	cfgMu.Lock()
+	defer cfgMu.Unlock()
+	if err == nil {
+		delete(providerFailures, provider)
+		return false
+	}
+	if cfg == nil {
+		return false
+	}
+
+	providerCfg, ok := cfg.Providers[provider]
+	if !ok || providerCfg.Disabled || providerCfg.DisableAfterFailures <= 0 {
+		return false
+	}
+	providerFailures[provider]++
+	if providerFailures[provider] < providerCfg.DisableAfterFailures {
+		return false
+	}
+	delete(providerFailures, provider)
+	providerCfg.Disabled = true
+	cfg.Providers[provider] = providerCfg
+	return true
+}
+
+// IsProviderDisabled reports whether provider is disabled, in the config or
+// after failing too often.
+func IsProviderDisabled(provider models.ModelProvider) bool {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	return cfg != nil && cfg.Providers[provider].Disabled
+}
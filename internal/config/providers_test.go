@@ -0,0 +1,110 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderTimeoutAndRetries(t *testing.T) {
+	original := cfg
+	t.Cleanup(func() { cfg = original })
+	t.Cleanup(viper.Reset)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".opencode.json"), []byte(`{
+		"providers": {
+			"anthropic": {"apiKey": "key", "timeout": "90s", "maxRetries": 2},
+			"openai": {"apiKey": "key"},
+			"groq": {"apiKey": "key", "timeout": "0s", "maxRetries": 0}
+		}
+	}`), 0o644))
+
+	viper.Reset()
+	configureViper()
+	require.NoError(t, loadAndMerge(t.TempDir()))
+	cfg = &Config{}
+	require.NoError(t, viper.Unmarshal(cfg))
+
+	anthropic := cfg.Providers[models.ProviderAnthropic]
+	assert.Equal(t, 90*time.Second, anthropic.TimeoutOrDefault())
+	assert.Equal(t, 2, anthropic.MaxRetriesOrDefault())
+	openai := cfg.Providers[models.ProviderOpenAI]
+	assert.Nil(t, openai.Timeout)
+	assert.Equal(t, DefaultProviderTimeout, openai.TimeoutOrDefault(), "unset values get the default")
+	assert.Equal(t, DefaultProviderMaxRetries, openai.MaxRetriesOrDefault())
+	groq := cfg.Providers[models.ProviderGROQ]
+	assert.Zero(t, groq.TimeoutOrDefault(), "an explicit zero turns the timeout off")
+	assert.Zero(t, groq.MaxRetriesOrDefault(), "an explicit zero turns retries off")
+	assert.NoError(t, validateProviders(cfg))
+
+	t.Run("negative values", func(t *testing.T) {
+		timeout, retries := -time.Second, -1
+		assert.Error(t, validateProviders(&Config{Providers: map[models.ModelProvider]Provider{
+			models.ProviderOpenAI: {Timeout: &timeout},
+		}}))
+		assert.Error(t, validateProviders(&Config{Providers: map[models.ModelProvider]Provider{
+			models.ProviderOpenAI: {MaxRetries: &retries},
+		}}))
+	})
+
+	t.Run("json round trip", func(t *testing.T) {
+		timeout, retries := 5*time.Minute, 0
+		data, err := json.Marshal(Provider{APIKey: "key", Timeout: &timeout, MaxRetries: &retries})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"apiKey": "key", "disabled": false, "timeout": "5m0s", "maxRetries": 0}`, string(data))
+
+		var provider Provider
+		require.NoError(t, json.Unmarshal(data, &provider))
+		assert.Equal(t, Provider{APIKey: "key", Timeout: &timeout, MaxRetries: &retries}, provider)
+
+		data, err = json.Marshal(Provider{APIKey: "key"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"apiKey": "key", "disabled": false}`, string(data), "unset values stay unset")
+
+		assert.Error(t, json.Unmarshal([]byte(`{"timeout": "soon"}`), &provider))
+	})
+}
+
+func TestRecordProviderResult(t *testing.T) {
+	original := cfg
+	t.Cleanup(func() { cfg = original })
+	t.Cleanup(func() { clear(providerFailures) })
+
+	cfg = &Config{Providers: map[models.ModelProvider]Provider{
+		models.ProviderAnthropic: {APIKey: "key", DisableAfterFailures: 3},
+		models.ProviderOpenAI:    {APIKey: "key"},
+	}}
+	failure := errors.New("401 unauthorized")
+
+	assert.False(t, RecordProviderResult(models.ProviderAnthropic, failure))
+	assert.False(t, RecordProviderResult(models.ProviderAnthropic, failure))
+	assert.False(t, RecordProviderResult(models.ProviderAnthropic, nil), "a success resets the count")
+	assert.False(t, RecordProviderResult(models.ProviderAnthropic, failure))
+	assert.False(t, RecordProviderResult(models.ProviderAnthropic, failure))
+	assert.False(t, IsProviderDisabled(models.ProviderAnthropic))
+
+	assert.True(t, RecordProviderResult(models.ProviderAnthropic, failure))
+	assert.True(t, IsProviderDisabled(models.ProviderAnthropic))
+	assert.False(t, RecordProviderResult(models.ProviderAnthropic, failure), "only reported once")
+
+	for range 10 {
+		assert.False(t, RecordProviderResult(models.ProviderOpenAI, failure))
+	}
+	assert.False(t, IsProviderDisabled(models.ProviderOpenAI), "no limit configured")
+	assert.False(t, RecordProviderResult(models.ProviderGemini, failure), "unconfigured provider")
+
+	assert.Error(t, validateProviders(&Config{Providers: map[models.ModelProvider]Provider{
+		models.ProviderAnthropic: {DisableAfterFailures: -1},
+	}}))
+}
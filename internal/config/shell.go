@@ -0,0 +1,76 @@
+package config
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/opencode-ai/opencode/internal/logging"
+)
+
+// defaultBannedCommands are the commands the bash tool refuses to run unless
+// the config says otherwise: mostly network clients and browsers, which a
+// prompt injection could use to send data out.
+var defaultBannedCommands = []string{
+	"alias", "curl", "curlie", "wget", "axel", "aria2c",
+	"nc", "telnet", "lynx", "w3m", "links", "httpie", "xh",
+	"http-prompt", "chrome", "firefox", "safari",
+}
+
+// defaultReadOnlyCommands are the commands the bash tool runs without asking
+// for permission unless the config says otherwise.
+var defaultReadOnlyCommands = []string{
+	"ls", "echo", "pwd", "date", "cal", "uptime", "whoami", "id", "groups", "env", "printenv", "set", "unset", "which", "type", "whereis",
+	"whatis", "uname", "hostname", "df", "du", "free", "top", "ps", "kill", "killall", "nice", "nohup", "time", "timeout",
+
+	"git status", "git log", "git diff", "git show", "git branch", "git tag", "git remote", "git ls-files", "git ls-remote",
+	"git rev-parse", "git config --get", "git config --list", "git describe", "git blame", "git grep", "git shortlog",
+
+	"go version", "go help", "go list", "go env", "go doc", "go vet", "go fmt", "go mod", "go test", "go build", "go run", "go install", "go clean",
+}
+
+// IsCommandBanned reports whether the bash tool must refuse command: its
+// leading words match an entry of shell.bannedCommands, ignoring case. An
+// entry of several words, such as "git push", bans just that subcommand.
+func (c *Config) IsCommandBanned(command string) bool {
+	fields := strings.Fields(command)
+	return slices.ContainsFunc(c.Shell.BannedCommands, func(banned string) bool {
+		bannedFields := strings.Fields(banned)
+		if len(bannedFields) == 0 || len(bannedFields) > len(fields) {
+			return false
+		}
+		for i, field := range bannedFields {
+			if !strings.EqualFold(fields[i], field) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// IsCommandReadOnly reports whether command starts with an entry of
+// shell.allowedReadOnly, ignoring case, so the bash tool can run it without
+// asking for permission. The entry must be followed by the end of the
+// command, a space or a dash.
+func (c *Config) IsCommandReadOnly(command string) bool {
+	cmdLower := strings.ToLower(command)
+	for _, safe := range c.Shell.AllowedReadOnly {
+		safe = strings.ToLower(safe)
+		if safe == "" || !strings.HasPrefix(cmdLower, safe) {
+			continue
+		}
+		if len(cmdLower) == len(safe) || cmdLower[len(safe)] == ' ' || cmdLower[len(safe)] == '-' {
+			return true
+		}
+	}
+	return false
+}
+
+// validateShell warns about commands listed as both banned and read-only.
+// Banning wins, so the read-only entry has no effect.
+func validateShell(cfg *Config) {
+	for _, safe := range cfg.Shell.AllowedReadOnly {
+		if cfg.IsCommandBanned(safe) {
+			logging.Warn("command is both banned and allowed as read-only, it stays banned", "command", safe)
+		}
+	}
+}
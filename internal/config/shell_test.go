@@ -0,0 +1,59 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsCommandBanned(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{Shell: ShellConfig{BannedCommands: []string{"curl", "git push", " "}}}
+	for command, banned := range map[string]bool{
+		"curl https://example.com": true,
+		"CURL -s x":                true,
+		"  curl":                   true,
+		"curlie x":                 false,
+		"git push origin main":     true,
+		"git Push":                 true,
+		"git pull":                 false,
+		"git":                      false,
+		"echo curl":                false,
+		"":                         false,
+	} {
+		assert.Equal(t, banned, c.IsCommandBanned(command), command)
+	}
+}
+
+func TestIsCommandReadOnly(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{Shell: ShellConfig{AllowedReadOnly: []string{"ls", "git status", ""}}}
+	for command, readOnly := range map[string]bool{
+		"ls":                 true,
+		"LS -la":             true,
+		"ls-files":           true,
+		"lsof":               false,
+		"git status --short": true,
+		"git stash":          false,
+		"rm -rf /":           false,
+	} {
+		assert.Equal(t, readOnly, c.IsCommandReadOnly(command), command)
+	}
+}
+
+func TestShellDefaults(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{Shell: ShellConfig{
+		BannedCommands:  defaultBannedCommands,
+		AllowedReadOnly: defaultReadOnlyCommands,
+	}}
+	assert.True(t, c.IsCommandBanned("wget http://example.com"))
+	assert.True(t, c.IsCommandReadOnly("git diff HEAD"))
+	assert.False(t, c.IsCommandReadOnly("git push"))
+	for _, safe := range defaultReadOnlyCommands {
+		assert.False(t, c.IsCommandBanned(safe), "default lists don't contradict each other: %s", safe)
+	}
+}
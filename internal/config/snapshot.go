@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/spf13/viper"
+)
+
+// Snapshot returns a deep copy of the current configuration. The copy is
+// taken consistently with Reload and in-place updates, and shares nothing with
+// the live configuration, so it can be read from any goroutine without
+// locking.
+func Snapshot() Config {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	if cfg == nil {
+		panic("config not loaded")
+	}
+	return cfg.clone()
+}
+
+// Reload reads the config files again and swaps the result in for later calls
+// to Get and Snapshot, keeping the working directory and debug setting. A
+// configuration obtained earlier is left as it was. If the new configuration
+// fails to load, the current one stays in place and the error is returned.
+func Reload() error {
+	cfgMu.Lock()
+	defer cfgMu.Unlock()
+	if cfg == nil {
+		return fmt.Errorf("config not loaded")
+	}
+
+	previous := cfg
+	cfg = nil
+	viper.Reset()
+	if _, err := Load(previous.WorkingDir, previous.Debug); err != nil {
+		cfg = previous
+		return fmt.Errorf("failed to reload config: %w", err)
+	}
+	return nil
+}
+
+// clone returns a copy of c that shares no maps or slices with it.
+func (c *Config) clone() Config {
+	clone := *c
+	clone.ContextPaths = slices.Clone(c.ContextPaths)
+	clone.Shell.Args = slices.Clone(c.Shell.Args)
+	clone.Shell.BannedCommands = slices.Clone(c.Shell.BannedCommands)
+	clone.Shell.AllowedReadOnly = slices.Clone(c.Shell.AllowedReadOnly)
+	clone.Permissions.AutoApproveTools = slices.Clone(c.Permissions.AutoApproveTools)
+	clone.Permissions.Deny = slices.Clone(c.Permissions.Deny)
+	clone.Providers = maps.Clone(c.Providers)
+	clone.TaskRouting = maps.Clone(c.TaskRouting)
+	clone.credentialSources = maps.Clone(c.credentialSources)
+
+	if c.Agents != nil {
+		clone.Agents = make(map[AgentName]Agent, len(c.Agents))
+		for name, agent := range c.Agents {
+			agent.ContextPaths = slices.Clone(agent.ContextPaths)
+			clone.Agents[name] = agent
+		}
+	}
+	if c.MCPServers != nil {
+		clone.MCPServers = make(map[string]MCPServer, len(c.MCPServers))
+		for name, server := range c.MCPServers {
+			server.Env = slices.Clone(server.Env)
+			server.Args = slices.Clone(server.Args)
+			server.Headers = maps.Clone(server.Headers)
+			clone.MCPServers[name] = server
+		}
+	}
+	if c.LSP != nil {
+		clone.LSP = make(map[string]LSPConfig, len(c.LSP))
+		for name, lsp := range c.LSP {
+			lsp.Args = slices.Clone(lsp.Args)
+			lsp.Options = cloneValue(lsp.Options)
+			clone.LSP[name] = lsp
+		}
+	}
+	if c.Templates != nil {
+		clone.Templates = make(map[string]SessionTemplate, len(c.Templates))
+		for name, template := range c.Templates {
+			template.Messages = slices.Clone(template.Messages)
+			clone.Templates[name] = template
+		}
+	}
+	return clone
+}
+
+// cloneValue deep copies a value decoded from a config file, such as language
+// server options.
+func cloneValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		clone := make(map[string]any, len(v))
+		for key, item := range v {
+			clone[key] = cloneValue(item)
+		}
+		return clone
+	case []any:
+		clone := make([]any, len(v))
+		for i, item := range v {
+			clone[i] = cloneValue(item)
+		}
+		return clone
+	default:
+		return value
+	}
+}
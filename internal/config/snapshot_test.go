@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotIsDeepCopy(t *testing.T) {
+	original := cfg
+	t.Cleanup(func() { cfg = original })
+
+	cfg = &Config{
+		ContextPaths: []string{"a.md"},
+		Agents:       map[AgentName]Agent{AgentCoder: {Model: models.GPT41}, AgentTask: {ContextPaths: []string{"task.md"}}},
+		MCPServers:   map[string]MCPServer{"fs": {Args: []string{"serve"}, Headers: map[string]string{"a": "b"}}},
+		LSP:          map[string]LSPConfig{"go": {Command: "gopls", Options: map[string]any{"hints": []any{"all"}}}},
+	}
+
+	snapshot := Snapshot()
+	snapshot.ContextPaths[0] = "changed.md"
+	snapshot.Agents[AgentCoder] = Agent{Model: models.GPT4o}
+	snapshot.Agents[AgentTask].ContextPaths[0] = "changed.md"
+	snapshot.MCPServers["fs"].Args[0] = "changed"
+	snapshot.MCPServers["fs"].Headers["a"] = "changed"
+	snapshot.LSP["go"].Options.(map[string]any)["hints"].([]any)[0] = "changed"
+
+	assert.Equal(t, "a.md", cfg.ContextPaths[0])
+	assert.Equal(t, models.GPT41, cfg.Agents[AgentCoder].Model)
+	assert.Equal(t, "task.md", cfg.Agents[AgentTask].ContextPaths[0])
+	assert.Equal(t, "serve", cfg.MCPServers["fs"].Args[0])
+	assert.Equal(t, "b", cfg.MCPServers["fs"].Headers["a"])
+	assert.Equal(t, "all", cfg.LSP["go"].Options.(map[string]any)["hints"].([]any)[0])
+}
+
+func TestReloadWithConcurrentReads(t *testing.T) {
+	original := cfg
+	t.Cleanup(func() { cfg = original })
+	t.Cleanup(viper.Reset)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	project := t.TempDir()
+	configFile := filepath.Join(home, ".opencode.json")
+	writeConfig := func(theme string) {
+		require.NoError(t, os.WriteFile(configFile, []byte(`{
+			"providers": {"openai": {"apiKey": "key"}},
+			"agents": {"coder": {"model": "gpt-4.1"}},
+			"tui": {"theme": "`+theme+`"}
+		}`), 0o644))
+	}
+	writeConfig("opencode")
+
+	cfg = nil
+	viper.Reset()
+	_, err := Load(project, false)
+	require.NoError(t, err)
+	assert.Equal(t, "opencode", Snapshot().TUI.Theme)
+
+	writeConfig("dracula")
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 5 {
+				assert.NoError(t, Reload())
+			}
+		}()
+	}
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				snapshot := Snapshot()
+				assert.Contains(t, []string{"opencode", "dracula"}, snapshot.TUI.Theme)
+				assert.Equal(t, models.GPT41, snapshot.Agents[AgentCoder].Model)
+				assert.Equal(t, project, WorkingDirectory())
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, "dracula", Snapshot().TUI.Theme)
+	assert.Equal(t, project, Get().WorkingDir)
+
+	t.Run("a failed reload keeps the current config", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(configFile, []byte(`{"tui": `), 0o644))
+		before := Get()
+		assert.Error(t, Reload())
+		assert.Same(t, before, Get())
+	})
+}
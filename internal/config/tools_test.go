@@ -0,0 +1,31 @@
+package config_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/agent"
+	"github.com/opencode-ai/opencode/internal/lsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinToolNamesMatchRegisteredTools(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	config.Get().MCPServers = nil
+
+	// an LSP client is needed for the diagnostics tool to be registered
+	var names []string
+	for _, tool := range agent.CoderAgentTools(nil, nil, nil, nil, map[string]*lsp.Client{"go": nil}) {
+		names = append(names, tool.Info().Name)
+	}
+	slices.Sort(names)
+
+	assert.Equal(t, names, config.BuiltinToolNames)
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+)
+
+// verifyTimeout bounds VerifyProvider when ctx has no earlier deadline.
+const verifyTimeout = 10 * time.Second
+
+// ErrVerifyUnsupported is returned by VerifyProvider for providers whose
+// credentials it has no way to check.
+var ErrVerifyUnsupported = errors.New("credential verification is not supported for this provider")
+
+// verifyRequest is a cheap authenticated request, such as listing models,
+// that only succeeds with valid credentials.
+type verifyRequest struct {
+	url  string
+	auth func(req *http.Request, apiKey string)
+}
+
+func bearerAuth(req *http.Request, apiKey string) {
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+}
+
+var verifyRequests = map[models.ModelProvider]verifyRequest{
+	models.ProviderAnthropic: {"https://api.anthropic.com/v1/models", func(req *http.Request, apiKey string) {
+		req.Header.Set("x-api-key", apiKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	}},
+	models.ProviderOpenAI: {"https://api.openai.com/v1/models", bearerAuth},
+	models.ProviderGemini: {"https://generativelanguage.googleapis.com/v1beta/models", func(req *http.Request, apiKey string) {
+		req.Header.Set("x-goog-api-key", apiKey)
+	}},
+	models.ProviderGROQ:       {"https://api.groq.com/openai/v1/models", bearerAuth},
+	models.ProviderOpenRouter: {"https://openrouter.ai/api/v1/auth/key", bearerAuth},
+	models.ProviderXAI:        {"https://api.x.ai/v1/models", bearerAuth},
+}
+
+// VerifyProvider checks that the provider's API key is accepted by making a
+// cheap authenticated request to it, without using any tokens. The check
+// gives up after ctx is done or ten seconds, whichever comes first.
+// Providers that authenticate other ways, such as through cloud credentials,
+// return ErrVerifyUnsupported.
+func (c *Config) VerifyProvider(ctx context.Context, provider models.ModelProvider) error {
+	request, ok := verifyRequests[provider]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrVerifyUnsupported, provider)
+	}
+	apiKey := c.Providers[provider].APIKey
+	if apiKey == "" {
+		return fmt.Errorf("provider %s has no API key", provider)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, request.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create verification request: %w", err)
+	}
+	request.auth(req, apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach provider %s: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("provider %s rejected the API key: %s", provider, resp.Status)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("provider %s verification failed: %s", provider, resp.Status)
+	}
+	return nil
+}
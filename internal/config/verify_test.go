@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer good":
+			w.Write([]byte(`{"data": []}`))
+		case "Bearer slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		case "Bearer broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	original := verifyRequests[models.ProviderOpenAI]
+	t.Cleanup(func() { verifyRequests[models.ProviderOpenAI] = original })
+	verifyRequests[models.ProviderOpenAI] = verifyRequest{url: server.URL, auth: bearerAuth}
+
+	verify := func(ctx context.Context, apiKey string) error {
+		c := &Config{Providers: map[models.ModelProvider]Provider{
+			models.ProviderOpenAI: {APIKey: apiKey},
+		}}
+		return c.VerifyProvider(ctx, models.ProviderOpenAI)
+	}
+
+	assert.NoError(t, verify(context.Background(), "good"))
+	assert.ErrorContains(t, verify(context.Background(), "bad"), "rejected the API key: 401")
+	assert.ErrorContains(t, verify(context.Background(), "broken"), "verification failed: 500")
+	assert.ErrorContains(t, verify(context.Background(), ""), "has no API key")
+
+	t.Run("time bounded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := verify(ctx, "slow")
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+
+	t.Run("unsupported provider", func(t *testing.T) {
+		c := &Config{Providers: map[models.ModelProvider]Provider{
+			models.ProviderBedrock: {APIKey: "key"},
+		}}
+		assert.ErrorIs(t, c.VerifyProvider(context.Background(), models.ProviderBedrock), ErrVerifyUnsupported)
+	})
+}
@@ -24,6 +24,9 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.addSessionUsageStmt, err = db.PrepareContext(ctx, addSessionUsage); err != nil {
+		return nil, fmt.Errorf("error preparing query AddSessionUsage: %w", err)
+	}
 	if q.createFileStmt, err = db.PrepareContext(ctx, createFile); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateFile: %w", err)
 	}
@@ -57,6 +60,9 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getMessageStmt, err = db.PrepareContext(ctx, getMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query GetMessage: %w", err)
 	}
+	if q.getMostRecentSessionStmt, err = db.PrepareContext(ctx, getMostRecentSession); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMostRecentSession: %w", err)
+	}
 	if q.getSessionByIDStmt, err = db.PrepareContext(ctx, getSessionByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetSessionByID: %w", err)
 	}
@@ -69,15 +75,36 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.listLatestSessionFilesStmt, err = db.PrepareContext(ctx, listLatestSessionFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query ListLatestSessionFiles: %w", err)
 	}
+	if q.listMessagesAfterStmt, err = db.PrepareContext(ctx, listMessagesAfter); err != nil {
+		return nil, fmt.Errorf("error preparing query ListMessagesAfter: %w", err)
+	}
 	if q.listMessagesBySessionStmt, err = db.PrepareContext(ctx, listMessagesBySession); err != nil {
 		return nil, fmt.Errorf("error preparing query ListMessagesBySession: %w", err)
 	}
 	if q.listNewFilesStmt, err = db.PrepareContext(ctx, listNewFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query ListNewFiles: %w", err)
 	}
+	if q.listRecentSessionsStmt, err = db.PrepareContext(ctx, listRecentSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListRecentSessions: %w", err)
+	}
 	if q.listSessionsStmt, err = db.PrepareContext(ctx, listSessions); err != nil {
 		return nil, fmt.Errorf("error preparing query ListSessions: %w", err)
 	}
+	if q.listSessionsByFingerprintStmt, err = db.PrepareContext(ctx, listSessionsByFingerprint); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSessionsByFingerprint: %w", err)
+	}
+	if q.listSessionsByMetadataStmt, err = db.PrepareContext(ctx, listSessionsByMetadata); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSessionsByMetadata: %w", err)
+	}
+	if q.searchMessagesStmt, err = db.PrepareContext(ctx, searchMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchMessages: %w", err)
+	}
+	if q.setMessagePinnedStmt, err = db.PrepareContext(ctx, setMessagePinned); err != nil {
+		return nil, fmt.Errorf("error preparing query SetMessagePinned: %w", err)
+	}
+	if q.setSessionUsageStmt, err = db.PrepareContext(ctx, setSessionUsage); err != nil {
+		return nil, fmt.Errorf("error preparing query SetSessionUsage: %w", err)
+	}
 	if q.updateFileStmt, err = db.PrepareContext(ctx, updateFile); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateFile: %w", err)
 	}
@@ -87,11 +114,28 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.updateSessionStmt, err = db.PrepareContext(ctx, updateSession); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateSession: %w", err)
 	}
+	if q.updateSessionFingerprintStmt, err = db.PrepareContext(ctx, updateSessionFingerprint); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateSessionFingerprint: %w", err)
+	}
+	if q.updateSessionMessageCountStmt, err = db.PrepareContext(ctx, updateSessionMessageCount); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateSessionMessageCount: %w", err)
+	}
+	if q.updateSessionMetadataStmt, err = db.PrepareContext(ctx, updateSessionMetadata); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateSessionMetadata: %w", err)
+	}
+	if q.updateSessionTitleStmt, err = db.PrepareContext(ctx, updateSessionTitle); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateSessionTitle: %w", err)
+	}
 	return &q, nil
 }
 
 func (q *Queries) Close() error {
 	var err error
+	if q.addSessionUsageStmt != nil {
+		if cerr := q.addSessionUsageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addSessionUsageStmt: %w", cerr)
+		}
+	}
 	if q.createFileStmt != nil {
 		if cerr := q.createFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createFileStmt: %w", cerr)
@@ -147,6 +191,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getMessageStmt: %w", cerr)
 		}
 	}
+	if q.getMostRecentSessionStmt != nil {
+		if cerr := q.getMostRecentSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMostRecentSessionStmt: %w", cerr)
+		}
+	}
 	if q.getSessionByIDStmt != nil {
 		if cerr := q.getSessionByIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getSessionByIDStmt: %w", cerr)
@@ -167,6 +216,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing listLatestSessionFilesStmt: %w", cerr)
 		}
 	}
+	if q.listMessagesAfterStmt != nil {
+		if cerr := q.listMessagesAfterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listMessagesAfterStmt: %w", cerr)
+		}
+	}
 	if q.listMessagesBySessionStmt != nil {
 		if cerr := q.listMessagesBySessionStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listMessagesBySessionStmt: %w", cerr)
@@ -177,11 +231,41 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing listNewFilesStmt: %w", cerr)
 		}
 	}
+	if q.listRecentSessionsStmt != nil {
+		if cerr := q.listRecentSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listRecentSessionsStmt: %w", cerr)
+		}
+	}
 	if q.listSessionsStmt != nil {
 		if cerr := q.listSessionsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listSessionsStmt: %w", cerr)
 		}
 	}
+	if q.listSessionsByFingerprintStmt != nil {
+		if cerr := q.listSessionsByFingerprintStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSessionsByFingerprintStmt: %w", cerr)
+		}
+	}
+	if q.listSessionsByMetadataStmt != nil {
+		if cerr := q.listSessionsByMetadataStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSessionsByMetadataStmt: %w", cerr)
+		}
+	}
+	if q.searchMessagesStmt != nil {
+		if cerr := q.searchMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchMessagesStmt: %w", cerr)
+		}
+	}
+	if q.setMessagePinnedStmt != nil {
+		if cerr := q.setMessagePinnedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setMessagePinnedStmt: %w", cerr)
+		}
+	}
+	if q.setSessionUsageStmt != nil {
+		if cerr := q.setSessionUsageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setSessionUsageStmt: %w", cerr)
+		}
+	}
 	if q.updateFileStmt != nil {
 		if cerr := q.updateFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateFileStmt: %w", cerr)
@@ -197,6 +281,26 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing updateSessionStmt: %w", cerr)
 		}
 	}
+	if q.updateSessionFingerprintStmt != nil {
+		if cerr := q.updateSessionFingerprintStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateSessionFingerprintStmt: %w", cerr)
+		}
+	}
+	if q.updateSessionMessageCountStmt != nil {
+		if cerr := q.updateSessionMessageCountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateSessionMessageCountStmt: %w", cerr)
+		}
+	}
+	if q.updateSessionMetadataStmt != nil {
+		if cerr := q.updateSessionMetadataStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateSessionMetadataStmt: %w", cerr)
+		}
+	}
+	if q.updateSessionTitleStmt != nil {
+		if cerr := q.updateSessionTitleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateSessionTitleStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -234,55 +338,81 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                          DBTX
-	tx                          *sql.Tx
-	createFileStmt              *sql.Stmt
-	createMessageStmt           *sql.Stmt
-	createSessionStmt           *sql.Stmt
-	deleteFileStmt              *sql.Stmt
-	deleteMessageStmt           *sql.Stmt
-	deleteSessionStmt           *sql.Stmt
-	deleteSessionFilesStmt      *sql.Stmt
-	deleteSessionMessagesStmt   *sql.Stmt
-	getFileStmt                 *sql.Stmt
-	getFileByPathAndSessionStmt *sql.Stmt
-	getMessageStmt              *sql.Stmt
-	getSessionByIDStmt          *sql.Stmt
-	listFilesByPathStmt         *sql.Stmt
-	listFilesBySessionStmt      *sql.Stmt
-	listLatestSessionFilesStmt  *sql.Stmt
-	listMessagesBySessionStmt   *sql.Stmt
-	listNewFilesStmt            *sql.Stmt
-	listSessionsStmt            *sql.Stmt
-	updateFileStmt              *sql.Stmt
-	updateMessageStmt           *sql.Stmt
-	updateSessionStmt           *sql.Stmt
+	db                            DBTX
+	tx                            *sql.Tx
+	addSessionUsageStmt           *sql.Stmt
+	createFileStmt                *sql.Stmt
+	createMessageStmt             *sql.Stmt
+	createSessionStmt             *sql.Stmt
+	deleteFileStmt                *sql.Stmt
+	deleteMessageStmt             *sql.Stmt
+	deleteSessionStmt             *sql.Stmt
+	deleteSessionFilesStmt        *sql.Stmt
+	deleteSessionMessagesStmt     *sql.Stmt
+	getFileStmt                   *sql.Stmt
+	getFileByPathAndSessionStmt   *sql.Stmt
+	getMessageStmt                *sql.Stmt
+	getMostRecentSessionStmt      *sql.Stmt
+	getSessionByIDStmt            *sql.Stmt
+	listFilesByPathStmt           *sql.Stmt
+	listFilesBySessionStmt        *sql.Stmt
+	listLatestSessionFilesStmt    *sql.Stmt
+	listMessagesAfterStmt         *sql.Stmt
+	listMessagesBySessionStmt     *sql.Stmt
+	listNewFilesStmt              *sql.Stmt
+	listRecentSessionsStmt        *sql.Stmt
+	listSessionsStmt              *sql.Stmt
+	listSessionsByFingerprintStmt *sql.Stmt
+	listSessionsByMetadataStmt    *sql.Stmt
+	searchMessagesStmt            *sql.Stmt
+	setMessagePinnedStmt          *sql.Stmt
+	setSessionUsageStmt           *sql.Stmt
+	updateFileStmt                *sql.Stmt
+	updateMessageStmt             *sql.Stmt
+	updateSessionStmt             *sql.Stmt
+	updateSessionFingerprintStmt  *sql.Stmt
+	updateSessionMessageCountStmt *sql.Stmt
+	updateSessionMetadataStmt     *sql.Stmt
+	updateSessionTitleStmt        *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                          tx,
-		tx:                          tx,
-		createFileStmt:              q.createFileStmt,
-		createMessageStmt:           q.createMessageStmt,
-		createSessionStmt:           q.createSessionStmt,
-		deleteFileStmt:              q.deleteFileStmt,
-		deleteMessageStmt:           q.deleteMessageStmt,
-		deleteSessionStmt:           q.deleteSessionStmt,
-		deleteSessionFilesStmt:      q.deleteSessionFilesStmt,
-		deleteSessionMessagesStmt:   q.deleteSessionMessagesStmt,
-		getFileStmt:                 q.getFileStmt,
-		getFileByPathAndSessionStmt: q.getFileByPathAndSessionStmt,
-		getMessageStmt:              q.getMessageStmt,
-		getSessionByIDStmt:          q.getSessionByIDStmt,
-		listFilesByPathStmt:         q.listFilesByPathStmt,
-		listFilesBySessionStmt:      q.listFilesBySessionStmt,
-		listLatestSessionFilesStmt:  q.listLatestSessionFilesStmt,
-		listMessagesBySessionStmt:   q.listMessagesBySessionStmt,
-		listNewFilesStmt:            q.listNewFilesStmt,
-		listSessionsStmt:            q.listSessionsStmt,
-		updateFileStmt:              q.updateFileStmt,
-		updateMessageStmt:           q.updateMessageStmt,
-		updateSessionStmt:           q.updateSessionStmt,
+		db:                            tx,
+		tx:                            tx,
+		addSessionUsageStmt:           q.addSessionUsageStmt,
+		createFileStmt:                q.createFileStmt,
+		createMessageStmt:             q.createMessageStmt,
+		createSessionStmt:             q.createSessionStmt,
+		deleteFileStmt:                q.deleteFileStmt,
+		deleteMessageStmt:             q.deleteMessageStmt,
+		deleteSessionStmt:             q.deleteSessionStmt,
+		deleteSessionFilesStmt:        q.deleteSessionFilesStmt,
+		deleteSessionMessagesStmt:     q.deleteSessionMessagesStmt,
+		getFileStmt:                   q.getFileStmt,
+		getFileByPathAndSessionStmt:   q.getFileByPathAndSessionStmt,
+		getMessageStmt:                q.getMessageStmt,
+		getMostRecentSessionStmt:      q.getMostRecentSessionStmt,
+		getSessionByIDStmt:            q.getSessionByIDStmt,
+		listFilesByPathStmt:           q.listFilesByPathStmt,
+		listFilesBySessionStmt:        q.listFilesBySessionStmt,
+		listLatestSessionFilesStmt:    q.listLatestSessionFilesStmt,
+		listMessagesAfterStmt:         q.listMessagesAfterStmt,
+		listMessagesBySessionStmt:     q.listMessagesBySessionStmt,
+		listNewFilesStmt:              q.listNewFilesStmt,
+		listRecentSessionsStmt:        q.listRecentSessionsStmt,
+		listSessionsStmt:              q.listSessionsStmt,
+		listSessionsByFingerprintStmt: q.listSessionsByFingerprintStmt,
+		listSessionsByMetadataStmt:    q.listSessionsByMetadataStmt,
+		searchMessagesStmt:            q.searchMessagesStmt,
+		setMessagePinnedStmt:          q.setMessagePinnedStmt,
+		setSessionUsageStmt:           q.setSessionUsageStmt,
+		updateFileStmt:                q.updateFileStmt,
+		updateMessageStmt:             q.updateMessageStmt,
+		updateSessionStmt:             q.updateSessionStmt,
+		updateSessionFingerprintStmt:  q.updateSessionFingerprintStmt,
+		updateSessionMessageCountStmt: q.updateSessionMessageCountStmt,
+		updateSessionMetadataStmt:     q.updateSessionMetadataStmt,
+		updateSessionTitleStmt:        q.updateSessionTitleStmt,
 	}
 }
@@ -22,7 +22,7 @@ INSERT INTO messages (
 ) VALUES (
     ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now')
 )
-RETURNING id, session_id, role, parts, model, created_at, updated_at, finished_at
+RETURNING id, session_id, role, parts, model, created_at, updated_at, finished_at, pinned
 `
 
 type CreateMessageParams struct {
@@ -51,6 +51,7 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.FinishedAt,
+		&i.Pinned,
 	)
 	return i, err
 }
@@ -76,7 +77,7 @@ func (q *Queries) DeleteSessionMessages(ctx context.Context, sessionID string) e
 }
 
 const getMessage = `-- name: GetMessage :one
-SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at
+SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, pinned
 FROM messages
 WHERE id = ? LIMIT 1
 `
@@ -93,12 +94,61 @@ func (q *Queries) GetMessage(ctx context.Context, id string) (Message, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.FinishedAt,
+		&i.Pinned,
 	)
 	return i, err
 }
 
+const listMessagesAfter = `-- name: ListMessagesAfter :many
+SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, pinned
+FROM messages
+WHERE session_id = ?1
+  AND (created_at, rowid) > (
+    SELECT created_at, rowid FROM messages WHERE id = ?2
+  )
+ORDER BY created_at ASC, rowid ASC
+`
+
+type ListMessagesAfterParams struct {
+	SessionID string `json:"session_id"`
+	MessageID string `json:"message_id"`
+}
+
+func (q *Queries) ListMessagesAfter(ctx context.Context, arg ListMessagesAfterParams) ([]Message, error) {
+	rows, err := q.query(ctx, q.listMessagesAfterStmt, listMessagesAfter, arg.SessionID, arg.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Message{}
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.SessionID,
+			&i.Role,
+			&i.Parts,
+			&i.Model,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FinishedAt,
+			&i.Pinned,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listMessagesBySession = `-- name: ListMessagesBySession :many
-SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at
+SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, pinned
 FROM messages
 WHERE session_id = ?
 ORDER BY created_at ASC
@@ -122,6 +172,7 @@ func (q *Queries) ListMessagesBySession(ctx context.Context, sessionID string) (
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.FinishedAt,
+			&i.Pinned,
 		); err != nil {
 			return nil, err
 		}
@@ -136,6 +187,75 @@ func (q *Queries) ListMessagesBySession(ctx context.Context, sessionID string) (
 	return items, nil
 }
 
+const searchMessages = `-- name: SearchMessages :many
+SELECT
+    m.id,
+    m.session_id,
+    CAST(snippet(messages_fts, 2, '', '', '...', 16) AS TEXT) AS snippet,
+    m.created_at
+FROM messages_fts
+JOIN messages AS m ON m.id = messages_fts.message_id
+WHERE messages_fts MATCH ?
+ORDER BY m.created_at DESC
+LIMIT ?
+`
+
+type SearchMessagesParams struct {
+	Query string `json:"query"`
+	Limit int64  `json:"limit"`
+}
+
+type SearchMessagesRow struct {
+	ID        string `json:"id"`
+	SessionID string `json:"session_id"`
+	Snippet   string `json:"snippet"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func (q *Queries) SearchMessages(ctx context.Context, arg SearchMessagesParams) ([]SearchMessagesRow, error) {
+	rows, err := q.query(ctx, q.searchMessagesStmt, searchMessages, arg.Query, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchMessagesRow{}
+	for rows.Next() {
+		var i SearchMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SessionID,
+			&i.Snippet,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setMessagePinned = `-- name: SetMessagePinned :exec
+UPDATE messages
+SET pinned = ?
+WHERE id = ?
+`
+
+type SetMessagePinnedParams struct {
+	Pinned bool   `json:"pinned"`
+	ID     string `json:"id"`
+}
+
+func (q *Queries) SetMessagePinned(ctx context.Context, arg SetMessagePinnedParams) error {
+	_, err := q.exec(ctx, q.setMessagePinnedStmt, setMessagePinned, arg.Pinned, arg.ID)
+	return err
+}
+
 const updateMessage = `-- name: UpdateMessage :exec
 UPDATE messages
 SET
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN fingerprint TEXT;
+CREATE INDEX IF NOT EXISTS idx_sessions_fingerprint ON sessions (fingerprint);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_sessions_fingerprint;
+ALTER TABLE sessions DROP COLUMN fingerprint;
+-- +goose StatementEnd
@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5 (
+    message_id UNINDEXED,
+    session_id UNINDEXED,
+    content
+);
+
+-- Only the text parts of a message are indexed
+CREATE TRIGGER IF NOT EXISTS messages_fts_on_insert
+AFTER INSERT ON messages
+BEGIN
+INSERT INTO messages_fts (message_id, session_id, content)
+SELECT new.id, new.session_id, group_concat(json_extract(value, '$.data.text'), ' ')
+FROM json_each(new.parts)
+WHERE json_extract(value, '$.type') = 'text';
+END;
+
+CREATE TRIGGER IF NOT EXISTS messages_fts_on_update
+AFTER UPDATE OF parts ON messages
+BEGIN
+DELETE FROM messages_fts WHERE message_id = old.id;
+INSERT INTO messages_fts (message_id, session_id, content)
+SELECT new.id, new.session_id, group_concat(json_extract(value, '$.data.text'), ' ')
+FROM json_each(new.parts)
+WHERE json_extract(value, '$.type') = 'text';
+END;
+
+CREATE TRIGGER IF NOT EXISTS messages_fts_on_delete
+AFTER DELETE ON messages
+BEGIN
+DELETE FROM messages_fts WHERE message_id = old.id;
+END;
+
+INSERT INTO messages_fts (message_id, session_id, content)
+SELECT m.id, m.session_id, (
+    SELECT group_concat(json_extract(p.value, '$.data.text'), ' ')
+    FROM json_each(m.parts) AS p
+    WHERE json_extract(p.value, '$.type') = 'text'
+)
+FROM messages AS m;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS messages_fts_on_delete;
+DROP TRIGGER IF EXISTS messages_fts_on_update;
+DROP TRIGGER IF EXISTS messages_fts_on_insert;
+DROP TABLE IF EXISTS messages_fts;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN last_message_at INTEGER;  -- Unix timestamp in seconds
+
+UPDATE sessions SET last_message_at = (
+    SELECT MAX(created_at) FROM messages WHERE messages.session_id = sessions.id
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_last_message_at ON sessions (last_message_at);
+
+CREATE TRIGGER IF NOT EXISTS update_session_last_message_at_on_insert
+AFTER INSERT ON messages
+BEGIN
+UPDATE sessions SET
+    last_message_at = MAX(COALESCE(last_message_at, 0), new.created_at)
+WHERE id = new.session_id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_session_last_message_at_on_insert;
+DROP INDEX IF EXISTS idx_sessions_last_message_at;
+ALTER TABLE sessions DROP COLUMN last_message_at;
+-- +goose StatementEnd
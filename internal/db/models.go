@@ -9,13 +9,14 @@ import (
 )
 
 type File struct {
-	ID        string `json:"id"`
-	SessionID string `json:"session_id"`
-	Path      string `json:"path"`
-	Content   string `json:"content"`
-	Version   string `json:"version"`
-	CreatedAt int64  `json:"created_at"`
-	UpdatedAt int64  `json:"updated_at"`
+	ID         string `json:"id"`
+	SessionID  string `json:"session_id"`
+	Path       string `json:"path"`
+	Content    string `json:"content"`
+	Version    string `json:"version"`
+	CreatedAt  int64  `json:"created_at"`
+	UpdatedAt  int64  `json:"updated_at"`
+	Compressed bool   `json:"compressed"`
 }
 
 type Message struct {
@@ -27,6 +28,7 @@ type Message struct {
 	CreatedAt  int64          `json:"created_at"`
 	UpdatedAt  int64          `json:"updated_at"`
 	FinishedAt sql.NullInt64  `json:"finished_at"`
+	Pinned     bool           `json:"pinned"`
 }
 
 type Session struct {
@@ -40,4 +42,7 @@ type Session struct {
 	UpdatedAt        int64          `json:"updated_at"`
 	CreatedAt        int64          `json:"created_at"`
 	SummaryMessageID sql.NullString `json:"summary_message_id"`
+	Fingerprint      sql.NullString `json:"fingerprint"`
+	LastMessageAt    sql.NullInt64  `json:"last_message_at"`
+	Metadata         sql.NullString `json:"metadata"`
 }
@@ -6,9 +6,11 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 type Querier interface {
+	AddSessionUsage(ctx context.Context, arg AddSessionUsageParams) (Session, error)
 	CreateFile(ctx context.Context, arg CreateFileParams) (File, error)
 	CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
@@ -20,16 +22,28 @@ type Querier interface {
 	GetFile(ctx context.Context, id string) (File, error)
 	GetFileByPathAndSession(ctx context.Context, arg GetFileByPathAndSessionParams) (File, error)
 	GetMessage(ctx context.Context, id string) (Message, error)
+	GetMostRecentSession(ctx context.Context) (Session, error)
 	GetSessionByID(ctx context.Context, id string) (Session, error)
 	ListFilesByPath(ctx context.Context, path string) ([]File, error)
 	ListFilesBySession(ctx context.Context, sessionID string) ([]File, error)
 	ListLatestSessionFiles(ctx context.Context, sessionID string) ([]File, error)
+	ListMessagesAfter(ctx context.Context, arg ListMessagesAfterParams) ([]Message, error)
 	ListMessagesBySession(ctx context.Context, sessionID string) ([]Message, error)
 	ListNewFiles(ctx context.Context) ([]File, error)
+	ListRecentSessions(ctx context.Context, limit int64) ([]Session, error)
 	ListSessions(ctx context.Context) ([]Session, error)
+	ListSessionsByFingerprint(ctx context.Context, fingerprint sql.NullString) ([]Session, error)
+	ListSessionsByMetadata(ctx context.Context, arg ListSessionsByMetadataParams) ([]Session, error)
+	SearchMessages(ctx context.Context, arg SearchMessagesParams) ([]SearchMessagesRow, error)
+	SetMessagePinned(ctx context.Context, arg SetMessagePinnedParams) error
+	SetSessionUsage(ctx context.Context, arg SetSessionUsageParams) (Session, error)
 	UpdateFile(ctx context.Context, arg UpdateFileParams) (File, error)
 	UpdateMessage(ctx context.Context, arg UpdateMessageParams) error
 	UpdateSession(ctx context.Context, arg UpdateSessionParams) (Session, error)
+	UpdateSessionFingerprint(ctx context.Context, arg UpdateSessionFingerprintParams) (Session, error)
+	UpdateSessionMessageCount(ctx context.Context, arg UpdateSessionMessageCountParams) (Session, error)
+	UpdateSessionMetadata(ctx context.Context, arg UpdateSessionMetadataParams) (Session, error)
+	UpdateSessionTitle(ctx context.Context, arg UpdateSessionTitleParams) (Session, error)
 }
 
 var _ Querier = (*Queries)(nil)
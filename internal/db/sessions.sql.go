@@ -10,6 +10,49 @@ import (
 	"database/sql"
 )
 
+const addSessionUsage = `-- name: AddSessionUsage :one
+UPDATE sessions
+SET
+    prompt_tokens = prompt_tokens + ?1,
+    completion_tokens = completion_tokens + ?2,
+    cost = cost + ?3
+WHERE id = ?4
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, fingerprint, last_message_at, metadata
+`
+
+type AddSessionUsageParams struct {
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	ID               string  `json:"id"`
+}
+
+func (q *Queries) AddSessionUsage(ctx context.Context, arg AddSessionUsageParams) (Session, error) {
+	row := q.queryRow(ctx, q.addSessionUsageStmt, addSessionUsage,
+		arg.PromptTokens,
+		arg.CompletionTokens,
+		arg.Cost,
+		arg.ID,
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.ParentSessionID,
+		&i.Title,
+		&i.MessageCount,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.Cost,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.SummaryMessageID,
+		&i.Fingerprint,
+		&i.LastMessageAt,
+		&i.Metadata,
+	)
+	return i, err
+}
+
 const createSession = `-- name: CreateSession :one
 INSERT INTO sessions (
     id,
@@ -19,6 +62,7 @@ INSERT INTO sessions (
     prompt_tokens,
     completion_tokens,
     cost,
+    fingerprint,
     summary_message_id,
     updated_at,
     created_at
@@ -30,10 +74,11 @@ INSERT INTO sessions (
     ?,
     ?,
     ?,
+    ?,
     null,
     strftime('%s', 'now'),
     strftime('%s', 'now')
-) RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+) RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, fingerprint, last_message_at, metadata
 `
 
 type CreateSessionParams struct {
@@ -44,6 +89,7 @@ type CreateSessionParams struct {
 	PromptTokens     int64          `json:"prompt_tokens"`
 	CompletionTokens int64          `json:"completion_tokens"`
 	Cost             float64        `json:"cost"`
+	Fingerprint      sql.NullString `json:"fingerprint"`
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
@@ -55,6 +101,7 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		arg.PromptTokens,
 		arg.CompletionTokens,
 		arg.Cost,
+		arg.Fingerprint,
 	)
 	var i Session
 	err := row.Scan(
@@ -68,6 +115,9 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.Fingerprint,
+		&i.LastMessageAt,
+		&i.Metadata,
 	)
 	return i, err
 }
@@ -82,8 +132,37 @@ func (q *Queries) DeleteSession(ctx context.Context, id string) error {
 	return err
 }
 
+const getMostRecentSession = `-- name: GetMostRecentSession :one
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, fingerprint, last_message_at, metadata
+FROM sessions
+WHERE parent_session_id is NULL
+ORDER BY updated_at DESC, created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetMostRecentSession(ctx context.Context) (Session, error) {
+	row := q.queryRow(ctx, q.getMostRecentSessionStmt, getMostRecentSession)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.ParentSessionID,
+		&i.Title,
+		&i.MessageCount,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.Cost,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.SummaryMessageID,
+		&i.Fingerprint,
+		&i.LastMessageAt,
+		&i.Metadata,
+	)
+	return i, err
+}
+
 const getSessionByID = `-- name: GetSessionByID :one
-SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, fingerprint, last_message_at, metadata
 FROM sessions
 WHERE id = ? LIMIT 1
 `
@@ -102,12 +181,60 @@ func (q *Queries) GetSessionByID(ctx context.Context, id string) (Session, error
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.Fingerprint,
+		&i.LastMessageAt,
+		&i.Metadata,
 	)
 	return i, err
 }
 
+const listRecentSessions = `-- name: ListRecentSessions :many
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, fingerprint, last_message_at, metadata
+FROM sessions
+WHERE parent_session_id is NULL
+ORDER BY COALESCE(last_message_at, created_at) DESC, updated_at DESC
+LIMIT ?
+`
+
+func (q *Queries) ListRecentSessions(ctx context.Context, limit int64) ([]Session, error) {
+	rows, err := q.query(ctx, q.listRecentSessionsStmt, listRecentSessions, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentSessionID,
+			&i.Title,
+			&i.MessageCount,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.Cost,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.SummaryMessageID,
+			&i.Fingerprint,
+			&i.LastMessageAt,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listSessions = `-- name: ListSessions :many
-SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, fingerprint, last_message_at, metadata
 FROM sessions
 WHERE parent_session_id is NULL
 ORDER BY created_at DESC
@@ -133,6 +260,58 @@ func (q *Queries) ListSessions(ctx context.Context) ([]Session, error) {
 			&i.UpdatedAt,
 			&i.CreatedAt,
 			&i.SummaryMessageID,
+			&i.Fingerprint,
+			&i.LastMessageAt,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSessionsByMetadata = `-- name: ListSessionsByMetadata :many
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, fingerprint, last_message_at, metadata
+FROM sessions
+WHERE json_extract(metadata, ?1) = ?2
+ORDER BY COALESCE(last_message_at, created_at) DESC, updated_at DESC
+`
+
+type ListSessionsByMetadataParams struct {
+	Path  string `json:"path"`
+	Value string `json:"value"`
+}
+
+func (q *Queries) ListSessionsByMetadata(ctx context.Context, arg ListSessionsByMetadataParams) ([]Session, error) {
+	rows, err := q.query(ctx, q.listSessionsByMetadataStmt, listSessionsByMetadata, arg.Path, arg.Value)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentSessionID,
+			&i.Title,
+			&i.MessageCount,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.Cost,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.SummaryMessageID,
+			&i.Fingerprint,
+			&i.LastMessageAt,
+			&i.Metadata,
 		); err != nil {
 			return nil, err
 		}
@@ -147,6 +326,93 @@ func (q *Queries) ListSessions(ctx context.Context) ([]Session, error) {
 	return items, nil
 }
 
+const listSessionsByFingerprint = `-- name: ListSessionsByFingerprint :many
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, fingerprint, last_message_at, metadata
+FROM sessions
+WHERE fingerprint = ? AND parent_session_id is NULL
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListSessionsByFingerprint(ctx context.Context, fingerprint sql.NullString) ([]Session, error) {
+	rows, err := q.query(ctx, q.listSessionsByFingerprintStmt, listSessionsByFingerprint, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentSessionID,
+			&i.Title,
+			&i.MessageCount,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.Cost,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.SummaryMessageID,
+			&i.Fingerprint,
+			&i.LastMessageAt,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setSessionUsage = `-- name: SetSessionUsage :one
+UPDATE sessions
+SET
+    prompt_tokens = ?1,
+    completion_tokens = ?2,
+    cost = cost + ?3
+WHERE id = ?4
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, fingerprint, last_message_at, metadata
+`
+
+type SetSessionUsageParams struct {
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	ID               string  `json:"id"`
+}
+
+func (q *Queries) SetSessionUsage(ctx context.Context, arg SetSessionUsageParams) (Session, error) {
+	row := q.queryRow(ctx, q.setSessionUsageStmt, setSessionUsage,
+		arg.PromptTokens,
+		arg.CompletionTokens,
+		arg.Cost,
+		arg.ID,
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.ParentSessionID,
+		&i.Title,
+		&i.MessageCount,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.Cost,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.SummaryMessageID,
+		&i.Fingerprint,
+		&i.LastMessageAt,
+		&i.Metadata,
+	)
+	return i, err
+}
+
 const updateSession = `-- name: UpdateSession :one
 UPDATE sessions
 SET
@@ -156,7 +422,7 @@ SET
     summary_message_id = ?,
     cost = ?
 WHERE id = ?
-RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, fingerprint, last_message_at, metadata
 `
 
 type UpdateSessionParams struct {
@@ -189,6 +455,144 @@ func (q *Queries) UpdateSession(ctx context.Context, arg UpdateSessionParams) (S
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.Fingerprint,
+		&i.LastMessageAt,
+		&i.Metadata,
+	)
+	return i, err
+}
+
+const updateSessionFingerprint = `-- name: UpdateSessionFingerprint :one
+UPDATE sessions
+SET fingerprint = ?
+WHERE id = ?
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, fingerprint, last_message_at, metadata
+`
+
+type UpdateSessionFingerprintParams struct {
+	Fingerprint sql.NullString `json:"fingerprint"`
+	ID          string         `json:"id"`
+}
+
+func (q *Queries) UpdateSessionFingerprint(ctx context.Context, arg UpdateSessionFingerprintParams) (Session, error) {
+	row := q.queryRow(ctx, q.updateSessionFingerprintStmt, updateSessionFingerprint, arg.Fingerprint, arg.ID)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.ParentSessionID,
+		&i.Title,
+		&i.MessageCount,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.Cost,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.SummaryMessageID,
+		&i.Fingerprint,
+		&i.LastMessageAt,
+		&i.Metadata,
+	)
+	return i, err
+}
+
+const updateSessionMessageCount = `-- name: UpdateSessionMessageCount :one
+UPDATE sessions
+SET message_count = ?
+WHERE id = ?
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, fingerprint, last_message_at, metadata
+`
+
+type UpdateSessionMessageCountParams struct {
+	MessageCount int64  `json:"message_count"`
+	ID           string `json:"id"`
+}
+
+func (q *Queries) UpdateSessionMessageCount(ctx context.Context, arg UpdateSessionMessageCountParams) (Session, error) {
+	row := q.queryRow(ctx, q.updateSessionMessageCountStmt, updateSessionMessageCount, arg.MessageCount, arg.ID)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.ParentSessionID,
+		&i.Title,
+		&i.MessageCount,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.Cost,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.SummaryMessageID,
+		&i.Fingerprint,
+		&i.LastMessageAt,
+		&i.Metadata,
+	)
+	return i, err
+}
+
+const updateSessionTitle = `-- name: UpdateSessionTitle :one
+UPDATE sessions
+SET title = ?
+WHERE id = ?
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, fingerprint, last_message_at, metadata
+`
+
+type UpdateSessionTitleParams struct {
+	Title string `json:"title"`
+	ID    string `json:"id"`
+}
+
+func (q *Queries) UpdateSessionTitle(ctx context.Context, arg UpdateSessionTitleParams) (Session, error) {
+	row := q.queryRow(ctx, q.updateSessionTitleStmt, updateSessionTitle, arg.Title, arg.ID)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.ParentSessionID,
+		&i.Title,
+		&i.MessageCount,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.Cost,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.SummaryMessageID,
+		&i.Fingerprint,
+		&i.LastMessageAt,
+		&i.Metadata,
+	)
+	return i, err
+}
+
+const updateSessionMetadata = `-- name: UpdateSessionMetadata :one
+UPDATE sessions
+SET metadata = CASE
+    WHEN ?1 IS NULL THEN NULL
+    ELSE nullif(json_patch(COALESCE(metadata, '{}'), ?1), '{}')
+END
+WHERE id = ?2
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, fingerprint, last_message_at, metadata
+`
+
+type UpdateSessionMetadataParams struct {
+	Patch sql.NullString `json:"patch"`
+	ID    string         `json:"id"`
+}
+
+func (q *Queries) UpdateSessionMetadata(ctx context.Context, arg UpdateSessionMetadataParams) (Session, error) {
+	row := q.queryRow(ctx, q.updateSessionMetadataStmt, updateSessionMetadata, arg.Patch, arg.ID)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.ParentSessionID,
+		&i.Title,
+		&i.MessageCount,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.Cost,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.SummaryMessageID,
+		&i.Fingerprint,
+		&i.LastMessageAt,
+		&i.Metadata,
 	)
 	return i, err
 }
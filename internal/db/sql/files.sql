@@ -29,10 +29,11 @@ INSERT INTO files (
     path,
     content,
     version,
+    compressed,
     created_at,
     updated_at
 ) VALUES (
-    ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now')
+    ?, ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now')
 )
 RETURNING *;
 
@@ -41,6 +42,7 @@ UPDATE files
 SET
     content = ?,
     version = ?,
+    compressed = ?,
     updated_at = strftime('%s', 'now')
 WHERE id = ?
 RETURNING *;
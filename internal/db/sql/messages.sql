@@ -9,6 +9,15 @@ FROM messages
 WHERE session_id = ?
 ORDER BY created_at ASC;
 
+-- name: ListMessagesAfter :many
+SELECT *
+FROM messages
+WHERE session_id = sqlc.arg(session_id)
+  AND (created_at, rowid) > (
+    SELECT created_at, rowid FROM messages WHERE id = sqlc.arg(message_id)
+  )
+ORDER BY created_at ASC, rowid ASC;
+
 -- name: CreateMessage :one
 INSERT INTO messages (
     id,
@@ -31,6 +40,10 @@ SET
     updated_at = strftime('%s', 'now')
 WHERE id = ?;
 
+-- name: SetMessagePinned :exec
+UPDATE messages
+SET pinned = ?
+WHERE id = ?;
 
 -- name: DeleteMessage :exec
 DELETE FROM messages
@@ -39,3 +52,15 @@ WHERE id = ?;
 -- name: DeleteSessionMessages :exec
 DELETE FROM messages
 WHERE session_id = ?;
+
+-- name: SearchMessages :many
+SELECT
+    m.id,
+    m.session_id,
+    CAST(snippet(messages_fts, 2, '', '', '...', 16) AS TEXT) AS snippet,
+    m.created_at
+FROM messages_fts
+JOIN messages AS m ON m.id = messages_fts.message_id
+WHERE messages_fts MATCH sqlc.arg(query)
+ORDER BY m.created_at DESC
+LIMIT sqlc.arg(limit);
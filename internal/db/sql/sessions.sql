@@ -7,6 +7,7 @@ INSERT INTO sessions (
     prompt_tokens,
     completion_tokens,
     cost,
+    fingerprint,
     summary_message_id,
     updated_at,
     created_at
@@ -18,6 +19,7 @@ INSERT INTO sessions (
     ?,
     ?,
     ?,
+    ?,
     null,
     strftime('%s', 'now'),
     strftime('%s', 'now')
@@ -49,3 +51,74 @@ RETURNING *;
 -- name: DeleteSession :exec
 DELETE FROM sessions
 WHERE id = ?;
+
+-- name: UpdateSessionFingerprint :one
+UPDATE sessions
+SET fingerprint = ?
+WHERE id = ?
+RETURNING *;
+
+-- name: ListSessionsByFingerprint :many
+SELECT *
+FROM sessions
+WHERE fingerprint = ? AND parent_session_id is NULL
+ORDER BY created_at DESC;
+
+-- name: GetMostRecentSession :one
+SELECT *
+FROM sessions
+WHERE parent_session_id is NULL
+ORDER BY updated_at DESC, created_at DESC
+LIMIT 1;
+
+-- name: AddSessionUsage :one
+UPDATE sessions
+SET
+    prompt_tokens = prompt_tokens + sqlc.arg(prompt_tokens),
+    completion_tokens = completion_tokens + sqlc.arg(completion_tokens),
+    cost = cost + sqlc.arg(cost)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: SetSessionUsage :one
+UPDATE sessions
+SET
+    prompt_tokens = sqlc.arg(prompt_tokens),
+    completion_tokens = sqlc.arg(completion_tokens),
+    cost = cost + sqlc.arg(cost)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ListRecentSessions :many
+SELECT *
+FROM sessions
+WHERE parent_session_id is NULL
+ORDER BY COALESCE(last_message_at, created_at) DESC, updated_at DESC
+LIMIT ?;
+
+-- name: UpdateSessionMessageCount :one
+UPDATE sessions
+SET message_count = ?
+WHERE id = ?
+RETURNING *;
+
+-- name: UpdateSessionTitle :one
+UPDATE sessions
+SET title = ?
+WHERE id = ?
+RETURNING *;
+
+-- name: UpdateSessionMetadata :one
+UPDATE sessions
+SET metadata = CASE
+    WHEN sqlc.narg(patch) IS NULL THEN NULL
+    ELSE nullif(json_patch(COALESCE(metadata, '{}'), sqlc.narg(patch)), '{}')
+END
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ListSessionsByMetadata :many
+SELECT *
+FROM sessions
+WHERE json_extract(metadata, sqlc.arg(path)) = sqlc.arg(value)
+ORDER BY COALESCE(last_message_at, created_at) DESC, updated_at DESC;
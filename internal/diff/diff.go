@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -18,6 +20,7 @@ import (
 	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/tui/theme"
 	"github.com/sergi/go-diff/diffmatchpatch"
+	"golang.org/x/term"
 )
 
 // -------------------------------------------------------------------------
@@ -33,34 +36,62 @@ const (
 	LineRemoved                 // Line removed from the old file
 )
 
+var lineTypeNames = map[LineType]string{
+	LineContext: "context",
+	LineAdded:   "added",
+	LineRemoved: "removed",
+}
+
+// MarshalText encodes the line type by name so that JSON diffs stay readable
+// and stable if the constants are ever reordered.
+func (t LineType) MarshalText() ([]byte, error) {
+	name, ok := lineTypeNames[t]
+	if !ok {
+		return nil, fmt.Errorf("unknown line type: %d", t)
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText decodes a line type encoded by MarshalText.
+func (t *LineType) UnmarshalText(text []byte) error {
+	for lineType, name := range lineTypeNames {
+		if name == string(text) {
+			*t = lineType
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown line type: %q", text)
+}
+
 // Segment represents a portion of a line for intra-line highlighting
 type Segment struct {
-	Start int
-	End   int
-	Type  LineType
-	Text  string
+	Start int      `json:"start"`
+	End   int      `json:"end"`
+	Type  LineType `json:"type"`
+	Text  string   `json:"text"`
 }
 
 // DiffLine represents a single line in a diff
 type DiffLine struct {
-	OldLineNo int       // Line number in old file (0 for added lines)
-	NewLineNo int       // Line number in new file (0 for removed lines)
-	Kind      LineType  // Type of line (added, removed, context)
-	Content   string    // Content of the line
-	Segments  []Segment // Segments for intraline highlighting
+	OldLineNo int       `json:"old_line,omitempty"` // Line number in old file (0 for added lines)
+	NewLineNo int       `json:"new_line,omitempty"` // Line number in new file (0 for removed lines)
+	Kind      LineType  `json:"kind"`               // Type of line (added, removed, context)
+	Content   string    `json:"content"`            // Content of the line
+	Segments  []Segment `json:"segments"`           // Segments for intraline highlighting
 }
 
 // Hunk represents a section of changes in a diff
 type Hunk struct {
-	Header string
-	Lines  []DiffLine
+	Header string     `json:"header"`
+	Lines  []DiffLine `json:"lines"`
 }
 
-// DiffResult contains the parsed result of a diff
+// DiffResult contains the parsed result of a diff. It encodes to a stable
+// JSON document so clients can render diffs without parsing unified text.
 type DiffResult struct {
-	OldFile string
-	NewFile string
-	Hunks   []Hunk
+	OldFile string `json:"old_file"`
+	NewFile string `json:"new_file"`
+	Hunks   []Hunk `json:"hunks"`
 }
 
 // linePair represents a pair of lines for side-by-side display
@@ -97,6 +128,44 @@ func WithContextSize(size int) ParseOption {
 // SideBySideConfig configures the rendering of side-by-side diffs
 type SideBySideConfig struct {
 	TotalWidth int
+	AutoWidth  bool // Use the terminal width, detected when rendering
+	NoColor    bool // Render without ANSI styling, for output that isn't a terminal
+	// NoLineNumbers leaves out the gutter of old and new line numbers
+	NoLineNumbers bool
+
+	widthSet bool // TotalWidth was set explicitly and wins over AutoWidth
+}
+
+// terminalWidth reports the width of the terminal attached to stdout
+var terminalWidth = func() (int, bool) {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return 0, false
+	}
+	return width, true
+}
+
+// stdoutIsTerminal reports whether stdout is attached to a terminal
+var stdoutIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// colorByDefault reports whether diffs are styled when no color option is
+// given: only when stdout is a terminal and NO_COLOR isn't set, so redirected
+// output stays free of escape codes
+func colorByDefault() bool {
+	return os.Getenv("NO_COLOR") == "" && stdoutIsTerminal()
+}
+
+// width returns the width to render at, detecting the terminal width when
+// AutoWidth is set and no explicit width was given
+func (c SideBySideConfig) width() int {
+	if c.AutoWidth && !c.widthSet {
+		if width, ok := terminalWidth(); ok {
+			return width
+		}
+	}
+	return c.TotalWidth
 }
 
 // SideBySideOption modifies a SideBySideConfig
@@ -106,6 +175,7 @@ type SideBySideOption func(*SideBySideConfig)
 func NewSideBySideConfig(opts ...SideBySideOption) SideBySideConfig {
 	config := SideBySideConfig{
 		TotalWidth: 160, // Default width for side-by-side view
+		NoColor:    !colorByDefault(),
 	}
 
 	for _, opt := range opts {
@@ -120,21 +190,84 @@ func WithTotalWidth(width int) SideBySideOption {
 	return func(s *SideBySideConfig) {
 		if width > 0 {
 			s.TotalWidth = width
+			s.widthSet = true
 		}
 	}
 }
 
+// WithAutoWidth sizes the side-by-side view to the terminal at render time,
+// falling back to the default width when stdout is not a terminal
+func WithAutoWidth() SideBySideOption {
+	return func(s *SideBySideConfig) {
+		s.AutoWidth = true
+	}
+}
+
+// WithNoColor renders plain text without ANSI styling
+func WithNoColor() SideBySideOption {
+	return func(s *SideBySideConfig) {
+		s.NoColor = true
+	}
+}
+
+// WithColor renders with ANSI styling even when stdout is not a terminal
+func WithColor() SideBySideOption {
+	return func(s *SideBySideConfig) {
+		s.NoColor = false
+	}
+}
+
+// WithNoLineNumbers renders each side without its line number gutter, leaving
+// more of the width for content
+func WithNoLineNumbers() SideBySideOption {
+	return func(s *SideBySideConfig) {
+		s.NoLineNumbers = true
+	}
+}
+
+// -------------------------------------------------------------------------
+// Generate Configuration
+// -------------------------------------------------------------------------
+
+// GenerateConfig configures the generation of unified diffs
+type GenerateConfig struct {
+	FunctionSplit bool // Split hunks at function/block boundaries
+	ContextLines  int  // Unchanged lines around each change
+}
+
+// GenerateOption modifies a GenerateConfig
+type GenerateOption func(*GenerateConfig)
+
+// WithFunctionSplit splits hunks at top-level function/block boundaries so
+// each logical unit is its own hunk
+func WithFunctionSplit() GenerateOption {
+	return func(g *GenerateConfig) {
+		g.FunctionSplit = true
+	}
+}
+
+// WithContextLines sets how many unchanged lines surround each change; a
+// negative count is treated as zero
+func WithContextLines(lines int) GenerateOption {
+	return func(g *GenerateConfig) {
+		g.ContextLines = max(lines, 0)
+	}
+}
+
 // -------------------------------------------------------------------------
 // Diff Parsing
 // -------------------------------------------------------------------------
 
+// hunkHeaderRe matches a unified diff hunk header
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+),?(\d*) \+(\d+),?(\d*) @@`)
+
 // ParseUnifiedDiff parses a unified diff format string into structured data
 func ParseUnifiedDiff(diff string) (DiffResult, error) {
 	var result DiffResult
 	var currentHunk *Hunk
 
-	hunkHeaderRe := regexp.MustCompile(`^@@ -(\d+),?(\d*) \+(\d+),?(\d*) @@`)
-	lines := strings.Split(diff, "\n")
+	// A trailing newline ends the last line rather than starting an empty one
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
 
 	var oldLine, newLine int
 	inFileHeader := true
@@ -229,6 +362,146 @@ func ParseUnifiedDiff(diff string) (DiffResult, error) {
 	return result, nil
 }
 
+// -------------------------------------------------------------------------
+// Context Expansion
+// -------------------------------------------------------------------------
+
+// hunkSpan describes the lines a hunk covers in the old and new file
+type hunkSpan struct {
+	oldFirst, oldCount int
+	newFirst, newCount int
+}
+
+func newHunkSpan(h Hunk) (hunkSpan, bool) {
+	matches := hunkHeaderRe.FindStringSubmatch(h.Header)
+	if matches == nil {
+		return hunkSpan{}, false
+	}
+	oldStart, _ := strconv.Atoi(matches[1])
+	newStart, _ := strconv.Atoi(matches[3])
+
+	var span hunkSpan
+	for _, l := range h.Lines {
+		if l.Kind != LineAdded {
+			span.oldCount++
+		}
+		if l.Kind != LineRemoved {
+			span.newCount++
+		}
+	}
+	// An empty side names the line the change comes after
+	span.oldFirst, span.newFirst = oldStart, newStart
+	if span.oldCount == 0 {
+		span.oldFirst++
+	}
+	if span.newCount == 0 {
+		span.newFirst++
+	}
+	return span, true
+}
+
+// hunkGroup is a run of hunks that share context once expanded, covering
+// old lines lo through hi
+type hunkGroup struct {
+	lo, hi int
+	hunks  []Hunk
+	spans  []hunkSpan
+}
+
+// build joins the group's hunks into one, filling the gaps between them with
+// context from the old file
+func (g *hunkGroup) build(origLines []string) Hunk {
+	var lines []DiffLine
+	addContext := func(from, to, delta int) {
+		for old := from; old <= to && old <= len(origLines); old++ {
+			lines = append(lines, DiffLine{
+				OldLineNo: old,
+				NewLineNo: old + delta,
+				Kind:      LineContext,
+				Content:   " " + origLines[old-1],
+			})
+		}
+	}
+
+	cursor := g.lo
+	for i, h := range g.hunks {
+		span := g.spans[i]
+		addContext(cursor, span.oldFirst-1, span.newFirst-span.oldFirst)
+		lines = append(lines, h.Lines...)
+		cursor = span.oldFirst + span.oldCount
+	}
+	last := g.spans[len(g.spans)-1]
+	addContext(cursor, g.hi, (last.newFirst+last.newCount)-(last.oldFirst+last.oldCount))
+
+	first := g.spans[0]
+	newLo := g.lo + first.newFirst - first.oldFirst
+	var oldCount, newCount int
+	for _, l := range lines {
+		if l.Kind != LineAdded {
+			oldCount++
+		}
+		if l.Kind != LineRemoved {
+			newCount++
+		}
+	}
+	oldLo := g.lo
+	if oldCount == 0 {
+		oldLo--
+	}
+	if newCount == 0 {
+		newLo--
+	}
+	return Hunk{
+		Header: fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldLo, oldCount, newLo, newCount),
+		Lines:  lines,
+	}
+}
+
+// ExpandContext returns a copy of the diff with every hunk widened by up to
+// lines extra lines of context taken from orig, the content of the old file.
+// Hunks that touch or overlap after widening are merged, and the context is
+// clamped to the bounds of the file.
+func (r DiffResult) ExpandContext(orig string, lines int) DiffResult {
+	lines = max(lines, 0)
+	origLines := strings.Split(orig, "\n")
+	if len(origLines) > 0 && origLines[len(origLines)-1] == "" {
+		origLines = origLines[:len(origLines)-1]
+	}
+
+	result := DiffResult{OldFile: r.OldFile, NewFile: r.NewFile}
+	var groups []*hunkGroup
+	flush := func() {
+		for _, g := range groups {
+			result.Hunks = append(result.Hunks, g.build(origLines))
+		}
+		groups = nil
+	}
+
+	for _, h := range r.Hunks {
+		span, ok := newHunkSpan(h)
+		if !ok {
+			// Hunks we can't place are passed through untouched
+			flush()
+			result.Hunks = append(result.Hunks, Hunk{Header: h.Header, Lines: slices.Clone(h.Lines)})
+			continue
+		}
+
+		lo := min(max(1, span.oldFirst-lines), span.oldFirst)
+		hi := max(min(len(origLines), span.oldFirst+span.oldCount-1+lines), span.oldFirst+span.oldCount-1)
+		if n := len(groups); n > 0 && lo <= groups[n-1].hi+1 {
+			last := groups[n-1]
+			last.hi = max(last.hi, hi)
+			last.hunks = append(last.hunks, h)
+			last.spans = append(last.spans, span)
+			continue
+		}
+		groups = append(groups, &hunkGroup{lo: lo, hi: hi, hunks: []Hunk{h}, spans: []hunkSpan{span}})
+	}
+	flush()
+
+	return result
+}
+
 // HighlightIntralineChanges updates lines in a hunk to show character-level differences
 func HighlightIntralineChanges(h *Hunk) {
 	var updated []DiffLine
@@ -677,8 +950,38 @@ func applyHighlighting(content string, segments []Segment, segmentType LineType,
 	return sb.String()
 }
 
+// minGutterWidth is the narrowest line number gutter, enough for files of up
+// to a million lines
+const minGutterWidth = 6
+
+// gutterWidth returns how wide the line numbers of a hunk are rendered: wide
+// enough for the largest of them, so every line of the hunk stays aligned
+func gutterWidth(h Hunk) int {
+	width := minGutterWidth
+	for _, dl := range h.Lines {
+		for _, n := range []int{dl.OldLineNo, dl.NewLineNo} {
+			width = max(width, len(strconv.Itoa(n)))
+		}
+	}
+	return width
+}
+
+// formatLineNo right-aligns a line number in a gutter of the given width,
+// leaving it blank for lines missing from that side. A zero width means there
+// is no gutter.
+func formatLineNo(lineNo, width int) string {
+	switch {
+	case width == 0:
+		return ""
+	case lineNo > 0:
+		return fmt.Sprintf("%*d ", width, lineNo)
+	default:
+		return strings.Repeat(" ", width+1)
+	}
+}
+
 // renderLeftColumn formats the left side of a side-by-side diff
-func renderLeftColumn(fileName string, dl *DiffLine, colWidth int) string {
+func renderLeftColumn(fileName string, dl *DiffLine, colWidth, gutter int) string {
 	t := theme.CurrentTheme()
 
 	if dl == nil {
@@ -704,14 +1007,8 @@ func renderLeftColumn(fileName string, dl *DiffLine, colWidth int) string {
 		bgStyle = contextLineStyle
 	}
 
-	// Format line number
-	lineNum := ""
-	if dl.OldLineNo > 0 {
-		lineNum = fmt.Sprintf("%6d", dl.OldLineNo)
-	}
-
 	// Create the line prefix
-	prefix := lineNumberStyle.Render(lineNum + " " + marker)
+	prefix := lineNumberStyle.Render(formatLineNo(dl.OldLineNo, gutter) + marker)
 
 	// Apply syntax highlighting
 	content := highlightLine(fileName, dl.Content, bgStyle.GetBackground())
@@ -738,7 +1035,7 @@ func renderLeftColumn(fileName string, dl *DiffLine, colWidth int) string {
 }
 
 // renderRightColumn formats the right side of a side-by-side diff
-func renderRightColumn(fileName string, dl *DiffLine, colWidth int) string {
+func renderRightColumn(fileName string, dl *DiffLine, colWidth, gutter int) string {
 	t := theme.CurrentTheme()
 
 	if dl == nil {
@@ -764,14 +1061,8 @@ func renderRightColumn(fileName string, dl *DiffLine, colWidth int) string {
 		bgStyle = contextLineStyle
 	}
 
-	// Format line number
-	lineNum := ""
-	if dl.NewLineNo > 0 {
-		lineNum = fmt.Sprintf("%6d", dl.NewLineNo)
-	}
-
 	// Create the line prefix
-	prefix := lineNumberStyle.Render(lineNum + " " + marker)
+	prefix := lineNumberStyle.Render(formatLineNo(dl.NewLineNo, gutter) + marker)
 
 	// Apply syntax highlighting
 	content := highlightLine(fileName, dl.Content, bgStyle.GetBackground())
@@ -797,6 +1088,41 @@ func renderRightColumn(fileName string, dl *DiffLine, colWidth int) string {
 	)
 }
 
+// plainTabWidth is how many spaces a tab expands to in plain output, matching
+// the styled rendering
+const plainTabWidth = 4
+
+// renderPlainColumn formats one side of a side-by-side diff without styling,
+// laid out like the styled columns: line number, marker, then the content,
+// truncated and padded to colWidth. side is LineRemoved for the left column
+// and LineAdded for the right.
+func renderPlainColumn(dl *DiffLine, side LineType, colWidth, gutter int) string {
+	if dl == nil {
+		return strings.Repeat(" ", colWidth)
+	}
+
+	lineNo, marker := dl.NewLineNo, "+"
+	if side == LineRemoved {
+		lineNo, marker = dl.OldLineNo, "-"
+	}
+	content := strings.ReplaceAll(dl.Content, "\t", strings.Repeat(" ", plainTabWidth))
+	switch dl.Kind {
+	case side:
+		// Add a padding space like the styled changed lines
+		content = " " + content
+	case LineContext:
+		marker = " "
+	default:
+		marker = "?"
+	}
+
+	lineText := ansi.Truncate(formatLineNo(lineNo, gutter)+marker+content, colWidth, "...")
+	if pad := colWidth - ansi.StringWidth(lineText); pad > 0 {
+		lineText += strings.Repeat(" ", pad)
+	}
+	return lineText
+}
+
 // -------------------------------------------------------------------------
 // Public API
 // -------------------------------------------------------------------------
@@ -817,21 +1143,36 @@ func RenderSideBySideHunk(fileName string, h Hunk, opts ...SideBySideOption) str
 	pairs := pairLines(hunkCopy.Lines)
 
 	// Calculate column width
-	colWidth := config.TotalWidth / 2
+	totalWidth := config.width()
+	colWidth := totalWidth / 2
 
 	leftWidth := colWidth
-	rightWidth := config.TotalWidth - colWidth
+	rightWidth := totalWidth - colWidth
+	gutter := 0
+	if !config.NoLineNumbers {
+		gutter = gutterWidth(hunkCopy)
+	}
 	var sb strings.Builder
+	if config.NoColor {
+		for _, p := range pairs {
+			leftStr := renderPlainColumn(p.left, LineRemoved, leftWidth, gutter)
+			rightStr := renderPlainColumn(p.right, LineAdded, rightWidth, gutter)
+			sb.WriteString(strings.TrimRight(leftStr+rightStr, " ") + "\n")
+		}
+		return sb.String()
+	}
 	for _, p := range pairs {
-		leftStr := renderLeftColumn(fileName, p.left, leftWidth)
-		rightStr := renderRightColumn(fileName, p.right, rightWidth)
+		leftStr := renderLeftColumn(fileName, p.left, leftWidth, gutter)
+		rightStr := renderRightColumn(fileName, p.right, rightWidth, gutter)
 		sb.WriteString(leftStr + rightStr + "\n")
 	}
 
 	return sb.String()
 }
 
-// FormatDiff creates a side-by-side formatted view of a diff
+// FormatDiff creates a side-by-side formatted view of a diff. It is styled
+// with ANSI escapes only when stdout is a terminal, unless WithColor or
+// WithNoColor says otherwise.
 func FormatDiff(diffText string, opts ...SideBySideOption) (string, error) {
 	diffResult, err := ParseUnifiedDiff(diffText)
 	if err != nil {
@@ -846,6 +1187,12 @@ func FormatDiff(diffText string, opts ...SideBySideOption) (string, error) {
 	return sb.String(), nil
 }
 
+// FormatDiffPlain creates the same side-by-side view as FormatDiff as plain
+// text, for writing to files and pipes
+func FormatDiffPlain(diffText string, opts ...SideBySideOption) (string, error) {
+	return FormatDiff(diffText, append(opts, WithNoColor())...)
+}
+
 // GenerateDiff creates a unified diff from two file contents
 func GenerateDiff(beforeContent, afterContent, fileName string) (string, int, int) {
 	// remove the cwd prefix and ensure consistent path format
@@ -855,7 +1202,7 @@ func GenerateDiff(beforeContent, afterContent, fileName string) (string, int, in
 	fileName = strings.TrimPrefix(fileName, "/")
 
 	var (
-		unified   = udiff.Unified("a/"+fileName, "b/"+fileName, beforeContent, afterContent)
+		unified   = GenerateUnifiedDiff(beforeContent, afterContent, fileName)
 		additions = 0
 		removals  = 0
 	)
@@ -871,3 +1218,101 @@ func GenerateDiff(beforeContent, afterContent, fileName string) (string, int, in
 
 	return unified, additions, removals
 }
+
+// GenerateUnifiedDiff creates a unified diff between two contents, labelling
+// the sides a/fileName and b/fileName. It returns an empty string when the
+// contents are equal.
+func GenerateUnifiedDiff(beforeContent, afterContent, fileName string, opts ...GenerateOption) string {
+	config := GenerateConfig{ContextLines: udiff.DefaultContextLines}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return generateUnified("a/"+fileName, "b/"+fileName, beforeContent, afterContent, config)
+}
+
+// GenerateUnified creates a unified diff between two contents with
+// contextLines of unchanged lines around each change; a negative count is
+// treated as zero. The names are used as given in the "---" and "+++"
+// headers, so pass "a/path" and "b/path" for output that ParseUnifiedDiff
+// reads back. The output depends only on the inputs, and is empty when the
+// contents are equal.
+func GenerateUnified(oldName, newName, oldContent, newContent string, contextLines int) string {
+	config := GenerateConfig{}
+	WithContextLines(contextLines)(&config)
+	return generateUnified(oldName, newName, oldContent, newContent, config)
+}
+
+func generateUnified(oldName, newName, oldContent, newContent string, config GenerateConfig) string {
+	edits := udiff.Strings(oldContent, newContent)
+	unified, err := udiff.ToUnifiedDiff(oldName, newName, oldContent, edits, config.ContextLines)
+	if err != nil {
+		// Can't happen: the edits are computed from oldContent.
+		return ""
+	}
+
+	if config.FunctionSplit {
+		splitAtBlocks(&unified)
+	}
+
+	return unified.String()
+}
+
+// splitAtBlocks splits every hunk at top-level block starts that sit between
+// two groups of changes, so that each function or block gets its own hunk
+// even when the changes are close enough to share context.
+func splitAtBlocks(u *udiff.UnifiedDiff) {
+	hunks := u.Hunks[:0:0]
+	for _, h := range u.Hunks {
+		lastChange := -1
+		for i, l := range h.Lines {
+			if l.Kind != udiff.Equal {
+				lastChange = i
+			}
+		}
+
+		fromLine, toLine := h.FromLine, h.ToLine
+		start := 0
+		changed := false
+		for i, l := range h.Lines {
+			if changed && i <= lastChange && h.Lines[i-1].Kind == udiff.Equal && isBlockStart(l.Content) {
+				part := *h
+				part.Lines = h.Lines[start:i]
+				part.FromLine, part.ToLine = fromLine, toLine
+				hunks = append(hunks, &part)
+
+				for _, pl := range part.Lines {
+					if pl.Kind != udiff.Insert {
+						fromLine++
+					}
+					if pl.Kind != udiff.Delete {
+						toLine++
+					}
+				}
+				start = i
+				changed = false
+			}
+			if l.Kind != udiff.Equal {
+				changed = true
+			}
+		}
+
+		part := *h
+		part.Lines = h.Lines[start:]
+		part.FromLine, part.ToLine = fromLine, toLine
+		hunks = append(hunks, &part)
+	}
+	u.Hunks = hunks
+}
+
+// isBlockStart reports whether a line opens a new top-level block, judged by
+// it being unindented and not closing a previous block.
+func isBlockStart(line string) bool {
+	if strings.TrimSpace(line) == "" {
+		return false
+	}
+	switch line[0] {
+	case ' ', '\t', '}', ')', ']':
+		return false
+	}
+	return true
+}
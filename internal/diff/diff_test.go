@@ -0,0 +1,404 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateUnifiedDiffFunctionSplit(t *testing.T) {
+	t.Parallel()
+
+	before := `package main
+
+func first() int {
+	a := 1
+	return a
+}
+
+func second() int {
+	b := 2
+	return b
+}
+`
+	after := `package main
+
+func first() int {
+	a := 1
+	return a + 1
+}
+
+func second() int {
+	b := 3
+	return b
+}
+`
+
+	plain, err := ParseUnifiedDiff(GenerateUnifiedDiff(before, after, "main.go"))
+	require.NoError(t, err)
+	assert.Len(t, plain.Hunks, 1)
+
+	split, err := ParseUnifiedDiff(GenerateUnifiedDiff(before, after, "main.go", WithFunctionSplit()))
+	require.NoError(t, err)
+	require.Len(t, split.Hunks, 2)
+
+	assert.Equal(t, "@@ -2,6 +2,6 @@", split.Hunks[0].Header)
+	assert.Equal(t, "@@ -8,4 +8,4 @@", split.Hunks[1].Header)
+	assert.Equal(t, LineContext, split.Hunks[1].Lines[0].Kind)
+	assert.Equal(t, " func second() int {", split.Hunks[1].Lines[0].Content)
+
+	// each hunk must still apply to the right lines of the original
+	for _, h := range split.Hunks {
+		for _, l := range h.Lines {
+			if l.Kind == LineContext {
+				assert.Equal(t, strings.TrimPrefix(l.Content, " "), lineAt(before, l.OldLineNo))
+			}
+		}
+	}
+}
+
+func TestGenerateUnifiedDiffEqual(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, GenerateUnifiedDiff("same\n", "same\n", "file.txt", WithFunctionSplit()))
+}
+
+func lineAt(content string, n int) string {
+	lines := strings.Split(content, "\n")
+	if n < 1 || n > len(lines) {
+		return ""
+	}
+	return lines[n-1]
+}
+
+func TestDiffResultJSON(t *testing.T) {
+	t.Parallel()
+
+	before := "one\ntwo\nthree\n"
+	after := "one\ntwo!\nthree\nfour\n"
+	result, err := ParseUnifiedDiff(GenerateUnifiedDiff(before, after, "file.txt"))
+	require.NoError(t, err)
+	require.Len(t, result.Hunks, 1)
+	HighlightIntralineChanges(&result.Hunks[0])
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+
+	var decoded DiffResult
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, result, decoded)
+
+	var doc struct {
+		OldFile string `json:"old_file"`
+		Hunks   []struct {
+			Lines []struct {
+				Kind     string `json:"kind"`
+				OldLine  int    `json:"old_line"`
+				NewLine  int    `json:"new_line"`
+				Segments []struct {
+					Type string `json:"type"`
+					Text string `json:"text"`
+				} `json:"segments"`
+			} `json:"lines"`
+		} `json:"hunks"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "file.txt", doc.OldFile)
+	lines := doc.Hunks[0].Lines
+	assert.Equal(t, "removed", lines[1].Kind)
+	assert.Equal(t, 2, lines[1].OldLine)
+	assert.Equal(t, 0, lines[1].NewLine)
+	assert.Equal(t, "added", lines[2].Kind)
+	require.NotEmpty(t, lines[2].Segments)
+	assert.Equal(t, "added", lines[2].Segments[0].Type)
+	assert.Equal(t, "!", lines[2].Segments[0].Text)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"hunks": [{"lines": [{"kind": "moved"}]}]}`), &decoded))
+}
+
+func TestExpandContext(t *testing.T) {
+	t.Parallel()
+
+	var before, after strings.Builder
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&before, "line %d\n", i)
+		switch i {
+		case 5:
+			after.WriteString("line five\n")
+		case 15:
+			after.WriteString("line fifteen\n")
+		default:
+			fmt.Fprintf(&after, "line %d\n", i)
+		}
+	}
+
+	result, err := ParseUnifiedDiff(GenerateUnifiedDiff(before.String(), after.String(), "file.txt"))
+	require.NoError(t, err)
+	require.Len(t, result.Hunks, 2)
+
+	t.Run("widens hunks", func(t *testing.T) {
+		expanded := result.ExpandContext(before.String(), 1)
+		require.Len(t, expanded.Hunks, 2)
+		assert.Equal(t, "@@ -1,9 +1,9 @@", expanded.Hunks[0].Header)
+		assert.Equal(t, "@@ -11,9 +11,9 @@", expanded.Hunks[1].Header)
+		assert.Equal(t, " line 11", expanded.Hunks[1].Lines[0].Content)
+		assert.Equal(t, 11, expanded.Hunks[1].Lines[0].NewLineNo)
+		assert.Len(t, result.Hunks[1].Lines, 8, "the original must not change")
+	})
+
+	t.Run("merges overlapping hunks", func(t *testing.T) {
+		expanded := result.ExpandContext(before.String(), 2)
+		require.Len(t, expanded.Hunks, 1)
+		assert.Equal(t, "@@ -1,20 +1,20 @@", expanded.Hunks[0].Header)
+
+		for i, l := range expanded.Hunks[0].Lines {
+			if l.Kind == LineContext {
+				assert.Equal(t, fmt.Sprintf(" line %d", l.OldLineNo), l.Content, "line %d", i)
+				assert.Equal(t, l.OldLineNo, l.NewLineNo)
+			}
+		}
+	})
+
+	t.Run("clamps to the file", func(t *testing.T) {
+		expanded := result.ExpandContext(before.String(), 100)
+		require.Len(t, expanded.Hunks, 1)
+		assert.Equal(t, "@@ -1,20 +1,20 @@", expanded.Hunks[0].Header)
+
+		reparsed, err := ParseUnifiedDiff("--- a/file.txt\n+++ b/file.txt\n" + hunkText(expanded.Hunks[0]))
+		require.NoError(t, err)
+		assert.Equal(t, expanded.Hunks, reparsed.Hunks)
+	})
+}
+
+func hunkText(h Hunk) string {
+	var sb strings.Builder
+	sb.WriteString(h.Header + "\n")
+	for _, l := range h.Lines {
+		switch l.Kind {
+		case LineAdded:
+			sb.WriteString("+" + l.Content + "\n")
+		case LineRemoved:
+			sb.WriteString("-" + l.Content + "\n")
+		default:
+			sb.WriteString(l.Content + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func TestSideBySideAutoWidth(t *testing.T) {
+	original := terminalWidth
+	t.Cleanup(func() { terminalWidth = original })
+
+	terminalWidth = func() (int, bool) { return 90, true }
+	assert.Equal(t, 160, NewSideBySideConfig().width())
+	assert.Equal(t, 90, NewSideBySideConfig(WithAutoWidth()).width())
+	assert.Equal(t, 120, NewSideBySideConfig(WithTotalWidth(120), WithAutoWidth()).width())
+	assert.Equal(t, 120, NewSideBySideConfig(WithAutoWidth(), WithTotalWidth(120)).width())
+
+	// detection happens per render, so a resize is picked up
+	config := NewSideBySideConfig(WithAutoWidth())
+	terminalWidth = func() (int, bool) { return 70, true }
+	assert.Equal(t, 70, config.width())
+
+	terminalWidth = func() (int, bool) { return 0, false }
+	assert.Equal(t, 160, config.width())
+}
+
+func TestFormatDiffPlain(t *testing.T) {
+	diffText := "--- a/file.txt\n+++ b/file.txt\n@@ -1,3 +1,4 @@\n a\n-\tb\n+\tB\n c\n+d\n"
+
+	plain, err := FormatDiffPlain(diffText, WithTotalWidth(40))
+	require.NoError(t, err)
+	assert.NotContains(t, plain, "\x1b[")
+	assert.Equal(t, strings.Join([]string{
+		"     1   a               1   a",
+		"     2 -     b           2 +     B",
+		"     3   c               3   c",
+		"                         4 + d",
+	}, "\n")+"\n", plain)
+
+	t.Run("long lines are truncated to the column", func(t *testing.T) {
+		long := GenerateUnifiedDiff("x\n", strings.Repeat("y", 50)+"\n", "file.txt")
+		plain, err := FormatDiffPlain(long, WithTotalWidth(40))
+		require.NoError(t, err)
+		for _, line := range strings.Split(strings.TrimSuffix(plain, "\n"), "\n") {
+			assert.LessOrEqual(t, len(line), 40)
+		}
+		assert.Contains(t, plain, "...")
+	})
+
+	t.Run("without line numbers", func(t *testing.T) {
+		plain, err := FormatDiffPlain(diffText, WithTotalWidth(40), WithNoLineNumbers())
+		require.NoError(t, err)
+		assert.Equal(t, strings.Join([]string{
+			"  a                   a",
+			"-     b             +     B",
+			"  c                   c",
+			"                    + d",
+		}, "\n")+"\n", plain)
+	})
+
+	t.Run("gutter widens for large line numbers", func(t *testing.T) {
+		wide := "--- a/file.txt\n+++ b/file.txt\n@@ -9999998,2 +9999998,2 @@\n x\n-y\n+Y\n"
+		plain, err := FormatDiffPlain(wide, WithTotalWidth(60))
+		require.NoError(t, err)
+		assert.Equal(t, strings.Join([]string{
+			"9999998   x                   9999998   x",
+			"9999999 - y                   9999999 + Y",
+		}, "\n")+"\n", plain)
+
+		for _, width := range []int{20, 30} {
+			plain, err := FormatDiffPlain(wide, WithTotalWidth(width))
+			require.NoError(t, err)
+			for _, line := range strings.Split(strings.TrimSuffix(plain, "\n"), "\n") {
+				assert.LessOrEqual(t, len(line), width)
+			}
+		}
+	})
+
+	t.Run("color follows stdout by default", func(t *testing.T) {
+		original := stdoutIsTerminal
+		t.Cleanup(func() { stdoutIsTerminal = original })
+		t.Setenv("NO_COLOR", "")
+
+		stdoutIsTerminal = func() bool { return false }
+		assert.True(t, NewSideBySideConfig().NoColor)
+		assert.False(t, NewSideBySideConfig(WithColor()).NoColor)
+		formatted, err := FormatDiff(diffText, WithTotalWidth(40))
+		require.NoError(t, err)
+		assert.Equal(t, plain, formatted)
+
+		stdoutIsTerminal = func() bool { return true }
+		assert.False(t, NewSideBySideConfig().NoColor)
+		assert.True(t, NewSideBySideConfig(WithNoColor()).NoColor)
+
+		t.Setenv("NO_COLOR", "1")
+		assert.True(t, NewSideBySideConfig().NoColor)
+	})
+}
+
+// applyParsed rebuilds the new content from the old one and a parsed diff.
+func applyParsed(t *testing.T, old string, result DiffResult) string {
+	t.Helper()
+	oldLines := strings.SplitAfter(old, "\n")
+	if oldLines[len(oldLines)-1] == "" {
+		oldLines = oldLines[:len(oldLines)-1]
+	}
+	var out []string
+	next := 1
+	for _, h := range result.Hunks {
+		// copy the unchanged lines before the hunk; a hunk that removes
+		// nothing starts after its old line rather than at it
+		m := hunkHeaderRe.FindStringSubmatch(h.Header)
+		require.NotNil(t, m)
+		oldStart, _ := strconv.Atoi(m[1])
+		if m[2] != "0" {
+			oldStart--
+		}
+		for ; next <= oldStart; next++ {
+			out = append(out, strings.TrimSuffix(oldLines[next-1], "\n"))
+		}
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case LineContext:
+				require.Equal(t, strings.TrimSuffix(oldLines[l.OldLineNo-1], "\n"), strings.TrimPrefix(l.Content, " "))
+				fallthrough
+			case LineRemoved:
+				for ; next < l.OldLineNo; next++ {
+					out = append(out, strings.TrimSuffix(oldLines[next-1], "\n"))
+				}
+				next = l.OldLineNo + 1
+				if l.Kind == LineContext {
+					out = append(out, strings.TrimPrefix(l.Content, " "))
+				}
+			case LineAdded:
+				out = append(out, l.Content)
+			}
+		}
+	}
+	for ; next <= len(oldLines); next++ {
+		out = append(out, strings.TrimSuffix(oldLines[next-1], "\n"))
+	}
+	return strings.Join(out, "\n")
+}
+
+func TestGenerateUnified(t *testing.T) {
+	t.Parallel()
+
+	var oldLines, newLines []string
+	for i := 1; i <= 40; i++ {
+		oldLines = append(oldLines, fmt.Sprintf("line %d", i))
+		switch i {
+		case 3:
+			newLines = append(newLines, "line 3 changed")
+		case 20:
+			// removed
+		case 35:
+			newLines = append(newLines, "inserted", "line 35")
+		default:
+			newLines = append(newLines, oldLines[i-1])
+		}
+	}
+	oldContent := strings.Join(oldLines, "\n") + "\n"
+	newContent := strings.Join(newLines, "\n") + "\n"
+
+	t.Run("round trip", func(t *testing.T) {
+		for _, contextLines := range []int{0, 1, 3, 10, 100} {
+			unified := GenerateUnified("a/file.txt", "b/file.txt", oldContent, newContent, contextLines)
+			result, err := ParseUnifiedDiff(unified)
+			require.NoError(t, err)
+			assert.Equal(t, "file.txt", result.OldFile)
+			assert.Equal(t, "file.txt", result.NewFile)
+			assert.Equal(t, strings.TrimSuffix(newContent, "\n"), applyParsed(t, oldContent, result), "context %d", contextLines)
+		}
+	})
+
+	t.Run("hunks follow the context size", func(t *testing.T) {
+		result, err := ParseUnifiedDiff(GenerateUnified("a/file.txt", "b/file.txt", oldContent, newContent, 3))
+		require.NoError(t, err)
+		require.Len(t, result.Hunks, 3)
+		assert.Equal(t, "@@ -1,6 +1,6 @@", result.Hunks[0].Header)
+		assert.Equal(t, "@@ -16,8 +16,7 @@", result.Hunks[1].Header)
+		assert.Equal(t, "@@ -31,7 +30,8 @@", result.Hunks[2].Header)
+
+		result, err = ParseUnifiedDiff(GenerateUnified("a/file.txt", "b/file.txt", oldContent, newContent, 20))
+		require.NoError(t, err)
+		assert.Len(t, result.Hunks, 1, "changes within twice the context share a hunk")
+
+		result, err = ParseUnifiedDiff(GenerateUnified("a/file.txt", "b/file.txt", oldContent, newContent, -1))
+		require.NoError(t, err)
+		require.Len(t, result.Hunks, 3)
+		assert.Equal(t, "@@ -3 +3 @@", result.Hunks[0].Header)
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		first := GenerateUnified("a/file.txt", "b/file.txt", oldContent, newContent, 3)
+		for range 10 {
+			assert.Equal(t, first, GenerateUnified("a/file.txt", "b/file.txt", oldContent, newContent, 3))
+		}
+		assert.True(t, strings.HasPrefix(first, "--- a/file.txt\n+++ b/file.txt\n@@ -1,6 +1,6 @@\n"))
+	})
+
+	t.Run("edge cases", func(t *testing.T) {
+		assert.Empty(t, GenerateUnified("a/x", "b/x", "same\n", "same\n", 3))
+
+		for _, tt := range []struct{ old, new string }{
+			{"", "created\nfile\n"},
+			{"deleted\nfile\n", ""},
+			{"no newline", "no newline\nat end"},
+			{"a\nb\n", "a\nb"},
+		} {
+			unified := GenerateUnified("a/x", "b/x", tt.old, tt.new, 3)
+			require.NotEmpty(t, unified)
+			result, err := ParseUnifiedDiff(unified)
+			require.NoError(t, err)
+			assert.Equal(t, strings.TrimSuffix(tt.new, "\n"), applyParsed(t, tt.old, result), "%q -> %q", tt.old, tt.new)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// JSONChangeType is the kind of change JSONDiff found at a path.
+type JSONChangeType string
+
+const (
+	JSONAdded   JSONChangeType = "added"
+	JSONRemoved JSONChangeType = "removed"
+	JSONChanged JSONChangeType = "changed"
+)
+
+// JSONChange is a single semantic change between two JSON documents. Path
+// locates the value, such as $.agents.coder.model or $.args[1]. Old is unset
+// for added values and New for removed ones.
+type JSONChange struct {
+	Path string
+	Type JSONChangeType
+	Old  any
+	New  any
+}
+
+// JSONDiff compares two JSON documents by value, ignoring formatting and key
+// order, and reports what was added, removed or changed. Object keys are
+// visited in sorted order and array elements by index, so the result is
+// stable. An error is returned when either side isn't valid JSON, so the
+// caller can fall back to a line diff.
+func JSONDiff(oldContent, newContent string) ([]JSONChange, error) {
+	oldValue, err := parseJSON(oldContent)
+	if err != nil {
+		return nil, fmt.Errorf("old content is not valid JSON: %w", err)
+	}
+	newValue, err := parseJSON(newContent)
+	if err != nil {
+		return nil, fmt.Errorf("new content is not valid JSON: %w", err)
+	}
+	changes := make([]JSONChange, 0)
+	return diffJSONValues("$", oldValue, newValue, changes), nil
+}
+
+// parseJSON decodes a single JSON value, keeping numbers exact.
+func parseJSON(content string) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(content)))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after the top-level value")
+	}
+	return value, nil
+}
+
+func diffJSONValues(path string, oldValue, newValue any, changes []JSONChange) []JSONChange {
+	switch o := oldValue.(type) {
+	case map[string]any:
+		n, ok := newValue.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := slices.Collect(maps.Keys(o))
+		for key := range n {
+			if _, ok := o[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			childPath := jsonPathKey(path, key)
+			oldChild, inOld := o[key]
+			newChild, inNew := n[key]
+			switch {
+			case !inNew:
+				changes = append(changes, JSONChange{Path: childPath, Type: JSONRemoved, Old: oldChild})
+			case !inOld:
+				changes = append(changes, JSONChange{Path: childPath, Type: JSONAdded, New: newChild})
+			default:
+				changes = diffJSONValues(childPath, oldChild, newChild, changes)
+			}
+		}
+		return changes
+	case []any:
+		n, ok := newValue.([]any)
+		if !ok {
+			break
+		}
+		for i := range max(len(o), len(n)) {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(n):
+				changes = append(changes, JSONChange{Path: childPath, Type: JSONRemoved, Old: o[i]})
+			case i >= len(o):
+				changes = append(changes, JSONChange{Path: childPath, Type: JSONAdded, New: n[i]})
+			default:
+				changes = diffJSONValues(childPath, o[i], n[i], changes)
+			}
+		}
+		return changes
+	}
+	if !jsonScalarEqual(oldValue, newValue) {
+		changes = append(changes, JSONChange{Path: path, Type: JSONChanged, Old: oldValue, New: newValue})
+	}
+	return changes
+}
+
+// jsonScalarEqual compares values that aren't both objects or both arrays.
+// Numbers are equal when they have the same value, however they are written.
+func jsonScalarEqual(a, b any) bool {
+	if an, ok := a.(json.Number); ok {
+		bn, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if an == bn {
+			return true
+		}
+		af, aErr := an.Float64()
+		bf, bErr := bn.Float64()
+		return aErr == nil && bErr == nil && af == bf
+	}
+	switch a.(type) {
+	case map[string]any, []any:
+		return false
+	}
+	return a == b
+}
+
+var jsonIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// jsonPathKey appends an object key to path, quoting keys that aren't plain
+// identifiers.
+func jsonPathKey(path, key string) string {
+	if jsonIdentifier.MatchString(key) {
+		return path + "." + key
+	}
+	return path + "[" + strconv.Quote(key) + "]"
+}
@@ -0,0 +1,55 @@
+package diff
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONDiff(t *testing.T) {
+	t.Parallel()
+
+	oldContent := `{
+  "theme": "opencode",
+  "agents": {"coder": {"model": "gpt-4.1", "maxTokens": 5000}},
+  "lsp": {"go": {"command": "gopls", "args": ["serve", "-rpc.trace"]}},
+  "debug": false
+}`
+	newContent := `{"debug":false,"lsp":{"go":{"args":["serve"],"command":"gopls"}},
+"agents":{"coder":{"maxTokens":5000.0,"model":"claude-4-sonnet"},"title":{"model":"gpt-4.1-mini"}},
+"data.dir":"/tmp"}`
+
+	changes, err := JSONDiff(oldContent, newContent)
+	require.NoError(t, err)
+	assert.Equal(t, []JSONChange{
+		{Path: "$.agents.coder.model", Type: JSONChanged, Old: "gpt-4.1", New: "claude-4-sonnet"},
+		{Path: "$.agents.title", Type: JSONAdded, New: map[string]any{"model": "gpt-4.1-mini"}},
+		{Path: `$["data.dir"]`, Type: JSONAdded, New: "/tmp"},
+		{Path: "$.lsp.go.args[1]", Type: JSONRemoved, Old: "-rpc.trace"},
+		{Path: "$.theme", Type: JSONRemoved, Old: "opencode"},
+	}, changes)
+
+	t.Run("reformatting is not a change", func(t *testing.T) {
+		changes, err := JSONDiff(`{"a": [1, 2], "b": null}`, "{\n  \"b\": null,\n  \"a\": [1,2]\n}\n")
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+
+	t.Run("type changes", func(t *testing.T) {
+		changes, err := JSONDiff(`{"a": [1], "b": 1}`, `{"a": {"0": 1}, "b": "1"}`)
+		require.NoError(t, err)
+		assert.Equal(t, []JSONChange{
+			{Path: "$.a", Type: JSONChanged, Old: []any{json.Number("1")}, New: map[string]any{"0": json.Number("1")}},
+			{Path: "$.b", Type: JSONChanged, Old: json.Number("1"), New: "1"},
+		}, changes)
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		_, err := JSONDiff(`{"a": 1`, `{}`)
+		assert.ErrorContains(t, err, "old content is not valid JSON")
+		_, err = JSONDiff(`{}`, `{} {}`)
+		assert.ErrorContains(t, err, "new content is not valid JSON")
+	})
+}
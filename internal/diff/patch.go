@@ -3,9 +3,12 @@ package diff
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
 type ActionType string
@@ -21,12 +24,35 @@ type FileChange struct {
 	OldContent *string
 	NewContent *string
 	MovePath   *string
+	Mode       os.FileMode // permissions of the original file, zero if unknown
 }
 
+// DefaultFileMode is the mode for added files and for changes whose original
+// mode is unknown.
+const DefaultFileMode os.FileMode = 0o644
+
 type Commit struct {
 	Changes map[string]FileChange
 }
 
+// SetModes records the permissions of the original files so they are kept
+// when the files are rewritten. Paths missing from modes are left unchanged.
+func (c Commit) SetModes(modes map[string]os.FileMode) {
+	for p, change := range c.Changes {
+		if mode, ok := modes[p]; ok && change.Type != ActionAdd {
+			change.Mode = mode
+			c.Changes[p] = change
+		}
+	}
+}
+
+func (c FileChange) mode() os.FileMode {
+	if c.Type == ActionAdd || c.Mode == 0 {
+		return DefaultFileMode
+	}
+	return c.Mode
+}
+
 type Chunk struct {
 	OrigIndex int      // line index of the first line in the original file
 	DelLines  []string // lines to delete
@@ -42,6 +68,9 @@ type PatchAction struct {
 
 type Patch struct {
 	Actions map[string]PatchAction
+	// CRLF is set when the patch text used CRLF line endings. Files added by
+	// the patch are written with them too.
+	CRLF bool
 }
 
 type DiffError struct {
@@ -61,6 +90,22 @@ func fileError(action, reason, path string) DiffError {
 	return NewDiffError(fmt.Sprintf("%s File Error: %s: %s", action, reason, path))
 }
 
+// ApplyError reports a change in a commit that could not be made: the file
+// being written or removed, the kind of change and the underlying error.
+type ApplyError struct {
+	Path   string
+	Action ActionType
+	Err    error
+}
+
+func (e *ApplyError) Error() string {
+	return fmt.Sprintf("failed to %s %s: %v", e.Action, e.Path, e.Err)
+}
+
+func (e *ApplyError) Unwrap() error {
+	return e.Err
+}
+
 func contextError(index int, context string, isEOF bool) DiffError {
 	prefix := "Invalid Context"
 	if isEOF {
@@ -158,6 +203,32 @@ func (p *Parser) Parse() error {
 			continue
 		}
 
+		// a move is an update whose destination is required, so it may come
+		// with hunks that change the file on the way
+		path = p.readStr("*** Move File: ", false)
+		if path != "" {
+			if _, exists := p.patch.Actions[path]; exists {
+				return fileError("Move", "Duplicate Path", path)
+			}
+			moveTo := p.readStr("*** Move to: ", false)
+			if moveTo == "" {
+				return fileError("Move", "Missing Move to", path)
+			}
+			if moveTo == path {
+				return fileError("Move", "Destination is the same file", path)
+			}
+			if _, exists := p.currentFiles[path]; !exists {
+				return fileError("Move", "Missing File", path)
+			}
+			action, err := p.parseUpdateFile(p.currentFiles[path])
+			if err != nil {
+				return err
+			}
+			action.MovePath = &moveTo
+			p.patch.Actions[path] = action
+			continue
+		}
+
 		path = p.readStr("*** Delete File: ", false)
 		if path != "" {
 			if _, exists := p.patch.Actions[path]; exists {
@@ -199,12 +270,13 @@ func (p *Parser) Parse() error {
 
 func (p *Parser) parseUpdateFile(text string) (PatchAction, error) {
 	action := PatchAction{Type: ActionUpdate, Chunks: []Chunk{}}
-	fileLines := strings.Split(text, "\n")
+	fileLines := strings.Split(toLF(text), "\n")
 	index := 0
 
 	endPrefixes := []string{
 		"*** End Patch",
 		"*** Update File:",
+		"*** Move File:",
 		"*** Delete File:",
 		"*** Add File:",
 		"*** End of File",
@@ -283,6 +355,7 @@ func (p *Parser) parseAddFile() (PatchAction, error) {
 	endPrefixes := []string{
 		"*** End Patch",
 		"*** Update File:",
+		"*** Move File:",
 		"*** Delete File:",
 		"*** Add File:",
 	}
@@ -386,6 +459,7 @@ func peekNextSection(lines []string, initialIndex int) ([]string, []Chunk, int,
 		return strings.HasPrefix(s, "@@") ||
 			strings.HasPrefix(s, "*** End Patch") ||
 			strings.HasPrefix(s, "*** Update File:") ||
+			strings.HasPrefix(s, "*** Move File:") ||
 			strings.HasPrefix(s, "*** Delete File:") ||
 			strings.HasPrefix(s, "*** Add File:") ||
 			strings.HasPrefix(s, "*** End of File") ||
@@ -457,9 +531,32 @@ func peekNextSection(lines []string, initialIndex int) ([]string, []Chunk, int,
 	return old, chunks, index, false
 }
 
+// usesCRLF reports whether text has CRLF line endings.
+func usesCRLF(text string) bool {
+	return strings.Contains(text, "\r\n")
+}
+
+func toLF(text string) string {
+	return strings.ReplaceAll(text, "\r\n", "\n")
+}
+
+func toCRLF(text string) string {
+	return strings.ReplaceAll(toLF(text), "\n", "\r\n")
+}
+
+// splitPatchLines splits patch text into lines without their line endings.
+func splitPatchLines(text string) []string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// TextToPatch parses the patch text against the original file contents.
+// Either may use CRLF line endings; lines are compared without them.
 func TextToPatch(text string, orig map[string]string) (Patch, int, error) {
-	text = strings.TrimSpace(text)
-	lines := strings.Split(text, "\n")
+	lines := splitPatchLines(text)
 	if len(lines) < 2 || !strings.HasPrefix(lines[0], "*** Begin Patch") || lines[len(lines)-1] != "*** End Patch" {
 		return Patch{}, 0, NewDiffError("Invalid patch text")
 	}
@@ -468,18 +565,21 @@ func TextToPatch(text string, orig map[string]string) (Patch, int, error) {
 	if err := parser.Parse(); err != nil {
 		return Patch{}, 0, err
 	}
+	parser.patch.CRLF = usesCRLF(text)
 	return parser.patch, parser.fuzz, nil
 }
 
 func IdentifyFilesNeeded(text string) []string {
-	text = strings.TrimSpace(text)
-	lines := strings.Split(text, "\n")
+	lines := splitPatchLines(text)
 	result := make(map[string]bool)
 
 	for _, line := range lines {
 		if strings.HasPrefix(line, "*** Update File: ") {
 			result[line[len("*** Update File: "):]] = true
 		}
+		if strings.HasPrefix(line, "*** Move File: ") {
+			result[line[len("*** Move File: "):]] = true
+		}
 		if strings.HasPrefix(line, "*** Delete File: ") {
 			result[line[len("*** Delete File: "):]] = true
 		}
@@ -493,8 +593,7 @@ func IdentifyFilesNeeded(text string) []string {
 }
 
 func IdentifyFilesAdded(text string) []string {
-	text = strings.TrimSpace(text)
-	lines := strings.Split(text, "\n")
+	lines := splitPatchLines(text)
 	result := make(map[string]bool)
 
 	for _, line := range lines {
@@ -550,16 +649,26 @@ func PatchToCommit(patch Patch, orig map[string]string) (Commit, error) {
 				OldContent: &oldContent,
 			}
 		case ActionAdd:
+			newContent := action.NewFile
+			if newContent != nil && patch.CRLF {
+				crlf := toCRLF(*newContent)
+				newContent = &crlf
+			}
 			commit.Changes[pathKey] = FileChange{
 				Type:       ActionAdd,
-				NewContent: action.NewFile,
+				NewContent: newContent,
 			}
 		case ActionUpdate:
-			newContent, err := getUpdatedFile(orig[pathKey], action, pathKey)
+			oldContent := orig[pathKey]
+			// the chunks were matched without line endings, so apply them the
+			// same way and put back the endings the file had
+			newContent, err := getUpdatedFile(toLF(oldContent), action, pathKey)
 			if err != nil {
 				return Commit{}, err
 			}
-			oldContent := orig[pathKey]
+			if usesCRLF(oldContent) {
+				newContent = toCRLF(newContent)
+			}
 			fileChange := FileChange{
 				Type:       ActionUpdate,
 				OldContent: &oldContent,
@@ -617,42 +726,288 @@ func LoadFiles(paths []string, openFn func(string) (string, error)) (map[string]
 	return orig, nil
 }
 
-func ApplyCommit(commit Commit, writeFn func(string, string) error, removeFn func(string) error) error {
+// ApplyConfig configures how a commit is applied
+type ApplyConfig struct {
+	Force bool // Write content even when it fails encoding validation
+	// WriteWithMode, when set, is used instead of the writeFn passed to
+	// ApplyCommit so the file's mode can be restored
+	WriteWithMode func(path, content string, mode os.FileMode) error
+}
+
+// writer returns the function used to write files for this config, or an
+// error when there is none to use.
+func (c ApplyConfig) writer(writeFn func(string, string) error) (func(string, string, os.FileMode) error, error) {
+	if c.WriteWithMode != nil {
+		return c.WriteWithMode, nil
+	}
+	if writeFn == nil {
+		return nil, NewDiffError("no function to write files with: writeFn is nil and no mode writer is set")
+	}
+	return func(path, content string, _ os.FileMode) error {
+		return writeFn(path, content)
+	}, nil
+}
+
+// ApplyOption modifies an ApplyConfig
+type ApplyOption func(*ApplyConfig)
+
+// WithForce skips the encoding validation performed before writing files
+func WithForce() ApplyOption {
+	return func(c *ApplyConfig) {
+		c.Force = true
+	}
+}
+
+// WithModeWriter writes files with fn, which is also given the mode to set:
+// the original file's for updates and moves, DefaultFileMode for added files
+func WithModeWriter(fn func(path, content string, mode os.FileMode) error) ApplyOption {
+	return func(c *ApplyConfig) {
+		c.WriteWithMode = fn
+	}
+}
+
+// ValidateUTF8 reports an error describing the first invalid UTF-8 sequence
+// in content, if any.
+func ValidateUTF8(content string) error {
+	if utf8.ValidString(content) {
+		return nil
+	}
+	line, col := 1, 1
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRuneInString(content[i:])
+		if r == utf8.RuneError && size <= 1 {
+			return NewDiffError(fmt.Sprintf("invalid UTF-8 byte 0x%02x at line %d, column %d (offset %d)", content[i], line, col, i))
+		}
+		if r == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+		i += size
+	}
+	return nil
+}
+
+// validateCommitEncoding checks the content the commit would write. What a
+// file held before doesn't matter, so files in other encodings can still be
+// replaced or removed.
+func validateCommitEncoding(commit Commit) error {
+	for p, change := range commit.Changes {
+		if change.NewContent == nil {
+			continue
+		}
+		if err := ValidateUTF8(*change.NewContent); err != nil {
+			return NewDiffError(fmt.Sprintf("%s: %s", p, err))
+		}
+	}
+	return nil
+}
+
+// ApplyCommit writes and removes the files changed by the commit, stopping at
+// the first change that fails. A failed write or remove is reported as an
+// *ApplyError naming the file and the kind of change. writeFn may only be nil
+// when WithModeWriter provides the writer instead.
+func ApplyCommit(commit Commit, writeFn func(string, string) error, removeFn func(string) error, opts ...ApplyOption) error {
+	config := ApplyConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if !config.Force {
+		if err := validateCommitEncoding(commit); err != nil {
+			return err
+		}
+	}
+
+	write, err := config.writer(writeFn)
+	if err != nil {
+		return err
+	}
 	for p, change := range commit.Changes {
+		if err := applyChange(p, change, write, removeFn, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyCommitAtomic applies the commit like ApplyCommit, but if any write or
+// remove fails the changes already made are reversed, newest first: added
+// files are removed again and restoreFn puts back the previous content of
+// updated, moved and deleted files. Because the previous content comes from
+// the commit, every update and delete must carry its OldContent. The returned
+// error wraps the *ApplyError that caused the rollback and includes any
+// failure to roll back.
+func ApplyCommitAtomic(commit Commit, writeFn func(string, string) error, removeFn func(string) error, restoreFn func(string, string) error, opts ...ApplyOption) error {
+	config := ApplyConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if !config.Force {
+		if err := validateCommitEncoding(commit); err != nil {
+			return err
+		}
+	}
+
+	paths := slices.Sorted(maps.Keys(commit.Changes))
+	for _, p := range paths {
+		change := commit.Changes[p]
+		if (change.Type == ActionUpdate || change.Type == ActionDelete) && change.OldContent == nil {
+			return NewDiffError(fmt.Sprintf("%s action for %s has nil old_content, it could not be rolled back", change.Type, p))
+		}
+	}
+
+	write, err := config.writer(writeFn)
+	if err != nil {
+		return err
+	}
+	var undo undoStack
+	for _, p := range paths {
+		if err := applyChange(p, commit.Changes[p], write, removeFn, restoreFn, &undo); err != nil {
+			if rollbackErr := undo.run(); rollbackErr != nil {
+				return errors.Join(err, fmt.Errorf("rollback failed: %w", rollbackErr))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// undoStack records how to reverse each step of a commit as it is applied.
+type undoStack []func() error
+
+func (u *undoStack) push(fn func() error) {
+	*u = append(*u, fn)
+}
+
+// run reverses the recorded steps, newest first, carrying on past failures.
+func (u undoStack) run() error {
+	var errs []error
+	for i := len(u) - 1; i >= 0; i-- {
+		if err := u[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// applyChange makes a single change. When undo is not nil, each completed
+// step is recorded on it so a later failure can be rolled back. Failures to
+// write or remove a file are returned as an *ApplyError.
+func applyChange(p string, change FileChange, writeFn func(string, string, os.FileMode) error, removeFn func(string) error, restoreFn func(string, string) error, undo *undoStack) error {
+	record := func(fn func() error) {
+		if undo != nil {
+			undo.push(fn)
+		}
+	}
+
+	fail := func(path string, err error) error {
+		return &ApplyError{Path: path, Action: change.Type, Err: err}
+	}
+
+	switch change.Type {
+	case ActionDelete:
+		if err := removeFn(p); err != nil {
+			return fail(p, err)
+		}
+		record(func() error { return restoreFn(p, *change.OldContent) })
+	case ActionAdd:
+		if change.NewContent == nil {
+			return NewDiffError(fmt.Sprintf("Add action for %s has nil new_content", p))
+		}
+		if err := writeFn(p, *change.NewContent, change.mode()); err != nil {
+			return fail(p, err)
+		}
+		record(func() error { return removeFn(p) })
+	case ActionUpdate:
+		if change.NewContent == nil {
+			return NewDiffError(fmt.Sprintf("Update action for %s has nil new_content", p))
+		}
+		if change.MovePath != nil {
+			dest := *change.MovePath
+			if err := writeFn(dest, *change.NewContent, change.mode()); err != nil {
+				return fail(dest, err)
+			}
+			record(func() error { return removeFn(dest) })
+			if err := removeFn(p); err != nil {
+				return fail(p, err)
+			}
+			record(func() error { return restoreFn(p, *change.OldContent) })
+		} else {
+			if err := writeFn(p, *change.NewContent, change.mode()); err != nil {
+				return fail(p, err)
+			}
+			record(func() error { return restoreFn(p, *change.OldContent) })
+		}
+	}
+	return nil
+}
+
+// DryRunCommit checks every change in the commit against the current file
+// contents, read through openFn, without writing or removing anything. It
+// returns the changes that would be applied, with OldContent set to what is on
+// disk now, and an error describing each change that would fail: deleting or
+// updating a missing file, adding or moving onto an existing one, updating a
+// file that changed since the patch was made, or invalid UTF-8 content unless
+// WithForce is given.
+func DryRunCommit(commit Commit, openFn func(string) (string, error), opts ...ApplyOption) (map[string]FileChange, error) {
+	config := ApplyConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	changes := make(map[string]FileChange, len(commit.Changes))
+	var errs []error
+	for _, p := range slices.Sorted(maps.Keys(commit.Changes)) {
+		change := commit.Changes[p]
+		current, openErr := openFn(p)
+		exists := openErr == nil
+
+		var err error
 		switch change.Type {
 		case ActionDelete:
-			if err := removeFn(p); err != nil {
-				return err
+			if !exists {
+				err = fileError("Delete", "File not found", p)
 			}
 		case ActionAdd:
 			if change.NewContent == nil {
-				return NewDiffError(fmt.Sprintf("Add action for %s has nil new_content", p))
-			}
-			if err := writeFn(p, *change.NewContent); err != nil {
-				return err
+				err = NewDiffError(fmt.Sprintf("Add action for %s has nil new_content", p))
+			} else if exists {
+				err = fileError("Add", "File already exists", p)
 			}
 		case ActionUpdate:
-			if change.NewContent == nil {
-				return NewDiffError(fmt.Sprintf("Update action for %s has nil new_content", p))
-			}
-			if change.MovePath != nil {
-				if err := writeFn(*change.MovePath, *change.NewContent); err != nil {
-					return err
-				}
-				if err := removeFn(p); err != nil {
-					return err
-				}
-			} else {
-				if err := writeFn(p, *change.NewContent); err != nil {
-					return err
+			switch {
+			case change.NewContent == nil:
+				err = NewDiffError(fmt.Sprintf("Update action for %s has nil new_content", p))
+			case !exists:
+				err = fileError("Update", "File not found", p)
+			case change.OldContent != nil && *change.OldContent != current:
+				err = fileError("Update", "File changed since the patch was made", p)
+			case change.MovePath != nil:
+				if _, moveErr := openFn(*change.MovePath); moveErr == nil {
+					err = fileError("Update", "Move destination already exists", *change.MovePath)
 				}
 			}
+		default:
+			err = NewDiffError(fmt.Sprintf("Unknown action %q for %s", change.Type, p))
+		}
+		if err == nil && !config.Force {
+			err = validateCommitEncoding(Commit{Changes: map[string]FileChange{p: change}})
 		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if exists {
+			change.OldContent = &current
+		}
+		changes[p] = change
 	}
-	return nil
+	return changes, errors.Join(errs...)
 }
 
-func ProcessPatch(text string, openFn func(string) (string, error), writeFn func(string, string) error, removeFn func(string) error) (string, error) {
+func ProcessPatch(text string, openFn func(string) (string, error), writeFn func(string, string) error, removeFn func(string) error, opts ...ApplyOption) (string, error) {
 	if !strings.HasPrefix(text, "*** Begin Patch") {
 		return "", NewDiffError("Patch must start with *** Begin Patch")
 	}
@@ -676,7 +1031,7 @@ func ProcessPatch(text string, openFn func(string) (string, error), writeFn func
 		return "", err
 	}
 
-	if err := ApplyCommit(commit, writeFn, removeFn); err != nil {
+	if err := ApplyCommit(commit, writeFn, removeFn, opts...); err != nil {
 		return "", err
 	}
 
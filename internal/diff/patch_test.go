@@ -0,0 +1,432 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateUTF8(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, ValidateUTF8("plain ascii\n"))
+	assert.NoError(t, ValidateUTF8("héllo wörld\n日本語\n🙂"))
+
+	err := ValidateUTF8("line one\nbad \xff byte")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2, column 5")
+
+	err = ValidateUTF8("truncated \xe6\x97")
+	assert.Error(t, err)
+}
+
+func TestApplyCommitEncoding(t *testing.T) {
+	t.Parallel()
+
+	invalid := "bad \xc3\x28 content"
+	commit := Commit{Changes: map[string]FileChange{
+		"file.txt": {Type: ActionAdd, NewContent: &invalid},
+	}}
+
+	written := map[string]string{}
+	writeFn := func(path, content string) error {
+		written[path] = content
+		return nil
+	}
+	removeFn := func(string) error { return nil }
+
+	err := ApplyCommit(commit, writeFn, removeFn)
+	require.Error(t, err)
+	assert.Empty(t, written)
+
+	err = ApplyCommit(commit, writeFn, removeFn, WithForce())
+	require.NoError(t, err)
+	assert.Equal(t, invalid, written["file.txt"])
+}
+
+func TestApplyCommitLatin1Source(t *testing.T) {
+	t.Parallel()
+
+	// "café\n" in Latin-1, which isn't valid UTF-8
+	latin1 := "caf\xe9\n"
+	utf8Content := "café\n"
+	commit := Commit{Changes: map[string]FileChange{
+		"menu.txt": {Type: ActionUpdate, OldContent: &latin1, NewContent: &utf8Content},
+		"old.txt":  {Type: ActionDelete, OldContent: &latin1},
+	}}
+
+	written := map[string]string{}
+	var removed []string
+	err := ApplyCommit(commit, func(path, content string) error {
+		written[path] = content
+		return nil
+	}, func(path string) error {
+		removed = append(removed, path)
+		return nil
+	})
+	require.NoError(t, err, "only the content written is validated")
+	assert.Equal(t, utf8Content, written["menu.txt"])
+	assert.Equal(t, []string{"old.txt"}, removed)
+
+	_, err = DryRunCommit(commit, func(p string) (string, error) { return latin1, nil })
+	assert.NoError(t, err)
+}
+
+func TestDryRunCommit(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"keep.txt":   "old\n",
+		"stale.txt":  "edited meanwhile\n",
+		"remove.txt": "bye\n",
+		"taken.txt":  "occupied\n",
+	}
+	openFn := func(p string) (string, error) {
+		content, ok := files[p]
+		if !ok {
+			return "", os.ErrNotExist
+		}
+		return content, nil
+	}
+	str := func(s string) *string { return &s }
+
+	commit := Commit{Changes: map[string]FileChange{
+		"keep.txt":    {Type: ActionUpdate, OldContent: str("old\n"), NewContent: str("new\n")},
+		"stale.txt":   {Type: ActionUpdate, OldContent: str("original\n"), NewContent: str("new\n")},
+		"remove.txt":  {Type: ActionDelete, OldContent: str("bye\n")},
+		"missing.txt": {Type: ActionDelete},
+		"added.txt":   {Type: ActionAdd, NewContent: str("hello\n")},
+		"taken.txt":   {Type: ActionAdd, NewContent: str("hello\n")},
+	}}
+
+	changes, err := DryRunCommit(commit, openFn)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Delete File Error: File not found: missing.txt")
+	assert.Contains(t, err.Error(), "Update File Error: File changed since the patch was made: stale.txt")
+	assert.Contains(t, err.Error(), "Add File Error: File already exists: taken.txt")
+
+	require.Len(t, changes, 3)
+	assert.Equal(t, "old\n", *changes["keep.txt"].OldContent)
+	assert.Equal(t, "new\n", *changes["keep.txt"].NewContent)
+	assert.Equal(t, ActionDelete, changes["remove.txt"].Type)
+	assert.Nil(t, changes["added.txt"].OldContent)
+
+	// nothing was touched
+	assert.Len(t, files, 4)
+	assert.Equal(t, "old\n", files["keep.txt"])
+
+	clean := Commit{Changes: map[string]FileChange{"keep.txt": commit.Changes["keep.txt"]}}
+	changes, err = DryRunCommit(clean, openFn)
+	require.NoError(t, err)
+	assert.Len(t, changes, 1)
+}
+
+func TestApplyCommitAtomicRollback(t *testing.T) {
+	t.Parallel()
+
+	str := func(s string) *string { return &s }
+	files := map[string]string{
+		"a.txt": "a old\n",
+		"c.txt": "c old\n",
+		"d.txt": "d old\n",
+	}
+	writeFn := func(p, content string) error {
+		if p == "d.txt" {
+			return errors.New("disk full")
+		}
+		files[p] = content
+		return nil
+	}
+	removeFn := func(p string) error {
+		delete(files, p)
+		return nil
+	}
+	restoreFn := func(p, content string) error {
+		files[p] = content
+		return nil
+	}
+
+	commit := Commit{Changes: map[string]FileChange{
+		"a.txt": {Type: ActionUpdate, OldContent: str("a old\n"), NewContent: str("a new\n")},
+		"b.txt": {Type: ActionAdd, NewContent: str("b new\n")},
+		"c.txt": {Type: ActionDelete, OldContent: str("c old\n")},
+		"d.txt": {Type: ActionUpdate, OldContent: str("d old\n"), NewContent: str("d new\n")},
+	}}
+
+	err := ApplyCommitAtomic(commit, writeFn, removeFn, restoreFn)
+	require.EqualError(t, err, "failed to update d.txt: disk full")
+	assert.Equal(t, map[string]string{
+		"a.txt": "a old\n",
+		"c.txt": "c old\n",
+		"d.txt": "d old\n",
+	}, files)
+
+	t.Run("reports rollback failures", func(t *testing.T) {
+		failingRestore := func(string, string) error { return errors.New("read-only") }
+		err := ApplyCommitAtomic(commit, writeFn, removeFn, failingRestore)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to update d.txt: disk full")
+		assert.Contains(t, err.Error(), "rollback failed: read-only")
+		var applyErr *ApplyError
+		assert.ErrorAs(t, err, &applyErr)
+	})
+
+	t.Run("requires old content", func(t *testing.T) {
+		commit := Commit{Changes: map[string]FileChange{
+			"a.txt": {Type: ActionUpdate, NewContent: str("a new\n")},
+		}}
+		wrote := false
+		writeFn := func(string, string) error {
+			wrote = true
+			return nil
+		}
+		assert.Error(t, ApplyCommitAtomic(commit, writeFn, removeFn, restoreFn))
+		assert.False(t, wrote, "nothing is written when the commit can't be rolled back")
+	})
+}
+
+func TestApplyCommitError(t *testing.T) {
+	t.Parallel()
+
+	str := func(s string) *string { return &s }
+	writeFn := func(p, content string) error {
+		if p == "locked.go" {
+			return fs.ErrPermission
+		}
+		return nil
+	}
+	removeFn := func(p string) error {
+		if p == "locked.go" {
+			return fs.ErrPermission
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name   string
+		change FileChange
+		path   string
+	}{
+		{"update", FileChange{Type: ActionUpdate, OldContent: str("a"), NewContent: str("b")}, "locked.go"},
+		{"add", FileChange{Type: ActionAdd, NewContent: str("b")}, "locked.go"},
+		{"delete", FileChange{Type: ActionDelete, OldContent: str("a")}, "locked.go"},
+		{"move onto locked file", FileChange{Type: ActionUpdate, OldContent: str("a"), NewContent: str("b"), MovePath: str("locked.go")}, "locked.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := "locked.go"
+			if tt.change.MovePath != nil {
+				source = "free.go"
+			}
+			commit := Commit{Changes: map[string]FileChange{source: tt.change}}
+
+			for _, err := range []error{
+				ApplyCommit(commit, writeFn, removeFn),
+				ApplyCommitAtomic(commit, writeFn, removeFn, func(string, string) error { return nil }),
+			} {
+				var applyErr *ApplyError
+				require.ErrorAs(t, err, &applyErr)
+				assert.Equal(t, tt.path, applyErr.Path)
+				assert.Equal(t, tt.change.Type, applyErr.Action)
+				assert.ErrorIs(t, err, fs.ErrPermission)
+				assert.Equal(t, fmt.Sprintf("failed to %s locked.go: permission denied", tt.change.Type), applyErr.Error())
+			}
+		})
+	}
+}
+
+func TestApplyCommitWithoutWriter(t *testing.T) {
+	t.Parallel()
+
+	str := func(s string) *string { return &s }
+	commit := Commit{Changes: map[string]FileChange{
+		"gone.go": {Type: ActionDelete, OldContent: str("a")},
+		"new.go":  {Type: ActionAdd, NewContent: str("b")},
+	}}
+	removed := 0
+	removeFn := func(string) error {
+		removed++
+		return nil
+	}
+
+	for _, err := range []error{
+		ApplyCommit(commit, nil, removeFn),
+		ApplyCommitAtomic(commit, nil, removeFn, func(string, string) error { return nil }),
+	} {
+		var diffErr DiffError
+		require.ErrorAs(t, err, &diffErr)
+		assert.Contains(t, err.Error(), "writeFn is nil")
+	}
+	assert.Zero(t, removed, "nothing is applied without a writer")
+}
+
+func TestProcessPatchLineEndings(t *testing.T) {
+	t.Parallel()
+
+	patch := func(eol string) string {
+		return strings.Join([]string{
+			"*** Begin Patch",
+			"*** Update File: main.go",
+			"@@ func main() {",
+			" \ta := 1",
+			"-\tprintln(a)",
+			"+\tprintln(a + 1)",
+			" }",
+			"*** Add File: notes.txt",
+			"+first",
+			"+second",
+			"*** End Patch",
+		}, eol) + eol
+	}
+	source := "package main\n\nfunc main() {\n\ta := 1\n\tprintln(a)\n}\n"
+
+	tests := []struct {
+		name      string
+		patchEOL  string
+		sourceEOL string
+		notes     string
+	}{
+		{"crlf patch, lf source", "\r\n", "\n", "first\r\nsecond"},
+		{"lf patch, crlf source", "\n", "\r\n", "first\nsecond"},
+		{"crlf patch, crlf source", "\r\n", "\r\n", "first\r\nsecond"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{"main.go": strings.ReplaceAll(source, "\n", tt.sourceEOL)}
+			openFn := func(p string) (string, error) {
+				content, ok := files[p]
+				if !ok {
+					return "", os.ErrNotExist
+				}
+				return content, nil
+			}
+			writeFn := func(p, content string) error {
+				files[p] = content
+				return nil
+			}
+			removeFn := func(p string) error {
+				delete(files, p)
+				return nil
+			}
+
+			_, err := ProcessPatch(patch(tt.patchEOL), openFn, writeFn, removeFn)
+			require.NoError(t, err)
+
+			want := strings.ReplaceAll(source, "println(a)", "println(a + 1)")
+			assert.Equal(t, strings.ReplaceAll(want, "\n", tt.sourceEOL), files["main.go"])
+			assert.Equal(t, tt.notes, files["notes.txt"])
+		})
+	}
+}
+
+func TestApplyCommitKeepsFileMode(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "run.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho one\n"), 0o755))
+
+	patchText := "*** Begin Patch\n" +
+		"*** Update File: " + script + "\n" +
+		"*** Move to: " + filepath.Join(dir, "build.sh") + "\n" +
+		" #!/bin/sh\n" +
+		"-echo one\n" +
+		"+echo two\n" +
+		"*** Add File: " + filepath.Join(dir, "notes.txt") + "\n" +
+		"+notes\n" +
+		"*** End Patch"
+	orig, err := LoadFiles(IdentifyFilesNeeded(patchText), OpenFile)
+	require.NoError(t, err)
+	patch, _, err := TextToPatch(patchText, orig)
+	require.NoError(t, err)
+	commit, err := PatchToCommit(patch, orig)
+	require.NoError(t, err)
+
+	info, err := os.Stat(script)
+	require.NoError(t, err)
+	commit.SetModes(map[string]os.FileMode{script: info.Mode().Perm()})
+
+	// write through a temporary file, which doesn't keep the old mode by itself
+	writeFn := func(path, content string, mode os.FileMode) error {
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, []byte(content), 0o600); err != nil {
+			return err
+		}
+		if err := os.Chmod(tmp, mode); err != nil {
+			return err
+		}
+		return os.Rename(tmp, path)
+	}
+	require.NoError(t, ApplyCommit(commit, nil, os.Remove, WithModeWriter(writeFn)))
+
+	info, err = os.Stat(filepath.Join(dir, "build.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+
+	info, err = os.Stat(filepath.Join(dir, "notes.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, DefaultFileMode, info.Mode().Perm())
+}
+
+func TestProcessPatchMoveFile(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"old/main.go": "package main\n\nfunc main() {\n\tprintln(1)\n}\n",
+		"util.go":     "package main\n\nfunc util() {}\n",
+		"README.md":   "docs\n",
+	}
+	openFn := func(p string) (string, error) {
+		content, ok := files[p]
+		if !ok {
+			return "", os.ErrNotExist
+		}
+		return content, nil
+	}
+	writeFn := func(p, content string) error {
+		files[p] = content
+		return nil
+	}
+	removeFn := func(p string) error {
+		delete(files, p)
+		return nil
+	}
+
+	patchText := strings.Join([]string{
+		"*** Begin Patch",
+		"*** Move File: old/main.go",
+		"*** Move to: cmd/main.go",
+		"@@ func main() {",
+		"-\tprintln(1)",
+		"+\tprintln(2)",
+		" }",
+		"*** Move File: README.md",
+		"*** Move to: docs/README.md",
+		"*** Update File: util.go",
+		"-func util() {}",
+		"+func util() int { return 1 }",
+		"*** End Patch",
+	}, "\n")
+	assert.ElementsMatch(t, []string{"old/main.go", "README.md", "util.go"}, IdentifyFilesNeeded(patchText))
+
+	_, err := ProcessPatch(patchText, openFn, writeFn, removeFn)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"cmd/main.go":    "package main\n\nfunc main() {\n\tprintln(2)\n}\n",
+		"docs/README.md": "docs\n",
+		"util.go":        "package main\n\nfunc util() int { return 1 }\n",
+	}, files)
+
+	t.Run("requires a destination", func(t *testing.T) {
+		patchText := "*** Begin Patch\n*** Move File: util.go\n*** End Patch"
+		_, _, err := TextToPatch(patchText, map[string]string{"util.go": files["util.go"]})
+		assert.ErrorContains(t, err, "Move File Error: Missing Move to: util.go")
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"io/fs"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -112,38 +113,31 @@ func SkipHidden(path string) bool {
 	return false
 }
 
-func GlobWithDoublestar(pattern, searchPath string, limit int) ([]string, bool, error) {
-	fsys := os.DirFS(searchPath)
+// GlobWithDoublestar returns the files under searchPath matching pattern,
+// most recently modified first. Symlinked directories are only walked when
+// followSymlinks is set, and a link back to a directory that is already being
+// walked is skipped, so cyclic links can't make the walk loop forever.
+func GlobWithDoublestar(pattern, searchPath string, limit int, followSymlinks bool) ([]string, bool, error) {
+	if err := ValidatePattern(pattern); err != nil {
+		return nil, false, err
+	}
 	relPattern := strings.TrimPrefix(pattern, "/")
-	var matches []FileInfo
 
-	err := doublestar.GlobWalk(fsys, relPattern, func(path string, d fs.DirEntry) error {
-		if d.IsDir() {
-			return nil
-		}
-		if SkipHidden(path) {
-			return nil
-		}
-		info, err := d.Info()
-		if err != nil {
-			return nil
-		}
-		absPath := path
-		if !strings.HasPrefix(absPath, searchPath) && searchPath != "." {
-			absPath = filepath.Join(searchPath, absPath)
-		} else if !strings.HasPrefix(absPath, "/") && searchPath == "." {
-			absPath = filepath.Join(searchPath, absPath) // Ensure relative paths are joined correctly
-		}
-
-		matches = append(matches, FileInfo{Path: absPath, ModTime: info.ModTime()})
-		if limit > 0 && len(matches) >= limit*2 {
-			return fs.SkipAll
-		}
-		return nil
-	})
-	if err != nil {
+	w := &globWalker{
+		root:           searchPath,
+		pattern:        relPattern,
+		followSymlinks: followSymlinks,
+		limit:          limit,
+	}
+	if !strings.Contains(relPattern, "**") {
+		// without ** the pattern can't match deeper than it has segments
+		w.maxDepth = strings.Count(relPattern, "/") + 1
+	}
+	base, _ := doublestar.SplitPattern(relPattern)
+	if err := w.walk(base); err != nil && err != fs.SkipAll {
 		return nil, false, fmt.Errorf("glob walk error: %w", err)
 	}
+	matches := w.matches
 
 	sort.Slice(matches, func(i, j int) bool {
 		return matches[i].ModTime.After(matches[j].ModTime)
@@ -161,3 +155,139 @@ func GlobWithDoublestar(pattern, searchPath string, limit int) ([]string, bool,
 	}
 	return results, truncated, nil
 }
+
+// ValidatePattern checks that pattern is a glob GlobWithDoublestar can match,
+// so a bad pattern can be rejected with a clear message before any walk. The
+// error wraps doublestar.ErrBadPattern.
+func ValidatePattern(pattern string) error {
+	if strings.TrimSpace(pattern) == "" {
+		return fmt.Errorf("invalid glob pattern: pattern is empty: %w", doublestar.ErrBadPattern)
+	}
+	if doublestar.ValidatePattern(strings.TrimPrefix(pattern, "/")) {
+		return nil
+	}
+	return fmt.Errorf("invalid glob pattern %q: %s: %w", pattern, patternProblem(pattern), doublestar.ErrBadPattern)
+}
+
+// patternProblem describes why doublestar rejected pattern.
+func patternProblem(pattern string) string {
+	var brackets, braces int
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if i == len(pattern)-1 {
+				return "trailing backslash"
+			}
+			i++
+		case '[':
+			if brackets == 0 {
+				brackets++
+			}
+		case ']':
+			if brackets > 0 {
+				brackets--
+			}
+		case '{':
+			if brackets == 0 {
+				braces++
+			}
+		case '}':
+			if brackets == 0 {
+				if braces == 0 {
+					return "unmatched '}'"
+				}
+				braces--
+			}
+		}
+	}
+	switch {
+	case brackets > 0:
+		return "unclosed '['"
+	case braces > 0:
+		return "unclosed '{'"
+	default:
+		return "malformed character class"
+	}
+}
+
+type globWalker struct {
+	root           string
+	pattern        string
+	followSymlinks bool
+	limit          int
+	maxDepth       int
+
+	// ancestors holds the directories on the path being walked, to detect
+	// symlink cycles
+	ancestors []fs.FileInfo
+	matches   []FileInfo
+}
+
+// walk collects the matching files under dir, a slash-separated path relative
+// to the root.
+func (w *globWalker) walk(dir string) error {
+	dirPath := filepath.Join(w.root, filepath.FromSlash(dir))
+	dirInfo, err := os.Stat(dirPath)
+	if err != nil {
+		return nil
+	}
+	for _, ancestor := range w.ancestors {
+		if os.SameFile(ancestor, dirInfo) {
+			return nil
+		}
+	}
+	w.ancestors = append(w.ancestors, dirInfo)
+	defer func() { w.ancestors = w.ancestors[:len(w.ancestors)-1] }()
+
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		rel := path.Join(dir, entry.Name())
+		if SkipHidden(filepath.FromSlash(rel)) {
+			continue
+		}
+
+		isDir := entry.IsDir()
+		var info fs.FileInfo
+		if entry.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Stat(filepath.Join(dirPath, entry.Name()))
+			if err != nil {
+				continue // dangling link
+			}
+			if target.IsDir() && !w.followSymlinks {
+				continue
+			}
+			isDir = target.IsDir()
+			info = target
+		}
+
+		if isDir {
+			if w.maxDepth > 0 && strings.Count(rel, "/")+1 >= w.maxDepth {
+				continue
+			}
+			if err := w.walk(rel); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if matched, _ := doublestar.Match(w.pattern, rel); !matched {
+			continue
+		}
+		if info == nil {
+			if info, err = entry.Info(); err != nil {
+				continue
+			}
+		}
+		w.matches = append(w.matches, FileInfo{
+			Path:    filepath.Join(w.root, filepath.FromSlash(rel)),
+			ModTime: info.ModTime(),
+		})
+		if w.limit > 0 && len(w.matches) >= w.limit*2 {
+			return fs.SkipAll
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobWithDoublestarSymlinks(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "pkg"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "pkg", "main.go"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "notes.txt"), nil, 0o644))
+	// a link back up the tree, and a second name for a directory
+	require.NoError(t, os.Symlink("..", filepath.Join(dir, "src", "pkg", "loop")))
+	require.NoError(t, os.Symlink("src", filepath.Join(dir, "alias")))
+
+	glob := func(pattern string, followSymlinks bool) []string {
+		t.Helper()
+		done := make(chan []string, 1)
+		go func() {
+			matches, _, err := GlobWithDoublestar(pattern, dir, 0, followSymlinks)
+			assert.NoError(t, err)
+			done <- matches
+		}()
+		select {
+		case matches := <-done:
+			return matches
+		case <-time.After(5 * time.Second):
+			t.Fatal("glob walk did not terminate")
+			return nil
+		}
+	}
+
+	assert.Equal(t, []string{filepath.Join(dir, "src", "pkg", "main.go")}, glob("**/*.go", false))
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dir, "alias", "pkg", "main.go"),
+		filepath.Join(dir, "src", "pkg", "main.go"),
+	}, glob("**/*.go", true))
+
+	assert.Equal(t, []string{filepath.Join(dir, "src", "notes.txt")}, glob("*/*.txt", false))
+	assert.Empty(t, glob("*.txt", true))
+
+	_, _, err := GlobWithDoublestar("[", dir, 0, false)
+	assert.ErrorIs(t, err, doublestar.ErrBadPattern)
+}
+
+func TestValidatePattern(t *testing.T) {
+	t.Parallel()
+
+	for _, pattern := range []string{"*.go", "**/*.{go,mod}", "/src/[a-z]*.txt", `a\[b`} {
+		assert.NoError(t, ValidatePattern(pattern), pattern)
+	}
+
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"", "pattern is empty"},
+		{"  ", "pattern is empty"},
+		{"src/[abc.go", "unclosed '['"},
+		{"**/*.{go,mod", "unclosed '{'"},
+		{"*.go}", "unmatched '}'"},
+		{`src\`, "trailing backslash"},
+	}
+	for _, tt := range tests {
+		err := ValidatePattern(tt.pattern)
+		require.Error(t, err, tt.pattern)
+		assert.ErrorIs(t, err, doublestar.ErrBadPattern)
+		assert.ErrorContains(t, err, tt.want, tt.pattern)
+
+		_, _, globErr := GlobWithDoublestar(tt.pattern, t.TempDir(), 0, false)
+		assert.Equal(t, err, globErr, "GlobWithDoublestar returns the validation error")
+	}
+}
@@ -0,0 +1,154 @@
+package fileutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FuzzyFind returns up to limit files under the working directory that match
+// query, best match first. Matching is done by fzf when it is installed and by
+// a case-insensitive substring ranking otherwise. A limit of zero or less
+// returns every match.
+func FuzzyFind(query string, limit int) ([]string, error) {
+	files, err := listFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	if fzfPath != "" {
+		matches, err = fzfFilter(query, files)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		matches = substringRank(query, files)
+	}
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// listFiles lists the files under the working directory with rg, or by
+// globbing when rg isn't installed, leaving out hidden and ignored paths.
+func listFiles() ([]string, error) {
+	var files []string
+	if cmd := GetRgCmd(""); cmd != nil {
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			// rg exits with 1 when there are no files to list
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+				return nil, fmt.Errorf("rg command failed: %w\nStderr: %s", err, stderr.String())
+			}
+		}
+		files = splitNullTerminated(stdout.Bytes())
+	} else {
+		globbed, _, err := GlobWithDoublestar("**/*", ".", 0, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+		files = globbed
+	}
+
+	visible := make([]string, 0, len(files))
+	for _, file := range files {
+		file = filepath.Join(".", file)
+		if !SkipHidden(file) {
+			visible = append(visible, file)
+		}
+	}
+	return visible, nil
+}
+
+func fzfFilter(query string, files []string) ([]string, error) {
+	cmd := GetFzfCmd(query)
+	var stdin, stdout, stderr bytes.Buffer
+	for _, file := range files {
+		stdin.WriteString(file)
+		stdin.WriteByte(0)
+	}
+	cmd.Stdin = &stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return []string{}, nil // No matches
+		}
+		return nil, fmt.Errorf("fzf command failed: %w\nStderr: %s", err, stderr.String())
+	}
+	return splitNullTerminated(stdout.Bytes()), nil
+}
+
+func splitNullTerminated(output []byte) []string {
+	output = bytes.TrimSuffix(output, []byte{0})
+	if len(output) == 0 {
+		return []string{}
+	}
+
+	parts := bytes.Split(output, []byte{0})
+	result := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if len(p) > 0 {
+			result = append(result, string(p))
+		}
+	}
+	return result
+}
+
+// substringRank keeps the files containing query, ignoring case. Files whose
+// name contains it come before files where only the directory does, then
+// earlier matches before later ones and shorter paths before longer ones.
+func substringRank(query string, files []string) []string {
+	query = strings.ToLower(query)
+
+	type ranked struct {
+		path   string
+		inBase bool
+		index  int
+	}
+	var matches []ranked
+	for _, file := range files {
+		index := strings.Index(strings.ToLower(file), query)
+		if index < 0 {
+			continue
+		}
+		m := ranked{path: file, index: index}
+		if i := strings.Index(strings.ToLower(filepath.Base(file)), query); i >= 0 {
+			m.inBase = true
+			m.index = i
+		}
+		matches = append(matches, m)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.inBase != b.inBase {
+			return a.inBase
+		}
+		if a.index != b.index {
+			return a.index < b.index
+		}
+		if len(a.path) != len(b.path) {
+			return len(a.path) < len(b.path)
+		}
+		return a.path < b.path
+	})
+
+	result := make([]string, len(matches))
+	for i, m := range matches {
+		result[i] = m.path
+	}
+	return result
+}
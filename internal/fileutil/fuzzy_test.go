@@ -0,0 +1,42 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyFindWithoutFzf(t *testing.T) {
+	originalRg, originalFzf := rgPath, fzfPath
+	t.Cleanup(func() { rgPath, fzfPath = originalRg, originalFzf })
+	rgPath, fzfPath = "", ""
+
+	dir := t.TempDir()
+	for _, name := range []string{
+		"main.go",
+		"cmd/main_test.go",
+		"domain/user.go",
+		"internal/app.go",
+		"node_modules/main.js",
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, nil, 0o644))
+	}
+	t.Chdir(dir)
+
+	matches, err := FuzzyFind("MAIN", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go", "cmd/main_test.go", "domain/user.go"}, matches)
+
+	matches, err = FuzzyFind("main", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go"}, matches)
+
+	matches, err = FuzzyFind("nothing", 0)
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
@@ -0,0 +1,219 @@
+package fileutil
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/fsnotify/fsnotify"
+	"github.com/opencode-ai/opencode/internal/logging"
+)
+
+// FileOp is the kind of change reported by Watch.
+type FileOp string
+
+const (
+	FileCreated FileOp = "created"
+	FileWritten FileOp = "written"
+	FileRemoved FileOp = "removed"
+)
+
+// FileEvent is a change to a file under a watched root.
+type FileEvent struct {
+	Path string
+	Op   FileOp
+}
+
+// watchCoalesceWindow is how long a path must stay quiet before its event is
+// sent, so an editor saving a file in several steps produces one event.
+const watchCoalesceWindow = 100 * time.Millisecond
+
+// Watch reports changes to files under root whose path relative to root
+// matches one of patterns, or every file when there are none. Hidden and
+// commonly ignored paths are left out, as with the other helpers in this
+// package. Events for the same path that arrive in quick succession are
+// merged into one. The channel is closed and the watches are released when
+// ctx is done.
+func Watch(ctx context.Context, root string, patterns []string) (<-chan FileEvent, error) {
+	for _, pattern := range patterns {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid watch pattern %q: %w", pattern, doublestar.ErrBadPattern)
+		}
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("failed to watch %s: not a directory", root)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+	w := &fileWatcher{
+		watcher:  watcher,
+		root:     root,
+		patterns: patterns,
+		pending:  make(map[string]pendingFileEvent),
+		events:   make(chan FileEvent, 64),
+	}
+	if err := w.addTree(root, false); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	go w.run(ctx)
+	return w.events, nil
+}
+
+type pendingFileEvent struct {
+	op   FileOp
+	last time.Time
+}
+
+type fileWatcher struct {
+	watcher  *fsnotify.Watcher
+	root     string
+	patterns []string
+	pending  map[string]pendingFileEvent
+	events   chan FileEvent
+}
+
+// addTree watches dir and the directories below it. For a directory that was
+// just created, files may already have been written to it before the watch
+// was in place, so reportFiles queues a create for each of them.
+func (w *fileWatcher) addTree(dir string, reportFiles bool) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			if reportFiles {
+				w.queue(path, FileCreated)
+			}
+			return nil
+		}
+		if path != w.root && w.ignored(path) {
+			return filepath.SkipDir
+		}
+		if err := w.watcher.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+func (w *fileWatcher) ignored(path string) bool {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return true
+	}
+	return SkipHidden(rel)
+}
+
+func (w *fileWatcher) matches(path string) bool {
+	if w.ignored(path) {
+		return false
+	}
+	if len(w.patterns) == 0 {
+		return true
+	}
+	rel, _ := filepath.Rel(w.root, path)
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range w.patterns {
+		if ok, _ := doublestar.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *fileWatcher) run(ctx context.Context) {
+	defer close(w.events)
+	defer w.watcher.Close()
+
+	ticker := time.NewTicker(watchCoalesceWindow / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handle(event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			logging.Error("Error watching files", "error", err)
+		case now := <-ticker.C:
+			if !w.flush(ctx, now) {
+				return
+			}
+		}
+	}
+}
+
+func (w *fileWatcher) handle(event fsnotify.Event) {
+	var op FileOp
+	switch {
+	case event.Has(fsnotify.Create):
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if !w.ignored(event.Name) {
+				if err := w.addTree(event.Name, true); err != nil {
+					logging.Error("Error watching new directory", "path", event.Name, "error", err)
+				}
+			}
+			return
+		}
+		op = FileCreated
+	case event.Has(fsnotify.Write):
+		op = FileWritten
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		// the new name of a renamed file arrives as its own create
+		op = FileRemoved
+	default:
+		return
+	}
+	w.queue(event.Name, op)
+}
+
+func (w *fileWatcher) queue(path string, op FileOp) {
+	if !w.matches(path) {
+		return
+	}
+	if prev, ok := w.pending[path]; ok && prev.op == FileCreated && op == FileWritten {
+		// writes right after creating a file are part of the creation
+		op = FileCreated
+	}
+	w.pending[path] = pendingFileEvent{op: op, last: time.Now()}
+}
+
+// flush sends the events for paths that have been quiet for the coalesce
+// window. It reports false if ctx ended while sending.
+func (w *fileWatcher) flush(ctx context.Context, now time.Time) bool {
+	for path, pending := range w.pending {
+		if now.Sub(pending.last) < watchCoalesceWindow {
+			continue
+		}
+		delete(w.pending, path)
+		select {
+		case w.events <- FileEvent{Path: path, Op: pending.op}:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package fileutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "node_modules"), 0o755))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := Watch(ctx, dir, []string{"**/*.go"})
+	require.NoError(t, err)
+
+	next := func() FileEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for file event")
+			return FileEvent{}
+		}
+	}
+
+	main := filepath.Join(dir, "main.go")
+	for i := range 5 {
+		require.NoError(t, os.WriteFile(main, []byte{byte(i)}, 0o644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "node_modules", "dep.go"), nil, 0o644))
+	assert.Equal(t, FileEvent{Path: main, Op: FileCreated}, next())
+
+	// directories created after the watch started are picked up too
+	pkg := filepath.Join(dir, "pkg")
+	require.NoError(t, os.Mkdir(pkg, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(pkg, "lib.go"), nil, 0o644))
+	assert.Equal(t, FileEvent{Path: filepath.Join(pkg, "lib.go"), Op: FileCreated}, next())
+
+	require.NoError(t, os.WriteFile(main, []byte("changed"), 0o644))
+	assert.Equal(t, FileEvent{Path: main, Op: FileWritten}, next())
+
+	require.NoError(t, os.Remove(main))
+	assert.Equal(t, FileEvent{Path: main, Op: FileRemoved}, next())
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("events channel was not closed")
+	}
+}
@@ -2,6 +2,7 @@ package format
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -22,24 +23,57 @@ func (f OutputFormat) String() string {
 	return string(f)
 }
 
+// formats lists every supported output format with the description shown in
+// the help text, in the order they are listed.
+var formats = []struct {
+	format      OutputFormat
+	description string
+}{
+	{Text, "Plain text output (default)"},
+	{JSON, "Output wrapped in a JSON object"},
+}
+
 // SupportedFormats is a list of all supported output formats as strings
-var SupportedFormats = []string{
-	string(Text),
-	string(JSON),
+var SupportedFormats = supportedFormats()
+
+func supportedFormats() []string {
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = string(f.format)
+	}
+	return names
+}
+
+// ErrInvalidFormat is matched, using errors.Is, by the error Parse returns
+// for an unsupported format.
+var ErrInvalidFormat = errors.New("invalid format")
+
+// InvalidFormatError is returned by Parse for an unsupported format. It
+// carries the format that was asked for and the supported ones.
+type InvalidFormatError struct {
+	Input     string
+	Supported []string
 }
 
-// Parse converts a string to an OutputFormat
+func (e *InvalidFormatError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidFormat, e.Input)
+}
+
+func (e *InvalidFormatError) Unwrap() error {
+	return ErrInvalidFormat
+}
+
+// Parse converts a string to an OutputFormat. An unsupported format is
+// reported as an *InvalidFormatError.
 func Parse(s string) (OutputFormat, error) {
 	s = strings.ToLower(strings.TrimSpace(s))
 
-	switch s {
-	case string(Text):
-		return Text, nil
-	case string(JSON):
-		return JSON, nil
-	default:
-		return "", fmt.Errorf("invalid format: %s", s)
+	for _, f := range formats {
+		if s == string(f.format) {
+			return f.format, nil
+		}
 	}
+	return "", &InvalidFormatError{Input: s, Supported: supportedFormats()}
 }
 
 // IsValid checks if the provided format string is supported
@@ -50,10 +84,12 @@ func IsValid(s string) bool {
 
 // GetHelpText returns a formatted string describing all supported formats
 func GetHelpText() string {
-	return fmt.Sprintf(`Supported output formats:
-- %s: Plain text output (default)
-- %s: Output wrapped in a JSON object`,
-		Text, JSON)
+	var sb strings.Builder
+	sb.WriteString("Supported output formats:")
+	for _, f := range formats {
+		fmt.Fprintf(&sb, "\n- %s: %s", f.format, f.description)
+	}
+	return sb.String()
 }
 
 // FormatOutput formats the AI response according to the specified format
@@ -0,0 +1,40 @@
+package format
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for input, want := range map[string]OutputFormat{
+		"text":    Text,
+		" JSON\n": JSON,
+	} {
+		got, err := Parse(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	_, err := Parse(" YAML ")
+	require.EqualError(t, err, "invalid format: yaml")
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+	var formatErr *InvalidFormatError
+	require.True(t, errors.As(err, &formatErr))
+	assert.Equal(t, "yaml", formatErr.Input)
+	assert.Equal(t, []string{"text", "json"}, formatErr.Supported)
+
+	assert.True(t, IsValid("json"))
+	assert.False(t, IsValid("yaml"))
+}
+
+func TestGetHelpText(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "Supported output formats:\n- text: Plain text output (default)\n- json: Output wrapped in a JSON object", GetHelpText())
+	assert.Equal(t, []string{"text", "json"}, SupportedFormats)
+}
@@ -0,0 +1,122 @@
+package format
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// OutputMeta is what's known about a response once it has finished, written
+// alongside it by JSONStreamWriter. Empty fields are left out.
+type OutputMeta struct {
+	SessionID    string
+	Model        string
+	FinishReason string
+}
+
+// JSONStreamWriter writes a response as the same JSON object FormatOutput
+// produces, but incrementally: the response string is written as deltas
+// arrive instead of once the whole response is known. The output is valid
+// JSON once Close has been called.
+type JSONStreamWriter struct {
+	w       io.Writer
+	started bool
+	closed  bool
+	err     error
+	// pending holds the start of a UTF-8 sequence split across deltas
+	pending []byte
+}
+
+// NewJSONStreamWriter returns a writer that streams a JSON response to w.
+func NewJSONStreamWriter(w io.Writer) *JSONStreamWriter {
+	return &JSONStreamWriter{w: w}
+}
+
+// WriteDelta appends delta to the response. Characters split across deltas are
+// written once they are complete.
+func (s *JSONStreamWriter) WriteDelta(delta string) error {
+	if s.closed {
+		return errors.New("json stream writer is closed")
+	}
+	s.start()
+
+	data := append(s.pending, delta...)
+	cut := completeRunes(data)
+	s.pending = append([]byte(nil), data[cut:]...)
+	s.writeString(data[:cut])
+	return s.err
+}
+
+// Close ends the response, writes the non-empty fields of meta after it and
+// closes the JSON object. Later calls do nothing.
+func (s *JSONStreamWriter) Close(meta OutputMeta) error {
+	if s.closed {
+		return s.err
+	}
+	s.start()
+	s.closed = true
+
+	s.writeString(s.pending)
+	s.pending = nil
+	s.write(`"`)
+	for _, field := range []struct{ key, value string }{
+		{"session_id", meta.SessionID},
+		{"model", meta.Model},
+		{"finish_reason", meta.FinishReason},
+	} {
+		if field.value == "" {
+			continue
+		}
+		s.write(",\n  " + quote(field.key) + ": " + quote(field.value))
+	}
+	s.write("\n}")
+	return s.err
+}
+
+// start opens the object and the response string on first use.
+func (s *JSONStreamWriter) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.write(`{` + "\n  " + `"response": "`)
+}
+
+// writeString writes data escaped as the inside of a JSON string.
+func (s *JSONStreamWriter) writeString(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	quoted := quote(string(data))
+	s.write(quoted[1 : len(quoted)-1])
+}
+
+func (s *JSONStreamWriter) write(text string) {
+	if s.err != nil {
+		return
+	}
+	_, s.err = io.WriteString(s.w, text)
+}
+
+// quote encodes text as a JSON string, escaped the way FormatOutput escapes
+// it.
+func quote(text string) string {
+	// Marshalling a string can't fail; invalid UTF-8 becomes U+FFFD
+	encoded, _ := json.Marshal(text)
+	return string(encoded)
+}
+
+// completeRunes returns the length of the longest prefix of data that doesn't
+// end partway through a UTF-8 sequence.
+func completeRunes(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if utf8.FullRune(data[i:]) {
+				return len(data)
+			}
+			return i
+		}
+	}
+	return len(data)
+}
@@ -0,0 +1,62 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONStreamWriter(t *testing.T) {
+	t.Run("matches FormatOutput", func(t *testing.T) {
+		content := "Here's the \"fix\":\n\tif a < b && c {\n}\nsee café ✓ \\ done"
+		var buf bytes.Buffer
+		w := NewJSONStreamWriter(&buf)
+
+		// split into small deltas, including inside multi-byte characters
+		data := []byte(content)
+		for i := 0; i < len(data); i += 3 {
+			require.NoError(t, w.WriteDelta(string(data[i:min(i+3, len(data))])))
+		}
+		require.NoError(t, w.Close(OutputMeta{}))
+
+		assert.Equal(t, FormatOutput(content, "json"), buf.String())
+	})
+
+	t.Run("streams before closing", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := NewJSONStreamWriter(&buf)
+		require.NoError(t, w.WriteDelta("partial"))
+		assert.True(t, strings.HasSuffix(buf.String(), `"response": "partial`))
+		assert.False(t, json.Valid(buf.Bytes()))
+
+		require.NoError(t, w.Close(OutputMeta{SessionID: "session", FinishReason: "end_turn"}))
+		var got map[string]string
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		assert.Equal(t, map[string]string{
+			"response":      "partial",
+			"session_id":    "session",
+			"finish_reason": "end_turn",
+		}, got)
+
+		require.NoError(t, w.Close(OutputMeta{}), "closing twice is harmless")
+		assert.Error(t, w.WriteDelta("more"))
+	})
+
+	t.Run("empty response", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewJSONStreamWriter(&buf).Close(OutputMeta{Model: "gpt-4.1"}))
+		assert.JSONEq(t, `{"response": "", "model": "gpt-4.1"}`, buf.String())
+	})
+
+	t.Run("incomplete character at the end", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := NewJSONStreamWriter(&buf)
+		require.NoError(t, w.WriteDelta("ok \xe2\x9c"))
+		require.NoError(t, w.Close(OutputMeta{}))
+		assert.Equal(t, FormatOutput("ok \xe2\x9c", "json"), buf.String())
+	})
+}
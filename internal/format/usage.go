@@ -0,0 +1,66 @@
+package format
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// tokenUnits are the suffixes FormatTokens abbreviates counts with, each a
+// thousand times the one before.
+var tokenUnits = []string{"K", "M", "B"}
+
+// FormatTokens formats a token count for display, abbreviating counts of a
+// thousand or more to one decimal place, such as 950, 1.2K or 3.4M. A count
+// that rounds up to the next unit is shown in that unit, so 999,950 is 1M
+// rather than 1000K.
+func FormatTokens(n int64) string {
+	if n > -1000 && n < 1000 {
+		return strconv.FormatInt(n, 10)
+	}
+
+	value := float64(n)
+	var unit string
+	for _, u := range tokenUnits {
+		value /= 1000
+		unit = u
+		if math.Abs(roundTo(value, 1)) < 1000 {
+			break
+		}
+	}
+	formatted := strconv.FormatFloat(roundTo(value, 1), 'f', 1, 64)
+	return strings.TrimSuffix(formatted, ".0") + unit
+}
+
+// FormatCost formats a cost in US dollars, which is what model prices are
+// given in. Costs under a dollar keep four decimal places so a single request
+// still shows up, such as $0.0123, larger ones are shown to the cent, and
+// costs too small to show are written as <$0.0001.
+func FormatCost(cost float64) string {
+	switch {
+	case cost == 0:
+		return "$0.00"
+	case cost > 0 && roundTo(cost, 4) == 0:
+		return "<$0.0001"
+	case math.Abs(roundTo(cost, 4)) < 1:
+		return formatDollars(cost, 4)
+	default:
+		return formatDollars(cost, 2)
+	}
+}
+
+func formatDollars(amount float64, decimals int) string {
+	amount = roundTo(amount, decimals)
+	if amount < 0 {
+		return fmt.Sprintf("-$%.*f", decimals, -amount)
+	}
+	return fmt.Sprintf("$%.*f", decimals, amount)
+}
+
+// roundTo rounds value to the given number of decimal places, halves away
+// from zero.
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,53 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatTokens(t *testing.T) {
+	tests := []struct {
+		tokens int64
+		want   string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1_000, "1K"},
+		{1_049, "1K"},
+		{1_050, "1.1K"},
+		{1_234, "1.2K"},
+		{110_000, "110K"},
+		{999_949, "999.9K"},
+		{999_950, "1M"},
+		{3_400_000, "3.4M"},
+		{999_950_000, "1B"},
+		{12_300_000_000, "12.3B"},
+		{-1_500, "-1.5K"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, FormatTokens(tt.tokens), tt.tokens)
+	}
+}
+
+func TestFormatCost(t *testing.T) {
+	tests := []struct {
+		cost float64
+		want string
+	}{
+		{0, "$0.00"},
+		{0.00004, "<$0.0001"},
+		{0.00005, "$0.0001"},
+		{0.0123, "$0.0123"},
+		{0.01234, "$0.0123"},
+		{0.99994, "$0.9999"},
+		{0.99995, "$1.00"},
+		{1, "$1.00"},
+		{12.345, "$12.35"},
+		{1234.5, "$1234.50"},
+		{-0.5, "-$0.5000"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, FormatCost(tt.cost), tt.cost)
+	}
+}
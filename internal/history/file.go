@@ -1,9 +1,15 @@
 package history
 
 import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"fmt"
+	"io"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -15,6 +21,10 @@ import (
 
 const (
 	InitialVersion = "initial"
+
+	// compressionThreshold is the content size in bytes above which file
+	// content is gzip-compressed before it is stored.
+	compressionThreshold = 8 * 1024
 )
 
 type File struct {
@@ -27,6 +37,14 @@ type File struct {
 	UpdatedAt int64
 }
 
+// FileChangeSummary describes how a single file changed during a session.
+type FileChangeSummary struct {
+	Path          string
+	VersionCount  int
+	LatestVersion string
+	UpdatedAt     int64
+}
+
 type Service interface {
 	pubsub.Suscriber[File]
 	Create(ctx context.Context, sessionID, path, content string) (File, error)
@@ -35,9 +53,11 @@ type Service interface {
 	GetByPathAndSession(ctx context.Context, path, sessionID string) (File, error)
 	ListBySession(ctx context.Context, sessionID string) ([]File, error)
 	ListLatestSessionFiles(ctx context.Context, sessionID string) ([]File, error)
+	ChangedFiles(ctx context.Context, sessionID string) ([]FileChangeSummary, error)
 	Update(ctx context.Context, file File) (File, error)
 	Delete(ctx context.Context, id string) error
 	DeleteSessionFiles(ctx context.Context, sessionID string) error
+	Prune(ctx context.Context, sessionID string, keep int) error
 }
 
 type service struct {
@@ -111,13 +131,20 @@ func (s *service) createWithVersion(ctx context.Context, sessionID, path, conten
 		// Create a new queries instance with the transaction
 		qtx := s.q.WithTx(tx)
 
+		storedContent, compressed, txErr := compressContent(content)
+		if txErr != nil {
+			tx.Rollback()
+			return File{}, txErr
+		}
+
 		// Try to create the file within the transaction
 		dbFile, txErr := qtx.CreateFile(ctx, db.CreateFileParams{
-			ID:        uuid.New().String(),
-			SessionID: sessionID,
-			Path:      path,
-			Content:   content,
-			Version:   version,
+			ID:         uuid.New().String(),
+			SessionID:  sessionID,
+			Path:       path,
+			Content:    storedContent,
+			Version:    version,
+			Compressed: compressed,
 		})
 		if txErr != nil {
 			// Rollback the transaction
@@ -147,7 +174,10 @@ func (s *service) createWithVersion(ctx context.Context, sessionID, path, conten
 			return File{}, fmt.Errorf("failed to commit transaction: %w", txErr)
 		}
 
-		file = s.fromDBItem(dbFile)
+		file, err = s.fromDBItem(dbFile)
+		if err != nil {
+			return File{}, err
+		}
 		s.Publish(pubsub.CreatedEvent, file)
 		return file, nil
 	}
@@ -160,7 +190,7 @@ func (s *service) Get(ctx context.Context, id string) (File, error) {
 	if err != nil {
 		return File{}, err
 	}
-	return s.fromDBItem(dbFile), nil
+	return s.fromDBItem(dbFile)
 }
 
 func (s *service) GetByPathAndSession(ctx context.Context, path, sessionID string) (File, error) {
@@ -171,7 +201,7 @@ func (s *service) GetByPathAndSession(ctx context.Context, path, sessionID strin
 	if err != nil {
 		return File{}, err
 	}
-	return s.fromDBItem(dbFile), nil
+	return s.fromDBItem(dbFile)
 }
 
 func (s *service) ListBySession(ctx context.Context, sessionID string) ([]File, error) {
@@ -181,7 +211,10 @@ func (s *service) ListBySession(ctx context.Context, sessionID string) ([]File,
 	}
 	files := make([]File, len(dbFiles))
 	for i, dbFile := range dbFiles {
-		files[i] = s.fromDBItem(dbFile)
+		files[i], err = s.fromDBItem(dbFile)
+		if err != nil {
+			return nil, err
+		}
 	}
 	return files, nil
 }
@@ -193,21 +226,72 @@ func (s *service) ListLatestSessionFiles(ctx context.Context, sessionID string)
 	}
 	files := make([]File, len(dbFiles))
 	for i, dbFile := range dbFiles {
-		files[i] = s.fromDBItem(dbFile)
+		files[i], err = s.fromDBItem(dbFile)
+		if err != nil {
+			return nil, err
+		}
 	}
 	return files, nil
 }
 
+// ChangedFiles summarizes every file touched in the session, ordered by path.
+// It works on the raw rows so stored content is never decompressed.
+func (s *service) ChangedFiles(ctx context.Context, sessionID string) ([]FileChangeSummary, error) {
+	dbFiles, err := s.q.ListFilesBySession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[string]*FileChangeSummary)
+	latest := make(map[string]File)
+	for _, dbFile := range dbFiles {
+		// Only the version metadata is needed, leave the content out
+		file := File{Path: dbFile.Path, Version: dbFile.Version, CreatedAt: dbFile.CreatedAt}
+		summary, ok := summaries[dbFile.Path]
+		if !ok {
+			summary = &FileChangeSummary{Path: dbFile.Path}
+			summaries[dbFile.Path] = summary
+		}
+		summary.VersionCount++
+
+		current, ok := latest[dbFile.Path]
+		if !ok || compareVersions(file, current) > 0 {
+			latest[dbFile.Path] = file
+			summary.LatestVersion = file.Version
+		}
+		if dbFile.UpdatedAt > summary.UpdatedAt {
+			summary.UpdatedAt = dbFile.UpdatedAt
+		}
+	}
+
+	result := make([]FileChangeSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, *summary)
+	}
+	slices.SortFunc(result, func(a, b FileChangeSummary) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return result, nil
+}
+
 func (s *service) Update(ctx context.Context, file File) (File, error) {
+	storedContent, compressed, err := compressContent(file.Content)
+	if err != nil {
+		return File{}, err
+	}
 	dbFile, err := s.q.UpdateFile(ctx, db.UpdateFileParams{
-		ID:      file.ID,
-		Content: file.Content,
-		Version: file.Version,
+		ID:         file.ID,
+		Content:    storedContent,
+		Version:    file.Version,
+		Compressed: compressed,
 	})
 	if err != nil {
 		return File{}, err
 	}
-	updatedFile := s.fromDBItem(dbFile)
+	updatedFile, err := s.fromDBItem(dbFile)
+	if err != nil {
+		return File{}, err
+	}
 	s.Publish(pubsub.UpdatedEvent, updatedFile)
 	return updatedFile, nil
 }
@@ -239,14 +323,133 @@ func (s *service) DeleteSessionFiles(ctx context.Context, sessionID string) erro
 	return nil
 }
 
-func (s *service) fromDBItem(item db.File) File {
+// Prune keeps the initial version and the most recent keep versions of every
+// file in the session and deletes the rest.
+func (s *service) Prune(ctx context.Context, sessionID string, keep int) error {
+	if keep <= 0 {
+		return fmt.Errorf("keep must be greater than zero, got %d", keep)
+	}
+
+	files, err := s.ListBySession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	versionsByPath := make(map[string][]File)
+	for _, file := range files {
+		if file.Version == InitialVersion {
+			continue
+		}
+		versionsByPath[file.Path] = append(versionsByPath[file.Path], file)
+	}
+
+	var pruned []File
+	for _, versions := range versionsByPath {
+		if len(versions) <= keep {
+			continue
+		}
+		// Oldest first, so everything before the last keep entries goes
+		slices.SortStableFunc(versions, compareVersions)
+		pruned = append(pruned, versions[:len(versions)-keep]...)
+	}
+	if len(pruned) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	qtx := s.q.WithTx(tx)
+	for _, file := range pruned {
+		if err := qtx.DeleteFile(ctx, file.ID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, file := range pruned {
+		s.Publish(pubsub.DeletedEvent, file)
+	}
+	return nil
+}
+
+// compareVersions orders two versions of a file by creation time, using the
+// version number to break ties within the same second.
+func compareVersions(a, b File) int {
+	if a.CreatedAt != b.CreatedAt {
+		return cmp.Compare(a.CreatedAt, b.CreatedAt)
+	}
+	return cmp.Compare(versionNumber(a.Version), versionNumber(b.Version))
+}
+
+// versionNumber returns the numeric part of a "vN" version, or -1 if the
+// version does not follow that format.
+func versionNumber(version string) int {
+	if !strings.HasPrefix(version, "v") {
+		return -1
+	}
+	n, err := strconv.Atoi(version[1:])
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func (s *service) fromDBItem(item db.File) (File, error) {
+	content := item.Content
+	if item.Compressed {
+		var err error
+		content, err = decompressContent(item.Content)
+		if err != nil {
+			return File{}, fmt.Errorf("failed to decompress file %s: %w", item.ID, err)
+		}
+	}
 	return File{
 		ID:        item.ID,
 		SessionID: item.SessionID,
 		Path:      item.Path,
-		Content:   item.Content,
+		Content:   content,
 		Version:   item.Version,
 		CreatedAt: item.CreatedAt,
 		UpdatedAt: item.UpdatedAt,
+	}, nil
+}
+
+// compressContent gzips content that exceeds compressionThreshold and returns
+// it base64 encoded so it can be stored in a TEXT column. Smaller content is
+// returned unchanged.
+func compressContent(content string) (string, bool, error) {
+	if len(content) <= compressionThreshold {
+		return content, false, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		return "", false, fmt.Errorf("failed to compress content: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return "", false, fmt.Errorf("failed to compress content: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), true, nil
+}
+
+func decompressContent(stored string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(stored)
+	if err != nil {
+		return "", err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		return "", err
 	}
+	return string(content), nil
 }
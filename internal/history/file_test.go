@@ -0,0 +1,197 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "opencode.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	goose.SetBaseFS(db.FS)
+	goose.SetLogger(goose.NopLogger())
+	require.NoError(t, goose.SetDialect("sqlite3"))
+	require.NoError(t, goose.Up(conn, "migrations"))
+
+	_, err = conn.Exec(`INSERT INTO sessions (id, title, created_at, updated_at) VALUES ('session', 'session', 0, 0), ('other', 'other', 0, 0)`)
+	require.NoError(t, err)
+	return conn
+}
+
+// insertFile stores a version of a file created at the given time, returning
+// its ID. Versions made through the service share a creation time when they
+// are made within a second, so tests that depend on their order insert them.
+func insertFile(t *testing.T, conn *sql.DB, sessionID, path, version, content string, createdAt int64) string {
+	t.Helper()
+	id := fmt.Sprintf("%s:%s:%s", sessionID, path, version)
+	_, err := conn.Exec(
+		`INSERT INTO files (id, session_id, path, content, version, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, sessionID, path, content, version, createdAt, createdAt,
+	)
+	require.NoError(t, err)
+	return id
+}
+
+// storedFile returns the content and compressed flag of a file as they are
+// stored, without decompressing.
+func storedFile(t *testing.T, conn *sql.DB, id string) (string, bool) {
+	t.Helper()
+	var content string
+	var compressed bool
+	require.NoError(t, conn.QueryRow(`SELECT content, compressed FROM files WHERE id = ?`, id).Scan(&content, &compressed))
+	return content, compressed
+}
+
+func TestCompressedContentRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	s := NewService(db.New(conn), conn)
+
+	large := strings.Repeat("func main() { println(\"hello, world\") }\n", compressionThreshold/20)
+	require.Greater(t, len(large), compressionThreshold)
+
+	file, err := s.Create(ctx, "session", "/project/main.go", large)
+	require.NoError(t, err)
+	assert.Equal(t, large, file.Content)
+	stored, compressed := storedFile(t, conn, file.ID)
+	assert.True(t, compressed)
+	assert.Less(t, len(stored), len(large))
+
+	got, err := s.Get(ctx, file.ID)
+	require.NoError(t, err)
+	assert.Equal(t, large, got.Content)
+
+	// an update recompresses, or stores small content as is
+	changed := large + "// changed\n"
+	file.Content = changed
+	file, err = s.Update(ctx, file)
+	require.NoError(t, err)
+	assert.Equal(t, changed, file.Content)
+	_, compressed = storedFile(t, conn, file.ID)
+	assert.True(t, compressed)
+
+	file.Content = "small"
+	file, err = s.Update(ctx, file)
+	require.NoError(t, err)
+	stored, compressed = storedFile(t, conn, file.ID)
+	assert.False(t, compressed)
+	assert.Equal(t, "small", stored)
+
+	version, err := s.CreateVersion(ctx, "session", "/project/main.go", large)
+	require.NoError(t, err)
+	files, err := s.ListBySession(ctx, "session")
+	require.NoError(t, err)
+	contents := map[string]string{}
+	for _, f := range files {
+		contents[f.ID] = f.Content
+	}
+	assert.Equal(t, map[string]string{file.ID: "small", version.ID: large}, contents)
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	s := NewService(db.New(conn), conn)
+
+	assert.Error(t, s.Prune(ctx, "session", 0))
+	assert.Error(t, s.Prune(ctx, "session", -1))
+
+	insertFile(t, conn, "session", "/project/a.go", InitialVersion, "a0", 1)
+	for i := 1; i <= 4; i++ {
+		insertFile(t, conn, "session", "/project/a.go", fmt.Sprintf("v%d", i), fmt.Sprintf("a%d", i), int64(1+i))
+	}
+	insertFile(t, conn, "session", "/project/b.go", InitialVersion, "b0", 1)
+	insertFile(t, conn, "session", "/project/b.go", "v1", "b1", 2)
+	insertFile(t, conn, "other", "/project/a.go", InitialVersion, "a0", 1)
+	for i := 1; i <= 4; i++ {
+		insertFile(t, conn, "other", "/project/a.go", fmt.Sprintf("v%d", i), fmt.Sprintf("a%d", i), int64(1+i))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := s.Subscribe(ctx)
+	require.NoError(t, s.Prune(ctx, "session", 2))
+
+	// every path keeps its initial version and its latest two
+	versions := func(sessionID string) map[string][]string {
+		files, err := s.ListBySession(context.Background(), sessionID)
+		require.NoError(t, err)
+		byPath := map[string][]string{}
+		for _, f := range files {
+			byPath[f.Path] = append(byPath[f.Path], f.Version)
+		}
+		return byPath
+	}
+	got := versions("session")
+	assert.ElementsMatch(t, []string{InitialVersion, "v3", "v4"}, got["/project/a.go"])
+	assert.ElementsMatch(t, []string{InitialVersion, "v1"}, got["/project/b.go"])
+	assert.ElementsMatch(t, []string{InitialVersion, "v1", "v2", "v3", "v4"}, versions("other")["/project/a.go"])
+
+	var deleted []string
+	for range 2 {
+		select {
+		case event := <-events:
+			assert.Equal(t, pubsub.DeletedEvent, event.Type)
+			assert.Equal(t, "session", event.Payload.SessionID)
+			deleted = append(deleted, event.Payload.Version)
+		case <-time.After(time.Second):
+			require.Fail(t, "no event for a pruned version")
+		}
+	}
+	assert.ElementsMatch(t, []string{"v1", "v2"}, deleted)
+	select {
+	case event := <-events:
+		assert.Fail(t, "unexpected event", "%s %s", event.Type, event.Payload.Version)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// nothing left to prune
+	require.NoError(t, s.Prune(ctx, "session", 2))
+	assert.Equal(t, got, versions("session"))
+}
+
+func TestChangedFiles(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	s := NewService(db.New(conn), conn)
+
+	changed, err := s.ChangedFiles(ctx, "session")
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	// main.go existed and was modified twice, added.go was created by the
+	// session, so its initial version is empty
+	insertFile(t, conn, "session", "/project/main.go", InitialVersion, "package main", 10)
+	insertFile(t, conn, "session", "/project/main.go", "v1", "package main\n", 20)
+	insertFile(t, conn, "session", "/project/main.go", "v2", "package main\n\nfunc main() {}\n", 30)
+	insertFile(t, conn, "session", "/project/added.go", InitialVersion, "", 15)
+	insertFile(t, conn, "session", "/project/added.go", "v1", "package project\n", 15)
+	insertFile(t, conn, "other", "/project/other.go", InitialVersion, "", 40)
+
+	changed, err = s.ChangedFiles(ctx, "session")
+	require.NoError(t, err)
+	assert.Equal(t, []FileChangeSummary{
+		{Path: "/project/added.go", VersionCount: 2, LatestVersion: "v1", UpdatedAt: 15},
+		{Path: "/project/main.go", VersionCount: 3, LatestVersion: "v2", UpdatedAt: 30},
+	}, changed)
+
+	changed, err = s.ChangedFiles(ctx, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+}
@@ -24,14 +24,23 @@ import (
 var (
 	ErrRequestCancelled = errors.New("request cancelled by user")
 	ErrSessionBusy      = errors.New("session is currently processing another request")
+	ErrProviderDisabled = errors.New("provider is disabled")
 )
 
+// streamSaveInterval is how often streamed text and reasoning are saved, so a
+// crash loses at most that much of a response and subscribers that dropped a
+// delta catch up.
+const streamSaveInterval = time.Second
+
 type AgentEventType string
 
 const (
 	AgentEventTypeError     AgentEventType = "error"
 	AgentEventTypeResponse  AgentEventType = "response"
 	AgentEventTypeSummarize AgentEventType = "summarize"
+	// AgentEventTypeProviderDisabled is published when the agent's provider
+	// is disabled after failing too many times in a row.
+	AgentEventTypeProviderDisabled AgentEventType = "provider_disabled"
 )
 
 type AgentEvent struct {
@@ -158,12 +167,13 @@ func (a *agent) generateTitle(ctx context.Context, sessionID string, content str
 	if a.titleProvider == nil {
 		return nil
 	}
-	session, err := a.sessions.Get(ctx, sessionID)
+	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
+	parts := []message.ContentPart{message.TextContent{Text: content}}
+	release, err := acquireRequestSlot(ctx)
 	if err != nil {
 		return err
 	}
-	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
-	parts := []message.ContentPart{message.TextContent{Text: content}}
+	defer release()
 	response, err := a.titleProvider.SendMessages(
 		ctx,
 		[]message.Message{
@@ -178,13 +188,10 @@ func (a *agent) generateTitle(ctx context.Context, sessionID string, content str
 		return err
 	}
 
-	title := strings.TrimSpace(strings.ReplaceAll(response.Content, "\n", " "))
-	if title == "" {
+	if strings.TrimSpace(response.Content) == "" {
 		return nil
 	}
-
-	session.Title = title
-	_, err = a.sessions.Save(ctx, session)
+	_, err = a.sessions.SetTitle(ctx, sessionID, response.Content)
 	return err
 }
 
@@ -196,13 +203,17 @@ func (a *agent) err(err error) AgentEvent {
 }
 
 func (a *agent) Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan AgentEvent, error) {
-	if !a.provider.Model().SupportsAttachments && attachments != nil {
+	if !a.provider.Model().Supports(models.CapabilityImages) && attachments != nil {
+		logging.Warn("Model does not support images, dropping attachments", "model", a.provider.Model().ID, "attachments", len(attachments))
 		attachments = nil
 	}
 	events := make(chan AgentEvent)
 	if a.IsSessionBusy(sessionID) {
 		return nil, ErrSessionBusy
 	}
+	if providerName := a.provider.Model().Provider; config.IsProviderDisabled(providerName) {
+		return nil, fmt.Errorf("%w: %s", ErrProviderDisabled, providerName)
+	}
 
 	genCtx, cancel := context.WithCancel(ctx)
 
@@ -248,22 +259,21 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 			}
 		}()
 	}
-	session, err := a.sessions.Get(ctx, sessionID)
-	if err != nil {
-		return a.err(fmt.Errorf("failed to get session: %w", err))
-	}
-	if session.SummaryMessageID != "" {
-		summaryMsgInex := -1
-		for i, msg := range msgs {
-			if msg.ID == session.SummaryMessageID {
-				summaryMsgInex = i
-				break
-			}
-		}
-		if summaryMsgInex != -1 {
-			msgs = msgs[summaryMsgInex:]
-			msgs[0].Role = message.User
+	// Once summarized, only pinned messages, the summary and what followed it
+	// are sent.
+	summary, err := a.sessions.GetSummary(ctx, sessionID)
+	switch {
+	case errors.Is(err, session.ErrNoSummary):
+	case err != nil:
+		return a.err(fmt.Errorf("failed to get session summary: %w", err))
+	default:
+		after, err := a.messages.ListAfter(ctx, sessionID, summary.ID)
+		if err != nil {
+			return a.err(fmt.Errorf("failed to list messages after summary: %w", err))
 		}
+		summary.Role = message.User
+		kept := pinnedBefore(msgs, summary.ID)
+		msgs = append(append(kept, summary), after...)
 	}
 
 	userMsg, err := a.createUserMessage(ctx, sessionID, content, attachmentParts)
@@ -310,6 +320,21 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 	}
 }
 
+// pinnedBefore returns the pinned messages that come before the message with
+// the given ID, which compaction would otherwise leave out.
+func pinnedBefore(msgs []message.Message, id string) []message.Message {
+	var pinned []message.Message
+	for _, msg := range msgs {
+		if msg.ID == id {
+			break
+		}
+		if msg.Pinned {
+			pinned = append(pinned, msg)
+		}
+	}
+	return pinned
+}
+
 func (a *agent) createUserMessage(ctx context.Context, sessionID, content string, attachmentParts []message.ContentPart) (message.Message, error) {
 	parts := []message.ContentPart{message.TextContent{Text: content}}
 	parts = append(parts, attachmentParts...)
@@ -319,9 +344,43 @@ func (a *agent) createUserMessage(ctx context.Context, sessionID, content string
 	})
 }
 
+// withoutImages strips image parts from the history so that earlier
+// attachments don't break requests to models that can't read them.
+func withoutImages(msgs []message.Message) []message.Message {
+	filtered := make([]message.Message, len(msgs))
+	for i, msg := range msgs {
+		parts := make([]message.ContentPart, 0, len(msg.Parts))
+		for _, part := range msg.Parts {
+			switch part.(type) {
+			case message.BinaryContent, message.ImageURLContent:
+				continue
+			}
+			parts = append(parts, part)
+		}
+		msg.Parts = parts
+		filtered[i] = msg
+	}
+	return filtered
+}
+
 func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msgHistory []message.Message) (message.Message, *message.Message, error) {
 	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
-	eventChan := a.provider.StreamResponse(ctx, msgHistory, a.tools)
+	if !a.provider.Model().Supports(models.CapabilityImages) {
+		msgHistory = withoutImages(msgHistory)
+	}
+	agentTools := a.tools
+	if !a.provider.Model().Supports(models.CapabilityTools) {
+		logging.Warn("Model does not support tool calls, sending request without tools", "model", a.provider.Model().ID)
+		agentTools = nil
+	}
+	// The slot is freed once the response has streamed, before tools run, so
+	// sub-agents started by tools can make requests of their own.
+	release, err := acquireRequestSlot(ctx)
+	if err != nil {
+		return message.Message{}, nil, err
+	}
+	defer release()
+	eventChan := a.provider.StreamResponse(ctx, msgHistory, agentTools)
 
 	assistantMsg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
 		Role:  message.Assistant,
@@ -336,8 +395,10 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 	ctx = context.WithValue(ctx, tools.MessageIDContextKey, assistantMsg.ID)
 
 	// Process each event in the stream.
+	stream := message.NewStreamAssembler(&assistantMsg)
+	stream.PublishDeltas(a.messages.DeltaPublisher())
 	for event := range eventChan {
-		if processErr := a.processEvent(ctx, sessionID, &assistantMsg, event); processErr != nil {
+		if processErr := a.processEvent(ctx, sessionID, stream, event); processErr != nil {
 			a.finishMessage(ctx, &assistantMsg, message.FinishReasonCanceled)
 			return assistantMsg, nil, processErr
 		}
@@ -346,6 +407,7 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 			return assistantMsg, nil, ctx.Err()
 		}
 	}
+	release()
 
 	toolResults := make([]message.ToolResult, len(assistantMsg.ToolCalls()))
 	toolCalls := assistantMsg.ToolCalls()
@@ -357,8 +419,9 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 			for j := i; j < len(toolCalls); j++ {
 				toolResults[j] = message.ToolResult{
 					ToolCallID: toolCalls[j].ID,
-					Content:    "Tool execution canceled by user",
+					Content:    message.ToolCanceledContent,
 					IsError:    true,
+					Canceled:   true,
 				}
 			}
 			goto out
@@ -387,6 +450,14 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 				}
 				continue
 			}
+			if err := toolCall.ValidateInput(tool.Info().InputSchema()); err != nil {
+				toolResults[i] = message.ToolResult{
+					ToolCallID: toolCall.ID,
+					Content:    err.Error(),
+					IsError:    true,
+				}
+				continue
+			}
 			toolResult, toolErr := tool.Run(ctx, tools.ToolCall{
 				ID:    toolCall.ID,
 				Name:  toolCall.Name,
@@ -402,8 +473,9 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 					for j := i + 1; j < len(toolCalls); j++ {
 						toolResults[j] = message.ToolResult{
 							ToolCallID: toolCalls[j].ID,
-							Content:    "Tool execution canceled by user",
+							Content:    message.ToolCanceledContent,
 							IsError:    true,
+							Canceled:   true,
 						}
 					}
 					a.finishMessage(ctx, &assistantMsg, message.FinishReasonPermissionDenied)
@@ -442,7 +514,7 @@ func (a *agent) finishMessage(ctx context.Context, msg *message.Message, finishR
 	_ = a.messages.Update(ctx, *msg)
 }
 
-func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg *message.Message, event provider.ProviderEvent) error {
+func (a *agent) processEvent(ctx context.Context, sessionID string, stream *message.StreamAssembler, event provider.ProviderEvent) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -450,65 +522,97 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 		// Continue processing.
 	}
 
+	// Text and thinking reach subscribers as deltas; the message itself is
+	// saved once a tool call starts or the response completes.
 	switch event.Type {
 	case provider.EventThinkingDelta:
-		assistantMsg.AppendReasoningContent(event.Content)
-		return a.messages.Update(ctx, *assistantMsg)
+		stream.AppendReasoningDelta(event.Content)
+		if stream.SaveDue(streamSaveInterval) {
+			return a.messages.Update(ctx, stream.Message())
+		}
 	case provider.EventContentDelta:
-		assistantMsg.AppendContent(event.Content)
-		return a.messages.Update(ctx, *assistantMsg)
+		stream.AppendTextDelta(event.Content)
+		if stream.SaveDue(streamSaveInterval) {
+			return a.messages.Update(ctx, stream.Message())
+		}
 	case provider.EventToolUseStart:
-		assistantMsg.AddToolCall(*event.ToolCall)
-		return a.messages.Update(ctx, *assistantMsg)
+		stream.StartToolCall(*event.ToolCall)
+		return a.messages.Update(ctx, stream.Message())
 	// TODO: see how to handle this
 	// case provider.EventToolUseDelta:
-	// 	tm := time.Unix(assistantMsg.UpdatedAt, 0)
-	// 	assistantMsg.AppendToolCallInput(event.ToolCall.ID, event.ToolCall.Input)
-	// 	if time.Since(tm) > 1000*time.Millisecond {
-	// 		err := a.messages.Update(ctx, *assistantMsg)
-	// 		assistantMsg.UpdatedAt = time.Now().Unix()
-	// 		return err
+	// 	msg := stream.Message()
+	// 	stream.AppendToolCallInputDelta(event.ToolCall.ID, event.ToolCall.Input)
+	// 	if time.Since(time.Unix(msg.UpdatedAt, 0)) > 1000*time.Millisecond {
+	// 		return a.messages.Update(ctx, stream.Message())
 	// 	}
 	case provider.EventToolUseStop:
-		assistantMsg.FinishToolCall(event.ToolCall.ID)
-		return a.messages.Update(ctx, *assistantMsg)
+		stream.FinishToolCall(event.ToolCall.ID)
+		return a.messages.Update(ctx, stream.Message())
 	case provider.EventError:
 		if errors.Is(event.Error, context.Canceled) {
 			logging.InfoPersist(fmt.Sprintf("Event processing canceled for session: %s", sessionID))
 			return context.Canceled
 		}
 		logging.ErrorPersist(event.Error.Error())
+		a.recordProviderResult(event.Error)
 		return event.Error
 	case provider.EventComplete:
-		assistantMsg.SetToolCalls(event.Response.ToolCalls)
-		assistantMsg.AddFinish(event.Response.FinishReason)
-		if err := a.messages.Update(ctx, *assistantMsg); err != nil {
+		a.recordProviderResult(nil)
+		stream.SetToolCalls(event.Response.ToolCalls)
+		usage := event.Response.Usage
+		stream.FinishWithUsage(
+			event.Response.FinishReason,
+			usage.InputTokens+usage.CacheCreationTokens,
+			usage.OutputTokens,
+			usage.CacheReadTokens,
+		)
+		if err := a.messages.Update(ctx, stream.Message()); err != nil {
 			return fmt.Errorf("failed to update message: %w", err)
 		}
-		return a.TrackUsage(ctx, sessionID, a.provider.Model(), event.Response.Usage)
+		return a.TrackUsage(ctx, sessionID, a.provider.Model(), usage)
 	}
 
 	return nil
 }
 
-func (a *agent) TrackUsage(ctx context.Context, sessionID string, model models.Model, usage provider.TokenUsage) error {
-	sess, err := a.sessions.Get(ctx, sessionID)
-	if err != nil {
-		return fmt.Errorf("failed to get session: %w", err)
+// acquireRequestSlot waits until the configured limit on concurrent provider
+// requests allows another one. The returned func frees the slot; calling it
+// more than once is safe.
+func acquireRequestSlot(ctx context.Context) (func(), error) {
+	limiter := config.Get().RequestLimiter()
+	if err := limiter.Acquire(ctx); err != nil {
+		return nil, err
 	}
+	return sync.OnceFunc(limiter.Release), nil
+}
 
-	cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-		model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-		model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-		model.CostPer1MOut/1e6*float64(usage.OutputTokens)
+// recordProviderResult counts consecutive provider failures and announces when
+// they get the provider disabled.
+func (a *agent) recordProviderResult(err error) {
+	providerName := a.provider.Model().Provider
+	if !config.RecordProviderResult(providerName, err) {
+		return
+	}
+	logging.ErrorPersist(fmt.Sprintf("Provider %s disabled after repeated failures", providerName))
+	a.Publish(pubsub.CreatedEvent, AgentEvent{
+		Type:  AgentEventTypeProviderDisabled,
+		Error: fmt.Errorf("%w: %s", ErrProviderDisabled, providerName),
+	})
+}
 
-	sess.Cost += cost
-	sess.CompletionTokens = usage.OutputTokens + usage.CacheReadTokens
-	sess.PromptTokens = usage.InputTokens + usage.CacheCreationTokens
+func (a *agent) TrackUsage(ctx context.Context, sessionID string, model models.Model, usage provider.TokenUsage) error {
+	cost := model.Cost(usage.InputTokens, usage.OutputTokens) +
+		model.CachedCost(usage.CacheCreationTokens, usage.CacheReadTokens)
 
-	_, err = a.sessions.Save(ctx, sess)
+	_, err := a.sessions.SetUsage(
+		ctx,
+		sessionID,
+		usage.InputTokens+usage.CacheCreationTokens,
+		usage.OutputTokens+usage.CacheReadTokens,
+		cost,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to save session: %w", err)
+		return fmt.Errorf("failed to save session usage: %w", err)
 	}
 	return nil
 }
@@ -606,11 +710,16 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 		a.Publish(pubsub.CreatedEvent, event)
 
 		// Send the messages to the summarize provider
-		response, err := a.summarizeProvider.SendMessages(
-			summarizeCtx,
-			msgsWithPrompt,
-			make([]tools.BaseTool, 0),
-		)
+		var response *provider.ProviderResponse
+		release, err := acquireRequestSlot(summarizeCtx)
+		if err == nil {
+			response, err = a.summarizeProvider.SendMessages(
+				summarizeCtx,
+				msgsWithPrompt,
+				make([]tools.BaseTool, 0),
+			)
+			release()
+		}
 		if err != nil {
 			event = AgentEvent{
 				Type:  AgentEventTypeError,
@@ -675,10 +784,8 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 		oldSession.PromptTokens = 0
 		model := a.summarizeProvider.Model()
 		usage := response.Usage
-		cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-			model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-			model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-			model.CostPer1MOut/1e6*float64(usage.OutputTokens)
+		cost := model.Cost(usage.InputTokens, usage.OutputTokens) +
+			model.CachedCost(usage.CacheCreationTokens, usage.CacheReadTokens)
 		oldSession.Cost += cost
 		_, err = a.sessions.Save(summarizeCtx, oldSession)
 		if err != nil {
@@ -705,8 +812,8 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 
 func createAgentProvider(agentName config.AgentName) (provider.Provider, error) {
 	cfg := config.Get()
-	agentConfig, ok := cfg.Agents[agentName]
-	if !ok {
+	agentConfig := cfg.AgentOrDefault(agentName)
+	if agentConfig.Model == "" {
 		return nil, fmt.Errorf("agent %s not found", agentName)
 	}
 	model, ok := models.SupportedModels[agentConfig.Model]
@@ -730,6 +837,8 @@ func createAgentProvider(agentName config.AgentName) (provider.Provider, error)
 		provider.WithModel(model),
 		provider.WithSystemMessage(prompt.GetAgentPrompt(agentName, model.Provider)),
 		provider.WithMaxTokens(maxTokens),
+		provider.WithTimeout(providerCfg.TimeoutOrDefault()),
+		provider.WithMaxRetries(providerCfg.MaxRetriesOrDefault()),
 	}
 	if model.Provider == models.ProviderOpenAI || model.Provider == models.ProviderLocal && model.CanReason {
 		opts = append(
@@ -25,6 +25,7 @@ var AnthropicModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.30,
 		CostPer1MOut:        15.0,
 		ContextWindow:       200000,
+		MaxOutputTokens:     8192,
 		DefaultMaxTokens:    5000,
 		SupportsAttachments: true,
 	},
@@ -38,6 +39,7 @@ var AnthropicModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.03,
 		CostPer1MOut:        1.25,
 		ContextWindow:       200000,
+		MaxOutputTokens:     4096,
 		DefaultMaxTokens:    4096,
 		SupportsAttachments: true,
 	},
@@ -51,6 +53,7 @@ var AnthropicModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.30,
 		CostPer1MOut:        15.0,
 		ContextWindow:       200000,
+		MaxOutputTokens:     64000,
 		DefaultMaxTokens:    50000,
 		CanReason:           true,
 		SupportsAttachments: true,
@@ -65,6 +68,7 @@ var AnthropicModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.08,
 		CostPer1MOut:        4.0,
 		ContextWindow:       200000,
+		MaxOutputTokens:     8192,
 		DefaultMaxTokens:    4096,
 		SupportsAttachments: true,
 	},
@@ -78,6 +82,7 @@ var AnthropicModels = map[ModelID]Model{
 		CostPer1MOutCached:  1.50,
 		CostPer1MOut:        75.0,
 		ContextWindow:       200000,
+		MaxOutputTokens:     4096,
 		DefaultMaxTokens:    4096,
 		SupportsAttachments: true,
 	},
@@ -91,6 +96,7 @@ var AnthropicModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.30,
 		CostPer1MOut:        15.0,
 		ContextWindow:       200000,
+		MaxOutputTokens:     64000,
 		DefaultMaxTokens:    50000,
 		CanReason:           true,
 		SupportsAttachments: true,
@@ -105,6 +111,7 @@ var AnthropicModels = map[ModelID]Model{
 		CostPer1MOutCached:  1.50,
 		CostPer1MOut:        75.0,
 		ContextWindow:       200000,
+		MaxOutputTokens:     32000,
 		DefaultMaxTokens:    4096,
 		SupportsAttachments: true,
 	},
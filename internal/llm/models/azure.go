@@ -121,6 +121,7 @@ var AzureModels = map[ModelID]Model{
 		ContextWindow:       OpenAIModels[O1Mini].ContextWindow,
 		DefaultMaxTokens:    OpenAIModels[O1Mini].DefaultMaxTokens,
 		CanReason:           OpenAIModels[O1Mini].CanReason,
+		NoToolCalls:         OpenAIModels[O1Mini].NoToolCalls,
 		SupportsAttachments: true,
 	},
 	AzureO3: {
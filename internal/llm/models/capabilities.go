@@ -0,0 +1,58 @@
+package models
+
+import (
+	"slices"
+	"strings"
+)
+
+// Capability is an optional feature a model may support.
+type Capability string
+
+const (
+	CapabilityImages    Capability = "images"
+	CapabilityTools     Capability = "tools"
+	CapabilityReasoning Capability = "reasoning"
+)
+
+// Supports reports whether the model can handle the given capability, so
+// callers can degrade gracefully instead of hitting a provider error.
+func (m Model) Supports(c Capability) bool {
+	switch c {
+	case CapabilityImages:
+		return m.SupportsAttachments
+	case CapabilityTools:
+		return !m.NoToolCalls
+	case CapabilityReasoning:
+		return m.CanReason
+	default:
+		return false
+	}
+}
+
+// OutputTokenLimit returns the most tokens the model will generate in one
+// response. Models without a published limit are allowed half their context
+// window; zero means the limit is unknown.
+func (m Model) OutputTokenLimit() int64 {
+	if m.MaxOutputTokens > 0 {
+		return m.MaxOutputTokens
+	}
+	return m.ContextWindow / 2
+}
+
+// reasoningEfforts are the effort levels accepted by models that can reason.
+var reasoningEfforts = []string{"low", "medium", "high"}
+
+// ReasoningEfforts returns the reasoning effort levels the model accepts, or
+// nil if it can't reason.
+func (m Model) ReasoningEfforts() []string {
+	if !m.CanReason {
+		return nil
+	}
+	return slices.Clone(reasoningEfforts)
+}
+
+// SupportsReasoningEffort reports whether effort is one of the model's
+// ReasoningEfforts, ignoring case.
+func (m Model) SupportsReasoningEffort(effort string) bool {
+	return slices.Contains(m.ReasoningEfforts(), strings.ToLower(effort))
+}
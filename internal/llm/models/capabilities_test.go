@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupports(t *testing.T) {
+	t.Parallel()
+
+	o1Mini := OpenAIModels[O1Mini]
+	assert.True(t, o1Mini.Supports(CapabilityReasoning))
+	assert.True(t, o1Mini.Supports(CapabilityImages))
+	assert.False(t, o1Mini.Supports(CapabilityTools))
+	assert.False(t, AzureModels[AzureO1Mini].Supports(CapabilityTools))
+
+	plain := Model{}
+	assert.True(t, plain.Supports(CapabilityTools))
+	assert.False(t, plain.Supports(CapabilityImages))
+	assert.False(t, plain.Supports(CapabilityReasoning))
+	assert.False(t, plain.Supports(Capability("audio")))
+}
+
+func TestOutputTokenLimit(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, int64(8192), AnthropicModels[Claude35Sonnet].OutputTokenLimit())
+	assert.Equal(t, int64(50000), Model{ContextWindow: 100000}.OutputTokenLimit())
+	assert.Zero(t, Model{}.OutputTokenLimit())
+}
+
+func TestReasoningEfforts(t *testing.T) {
+	t.Parallel()
+
+	o3 := OpenAIModels[O3]
+	assert.Equal(t, []string{"low", "medium", "high"}, o3.ReasoningEfforts())
+	assert.True(t, o3.SupportsReasoningEffort("HIGH"))
+	assert.False(t, o3.SupportsReasoningEffort("max"))
+	assert.False(t, o3.SupportsReasoningEffort(""))
+
+	gpt41 := OpenAIModels[GPT41]
+	assert.Nil(t, gpt41.ReasoningEfforts())
+	assert.False(t, gpt41.SupportsReasoningEffort("low"))
+}
@@ -0,0 +1,24 @@
+package models
+
+// Cost returns the price in USD of the given prompt and completion tokens at
+// the model's per-million input and output rates.
+func (m Model) Cost(promptTokens, completionTokens int64) float64 {
+	return m.CostPer1MIn/1e6*float64(promptTokens) +
+		m.CostPer1MOut/1e6*float64(completionTokens)
+}
+
+// CachedCost returns the price in USD of prompt tokens written to and read
+// from the provider's prompt cache. Models without cache pricing bill those
+// tokens at the input rate.
+func (m Model) CachedCost(cacheCreationTokens, cacheReadTokens int64) float64 {
+	writeRate := m.CostPer1MInCached
+	if writeRate == 0 {
+		writeRate = m.CostPer1MIn
+	}
+	readRate := m.CostPer1MOutCached
+	if readRate == 0 {
+		readRate = m.CostPer1MIn
+	}
+	return writeRate/1e6*float64(cacheCreationTokens) +
+		readRate/1e6*float64(cacheReadTokens)
+}
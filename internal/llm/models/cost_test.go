@@ -0,0 +1,24 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCost(t *testing.T) {
+	t.Parallel()
+
+	m := Model{
+		CostPer1MIn:        3,
+		CostPer1MOut:       15,
+		CostPer1MInCached:  3.75,
+		CostPer1MOutCached: 0.3,
+	}
+	assert.InDelta(t, 0.003+0.015, m.Cost(1000, 1000), 1e-12)
+	assert.InDelta(t, 0.00375+0.0003, m.CachedCost(1000, 1000), 1e-12)
+
+	uncached := Model{CostPer1MIn: 2, CostPer1MOut: 8}
+	assert.InDelta(t, 0.004, uncached.CachedCost(1000, 1000), 1e-12)
+	assert.Zero(t, Model{}.Cost(1000, 1000))
+}
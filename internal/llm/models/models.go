@@ -18,8 +18,10 @@ type Model struct {
 	CostPer1MOutCached  float64       `json:"cost_per_1m_out_cached"`
 	ContextWindow       int64         `json:"context_window"`
 	DefaultMaxTokens    int64         `json:"default_max_tokens"`
+	MaxOutputTokens     int64         `json:"max_output_tokens"`
 	CanReason           bool          `json:"can_reason"`
 	SupportsAttachments bool          `json:"supports_attachments"`
+	NoToolCalls         bool          `json:"no_tool_calls"`
 }
 
 // Model IDs
@@ -28,6 +28,7 @@ var OpenAIModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.0,
 		CostPer1MOut:        8.00,
 		ContextWindow:       1_047_576,
+		MaxOutputTokens:     32_768,
 		DefaultMaxTokens:    20000,
 		SupportsAttachments: true,
 	},
@@ -41,6 +42,7 @@ var OpenAIModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.0,
 		CostPer1MOut:        1.60,
 		ContextWindow:       200_000,
+		MaxOutputTokens:     32_768,
 		DefaultMaxTokens:    20000,
 		SupportsAttachments: true,
 	},
@@ -54,6 +56,7 @@ var OpenAIModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.0,
 		CostPer1MOut:        0.40,
 		ContextWindow:       1_047_576,
+		MaxOutputTokens:     32_768,
 		DefaultMaxTokens:    20000,
 		SupportsAttachments: true,
 	},
@@ -67,6 +70,7 @@ var OpenAIModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.0,
 		CostPer1MOut:        150.00,
 		ContextWindow:       128_000,
+		MaxOutputTokens:     16_384,
 		DefaultMaxTokens:    15000,
 		SupportsAttachments: true,
 	},
@@ -80,6 +84,7 @@ var OpenAIModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.0,
 		CostPer1MOut:        10.00,
 		ContextWindow:       128_000,
+		MaxOutputTokens:     16_384,
 		DefaultMaxTokens:    4096,
 		SupportsAttachments: true,
 	},
@@ -93,6 +98,7 @@ var OpenAIModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.0,
 		CostPer1MOut:        0.60,
 		ContextWindow:       128_000,
+		MaxOutputTokens:     16_384,
 		SupportsAttachments: true,
 	},
 	O1: {
@@ -105,6 +111,7 @@ var OpenAIModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.0,
 		CostPer1MOut:        60.00,
 		ContextWindow:       200_000,
+		MaxOutputTokens:     100_000,
 		DefaultMaxTokens:    50000,
 		CanReason:           true,
 		SupportsAttachments: true,
@@ -119,6 +126,7 @@ var OpenAIModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.0,
 		CostPer1MOut:        600.00,
 		ContextWindow:       200_000,
+		MaxOutputTokens:     100_000,
 		DefaultMaxTokens:    50000,
 		CanReason:           true,
 		SupportsAttachments: true,
@@ -133,9 +141,11 @@ var OpenAIModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.0,
 		CostPer1MOut:        4.40,
 		ContextWindow:       128_000,
+		MaxOutputTokens:     65_536,
 		DefaultMaxTokens:    50000,
 		CanReason:           true,
 		SupportsAttachments: true,
+		NoToolCalls:         true,
 	},
 	O3: {
 		ID:                  O3,
@@ -147,6 +157,7 @@ var OpenAIModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.0,
 		CostPer1MOut:        40.00,
 		ContextWindow:       200_000,
+		MaxOutputTokens:     100_000,
 		CanReason:           true,
 		SupportsAttachments: true,
 	},
@@ -160,6 +171,7 @@ var OpenAIModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.0,
 		CostPer1MOut:        4.40,
 		ContextWindow:       200_000,
+		MaxOutputTokens:     100_000,
 		DefaultMaxTokens:    50000,
 		CanReason:           true,
 		SupportsAttachments: false,
@@ -174,6 +186,7 @@ var OpenAIModels = map[ModelID]Model{
 		CostPer1MOutCached:  0.0,
 		CostPer1MOut:        4.40,
 		ContextWindow:       128_000,
+		MaxOutputTokens:     100_000,
 		DefaultMaxTokens:    50000,
 		CanReason:           true,
 		SupportsAttachments: true,
@@ -135,6 +135,7 @@ var OpenRouterModels = map[ModelID]Model{
 		ContextWindow:      OpenAIModels[O1Mini].ContextWindow,
 		DefaultMaxTokens:   OpenAIModels[O1Mini].DefaultMaxTokens,
 		CanReason:          OpenAIModels[O1Mini].CanReason,
+		NoToolCalls:        OpenAIModels[O1Mini].NoToolCalls,
 	},
 	OpenRouterO3: {
 		ID:                 OpenRouterO3,
@@ -2,9 +2,6 @@ package prompt
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
 	"sync"
 
 	"github.com/opencode-ai/opencode/internal/config"
@@ -27,9 +24,10 @@ func GetAgentPrompt(agentName config.AgentName, provider models.ModelProvider) s
 		basePrompt = "You are a helpful assistant"
 	}
 
-	if agentName == config.AgentCoder || agentName == config.AgentTask {
+	// Agents with their own context paths get project context as well
+	if agentName == config.AgentCoder || agentName == config.AgentTask || len(config.Get().Agents[agentName].ContextPaths) > 0 {
 		// Add context from project-specific instruction files if they exist
-		contextContent := getContextFromPaths()
+		contextContent := getContextFromPaths(agentName)
 		logging.Debug("Context content", "Context", contextContent)
 		if contextContent != "" {
 			return fmt.Sprintf("%s\n\n# Project-Specific Context\n Make sure to follow the instructions in the context below\n%s", basePrompt, contextContent)
@@ -38,100 +36,36 @@ func GetAgentPrompt(agentName config.AgentName, provider models.ModelProvider) s
 	return basePrompt
 }
 
+// maxContextBytes caps how much project context is added to the prompt so a
+// few large instruction files can't use up the token budget.
+const maxContextBytes = 64 * 1024
+
 var (
-	onceContext    sync.Once
-	contextContent string
+	contextMu      sync.Mutex
+	contextDir     string
+	contextContent = make(map[config.AgentName]string)
 )
 
-func getContextFromPaths() string {
-	onceContext.Do(func() {
-		var (
-			cfg          = config.Get()
-			workDir      = cfg.WorkingDir
-			contextPaths = cfg.ContextPaths
-		)
-
-		contextContent = processContextPaths(workDir, contextPaths)
-	})
-
-	return contextContent
-}
-
-func processContextPaths(workDir string, paths []string) string {
-	var (
-		wg       sync.WaitGroup
-		resultCh = make(chan string)
-	)
-
-	// Track processed files to avoid duplicates
-	processedFiles := make(map[string]bool)
-	var processedMutex sync.Mutex
-
-	for _, path := range paths {
-		wg.Add(1)
-		go func(p string) {
-			defer wg.Done()
+// getContextFromPaths loads an agent's project context once per working
+// directory.
+func getContextFromPaths(agentName config.AgentName) string {
+	contextMu.Lock()
+	defer contextMu.Unlock()
 
-			if strings.HasSuffix(p, "/") {
-				filepath.WalkDir(filepath.Join(workDir, p), func(path string, d os.DirEntry, err error) error {
-					if err != nil {
-						return err
-					}
-					if !d.IsDir() {
-						// Check if we've already processed this file (case-insensitive)
-						processedMutex.Lock()
-						lowerPath := strings.ToLower(path)
-						if !processedFiles[lowerPath] {
-							processedFiles[lowerPath] = true
-							processedMutex.Unlock()
-
-							if result := processFile(path); result != "" {
-								resultCh <- result
-							}
-						} else {
-							processedMutex.Unlock()
-						}
-					}
-					return nil
-				})
-			} else {
-				fullPath := filepath.Join(workDir, p)
-
-				// Check if we've already processed this file (case-insensitive)
-				processedMutex.Lock()
-				lowerPath := strings.ToLower(fullPath)
-				if !processedFiles[lowerPath] {
-					processedFiles[lowerPath] = true
-					processedMutex.Unlock()
-
-					result := processFile(fullPath)
-					if result != "" {
-						resultCh <- result
-					}
-				} else {
-					processedMutex.Unlock()
-				}
-			}
-		}(path)
+	workingDir := config.WorkingDirectory()
+	if workingDir != contextDir {
+		contextDir = workingDir
+		clear(contextContent)
 	}
-
-	go func() {
-		wg.Wait()
-		close(resultCh)
-	}()
-
-	results := make([]string, 0)
-	for result := range resultCh {
-		results = append(results, result)
+	if content, ok := contextContent[agentName]; ok {
+		return content
 	}
 
-	return strings.Join(results, "\n")
-}
-
-func processFile(filePath string) string {
-	content, err := os.ReadFile(filePath)
+	content, err := config.Get().LoadContextFor(agentName, workingDir, maxContextBytes)
 	if err != nil {
+		logging.Warn("failed to load project context", "agent", agentName, "error", err)
 		return ""
 	}
-	return "# From:" + filePath + "\n" + string(content)
+	contextContent[agentName] = content
+	return content
 }
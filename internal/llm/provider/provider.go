@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/opencode-ai/opencode/internal/llm/models"
 	"github.com/opencode-ai/opencode/internal/llm/tools"
@@ -12,7 +13,9 @@ import (
 
 type EventType string
 
-const maxRetries = 8
+// defaultMaxRetries is how many times a failed request is retried when the
+// provider isn't given a limit.
+const defaultMaxRetries = 8
 
 const (
 	EventContentStart  EventType = "content_start"
@@ -63,6 +66,8 @@ type providerClientOptions struct {
 	model         models.Model
 	maxTokens     int64
 	systemMessage string
+	timeout       time.Duration
+	maxRetries    int
 
 	anthropicOptions []AnthropicOption
 	openaiOptions    []OpenAIOption
@@ -84,7 +89,7 @@ type baseProvider[C ProviderClient] struct {
 }
 
 func NewProvider(providerName models.ModelProvider, opts ...ProviderClientOption) (Provider, error) {
-	clientOptions := providerClientOptions{}
+	clientOptions := providerClientOptions{maxRetries: defaultMaxRetries}
 	for _, o := range opts {
 		o(&clientOptions)
 	}
@@ -180,6 +185,11 @@ func (p *baseProvider[C]) cleanMessages(messages []message.Message) (cleaned []m
 
 func (p *baseProvider[C]) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
 	messages = p.cleanMessages(messages)
+	if p.options.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.options.timeout)
+		defer cancel()
+	}
 	return p.client.send(ctx, messages, tools)
 }
 
@@ -189,7 +199,42 @@ func (p *baseProvider[C]) Model() models.Model {
 
 func (p *baseProvider[C]) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
 	messages = p.cleanMessages(messages)
-	return p.client.stream(ctx, messages, tools)
+	if p.options.timeout <= 0 {
+		return p.client.stream(ctx, messages, tools)
+	}
+
+	// The timeout is released once the client is done streaming
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, p.options.timeout)
+	events := p.client.stream(ctx, messages, tools)
+	forwarded := make(chan ProviderEvent)
+	go func() {
+		defer close(forwarded)
+		defer cancel()
+		finished := false
+		for event := range events {
+			finished = finished || event.Type == EventComplete || event.Type == EventError
+			select {
+			case forwarded <- event:
+			case <-parent.Done():
+				// nobody is reading any more; cancelling ctx ends the
+				// client's stream, which is drained so it can finish
+				cancel()
+				for range events {
+				}
+				return
+			}
+		}
+		// a stream cut short by the timeout must not look like one that
+		// ended normally
+		if !finished && ctx.Err() == context.DeadlineExceeded {
+			select {
+			case forwarded <- ProviderEvent{Type: EventError, Error: context.DeadlineExceeded}:
+			case <-parent.Done():
+			}
+		}
+	}()
+	return forwarded
 }
 
 func WithAPIKey(apiKey string) ProviderClientOption {
@@ -216,6 +261,21 @@ func WithSystemMessage(systemMessage string) ProviderClientOption {
 	}
 }
 
+// WithTimeout bounds each request, retries included. Zero means no limit.
+func WithTimeout(timeout time.Duration) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.timeout = timeout
+	}
+}
+
+// WithMaxRetries sets how many times a failed request is retried. Zero turns
+// retries off.
+func WithMaxRetries(maxRetries int) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.maxRetries = maxRetries
+	}
+}
+
 func WithAnthropicOptions(anthropicOptions ...AnthropicOption) ProviderClientOption {
 	return func(options *providerClientOptions) {
 		options.anthropicOptions = anthropicOptions
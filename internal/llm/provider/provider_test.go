@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hangingClient streams one delta and then hangs until the request is
+// cancelled, reporting the cancellation as an error when reportErr is set.
+type hangingClient struct {
+	reportErr bool
+}
+
+func (c hangingClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (c hangingClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	events := make(chan ProviderEvent)
+	go func() {
+		defer close(events)
+		events <- ProviderEvent{Type: EventContentDelta, Content: "partial"}
+		<-ctx.Done()
+		if c.reportErr {
+			events <- ProviderEvent{Type: EventError, Error: ctx.Err()}
+		}
+	}()
+	return events
+}
+
+func TestStreamResponseTimeout(t *testing.T) {
+	for _, reportErr := range []bool{true, false} {
+		p := &baseProvider[hangingClient]{
+			options: providerClientOptions{timeout: 20 * time.Millisecond},
+			client:  hangingClient{reportErr: reportErr},
+		}
+		msgs := []message.Message{{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "hi"}}}}
+
+		var received []ProviderEvent
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for event := range p.StreamResponse(context.Background(), msgs, nil) {
+				received = append(received, event)
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			require.Fail(t, "stream did not end after the timeout")
+		}
+
+		require.Len(t, received, 2, "reportErr=%v", reportErr)
+		assert.Equal(t, EventContentDelta, received[0].Type)
+		assert.Equal(t, EventError, received[1].Type)
+		assert.ErrorIs(t, received[1].Error, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,64 @@
+// Package tokenize estimates how many tokens a piece of text uses, per
+// provider. Providers without a registered tokenizer fall back to a
+// character based heuristic.
+package tokenize
+
+import (
+	"sync"
+	"unicode/utf8"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+)
+
+// Tokenizer counts the tokens a model would see for text.
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// TokenizerFunc adapts a plain function to a Tokenizer.
+type TokenizerFunc func(text string) int
+
+func (f TokenizerFunc) Count(text string) int {
+	return f(text)
+}
+
+// charsPerToken is the rough number of characters in a token for English
+// text and code.
+const charsPerToken = 4
+
+// Fallback estimates one token per four characters, rounding up.
+var Fallback Tokenizer = TokenizerFunc(func(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+})
+
+var (
+	mu         sync.RWMutex
+	tokenizers = make(map[models.ModelProvider]Tokenizer)
+)
+
+// Register sets the tokenizer used for a provider, replacing any registered
+// before. Registering nil restores the fallback.
+func Register(provider models.ModelProvider, t Tokenizer) {
+	mu.Lock()
+	defer mu.Unlock()
+	if t == nil {
+		delete(tokenizers, provider)
+		return
+	}
+	tokenizers[provider] = t
+}
+
+// For returns the tokenizer registered for provider, or Fallback.
+func For(provider models.ModelProvider) Tokenizer {
+	mu.RLock()
+	defer mu.RUnlock()
+	if t, ok := tokenizers[provider]; ok {
+		return t
+	}
+	return Fallback
+}
+
+// Count counts the tokens in text using the provider's tokenizer.
+func Count(provider models.ModelProvider, text string) int {
+	return For(provider).Count(text)
+}
@@ -0,0 +1,33 @@
+package tokenize
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFallback(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 0, Fallback.Count(""))
+	assert.Equal(t, 1, Fallback.Count("abc"))
+	assert.Equal(t, 1, Fallback.Count("abcd"))
+	assert.Equal(t, 2, Fallback.Count("abcde"))
+	assert.Equal(t, 1, Fallback.Count("ééé"), "counts characters, not bytes")
+	assert.Equal(t, Fallback.Count("unregistered text"), Count(models.ProviderMock, "unregistered text"))
+}
+
+func TestRegister(t *testing.T) {
+	const provider models.ModelProvider = "stub"
+	t.Cleanup(func() { Register(provider, nil) })
+
+	words := TokenizerFunc(func(text string) int { return len(strings.Fields(text)) })
+	Register(provider, words)
+	assert.Equal(t, 3, Count(provider, "one two three"))
+	assert.Equal(t, 4, Count(models.ProviderOpenAI, "one two three"), "other providers keep the fallback")
+
+	Register(provider, nil)
+	assert.Equal(t, 4, Count(provider, "one two three"))
+}
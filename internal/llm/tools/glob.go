@@ -96,6 +96,9 @@ func (g *globTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	if params.Pattern == "" {
 		return NewTextErrorResponse("pattern is required"), nil
 	}
+	if err := fileutil.ValidatePattern(params.Pattern); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
 
 	searchPath := params.Path
 	if searchPath == "" {
@@ -137,7 +140,7 @@ func globFiles(pattern, searchPath string, limit int) ([]string, bool, error) {
 		logging.Warn(fmt.Sprintf("Ripgrep execution failed: %v. Falling back to doublestar.", err))
 	}
 
-	return fileutil.GlobWithDoublestar(pattern, searchPath, limit)
+	return fileutil.GlobWithDoublestar(pattern, searchPath, limit, false)
 }
 
 func runRipgrep(cmd *exec.Cmd, searchRoot string, limit int) ([]string, error) {
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/opencode-ai/opencode/internal/config"
@@ -48,6 +50,9 @@ The patch text must follow this format:
 +Content of the new file
 +More content
 *** Delete File: /path/to/file/to/delete
+*** Move File: /path/to/old/file
+*** Move to: /path/to/new/file
+@@ Optional hunks, as for Update File, to change the file as it moves
 *** End Patch
 
 Before using this tool:
@@ -150,6 +155,7 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 
 	// Load all required files
 	currentFiles := make(map[string]string)
+	fileModes := make(map[string]os.FileMode)
 	for _, filePath := range filesToRead {
 		absPath := filePath
 		if !filepath.IsAbs(absPath) {
@@ -162,6 +168,9 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			return ToolResponse{}, fmt.Errorf("failed to read file %s: %w", absPath, err)
 		}
 		currentFiles[filePath] = string(content)
+		if info, err := os.Stat(absPath); err == nil {
+			fileModes[filePath] = info.Mode().Perm()
+		}
 	}
 
 	// Process the patch
@@ -179,6 +188,21 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	if err != nil {
 		return NewTextErrorResponse(fmt.Sprintf("failed to create commit from patch: %s", err)), nil
 	}
+	commit.SetModes(fileModes)
+
+	// Moves must not overwrite existing files
+	for _, change := range commit.Changes {
+		if change.MovePath == nil {
+			continue
+		}
+		absPath := *change.MovePath
+		if !filepath.IsAbs(absPath) {
+			absPath = filepath.Join(config.WorkingDirectory(), absPath)
+		}
+		if _, err := os.Stat(absPath); err == nil {
+			return NewTextErrorResponse(fmt.Sprintf("file already exists and cannot be moved onto: %s", absPath)), nil
+		}
+	}
 
 	// Get session ID and message ID
 	sessionID, messageID := GetContextValues(ctx)
@@ -186,79 +210,78 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for creating a patch")
 	}
 
-	// Request permission for all changes
-	for path, change := range commit.Changes {
+	// Request permission for all changes in one prompt. Each request is for
+	// the file itself so the outcomes can be told apart.
+	var requests []permission.CreatePermissionRequest
+	for _, path := range slices.Sorted(maps.Keys(commit.Changes)) {
+		change := commit.Changes[path]
+		oldContent := ""
+		if change.OldContent != nil {
+			oldContent = *change.OldContent
+		}
+		newContent := ""
+		if change.NewContent != nil {
+			newContent = *change.NewContent
+		}
+		patchDiff, _, _ := diff.GenerateDiff(oldContent, newContent, path)
+
+		var action, description string
 		switch change.Type {
 		case diff.ActionAdd:
-			dir := filepath.Dir(path)
-			patchDiff, _, _ := diff.GenerateDiff("", *change.NewContent, path)
-			p := p.permissions.Request(
-				permission.CreatePermissionRequest{
-					SessionID:   sessionID,
-					Path:        dir,
-					ToolName:    PatchToolName,
-					Action:      "create",
-					Description: fmt.Sprintf("Create file %s", path),
-					Params: EditPermissionsParams{
-						FilePath: path,
-						Diff:     patchDiff,
-					},
-				},
-			)
-			if !p {
-				return ToolResponse{}, permission.ErrorPermissionDenied
-			}
+			action = "create"
+			description = fmt.Sprintf("Create file %s", path)
 		case diff.ActionUpdate:
-			currentContent := ""
-			if change.OldContent != nil {
-				currentContent = *change.OldContent
-			}
-			newContent := ""
-			if change.NewContent != nil {
-				newContent = *change.NewContent
-			}
-			patchDiff, _, _ := diff.GenerateDiff(currentContent, newContent, path)
-			dir := filepath.Dir(path)
-			p := p.permissions.Request(
-				permission.CreatePermissionRequest{
-					SessionID:   sessionID,
-					Path:        dir,
-					ToolName:    PatchToolName,
-					Action:      "update",
-					Description: fmt.Sprintf("Update file %s", path),
-					Params: EditPermissionsParams{
-						FilePath: path,
-						Diff:     patchDiff,
-					},
-				},
-			)
-			if !p {
-				return ToolResponse{}, permission.ErrorPermissionDenied
+			action = "update"
+			description = fmt.Sprintf("Update file %s", path)
+			if change.MovePath != nil {
+				description = fmt.Sprintf("Move file %s to %s", path, *change.MovePath)
 			}
 		case diff.ActionDelete:
-			dir := filepath.Dir(path)
-			patchDiff, _, _ := diff.GenerateDiff(*change.OldContent, "", path)
-			p := p.permissions.Request(
-				permission.CreatePermissionRequest{
-					SessionID:   sessionID,
-					Path:        dir,
-					ToolName:    PatchToolName,
-					Action:      "delete",
-					Description: fmt.Sprintf("Delete file %s", path),
-					Params: EditPermissionsParams{
-						FilePath: path,
-						Diff:     patchDiff,
-					},
+			action = "delete"
+			description = fmt.Sprintf("Delete file %s", path)
+		default:
+			continue
+		}
+		requests = append(requests, permission.CreatePermissionRequest{
+			SessionID:   sessionID,
+			Path:        path,
+			ToolName:    PatchToolName,
+			Action:      action,
+			Description: description,
+			Params: EditPermissionsParams{
+				FilePath: path,
+				Diff:     patchDiff,
+			},
+		})
+		if change.MovePath != nil {
+			// the destination is written too, so it needs its own approval
+			// and is checked against the deny rules like any new file
+			dest := *change.MovePath
+			requests = append(requests, permission.CreatePermissionRequest{
+				SessionID:   sessionID,
+				Path:        dest,
+				ToolName:    PatchToolName,
+				Action:      "create",
+				Description: fmt.Sprintf("Create file %s, moved from %s", dest, path),
+				Params: EditPermissionsParams{
+					FilePath: dest,
+					Diff:     patchDiff,
 				},
-			)
-			if !p {
-				return ToolResponse{}, permission.ErrorPermissionDenied
-			}
+			})
+		}
+	}
+	granted, err := p.permissions.RequestBatch(requests)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("failed to request permission: %w", err)
+	}
+	for _, ok := range granted {
+		if !ok {
+			return ToolResponse{}, permission.ErrorPermissionDenied
 		}
 	}
 
 	// Apply the changes to the filesystem
-	err = diff.ApplyCommit(commit, func(path string, content string) error {
+	writeFile := func(path string, content string, mode os.FileMode) error {
 		absPath := path
 		if !filepath.IsAbs(absPath) {
 			wd := config.WorkingDirectory()
@@ -271,15 +294,18 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			return fmt.Errorf("failed to create parent directories for %s: %w", absPath, err)
 		}
 
-		return os.WriteFile(absPath, []byte(content), 0o644)
-	}, func(path string) error {
+		// new files, moved ones included, are created with mode; existing
+		// files keep theirs
+		return os.WriteFile(absPath, []byte(content), mode)
+	}
+	err = diff.ApplyCommit(commit, nil, func(path string) error {
 		absPath := path
 		if !filepath.IsAbs(absPath) {
 			wd := config.WorkingDirectory()
 			absPath = filepath.Join(wd, absPath)
 		}
 		return os.Remove(absPath)
-	})
+	}, diff.WithModeWriter(writeFile))
 	if err != nil {
 		return NewTextErrorResponse(fmt.Sprintf("failed to apply patch: %s", err)), nil
 	}
@@ -295,7 +321,15 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			wd := config.WorkingDirectory()
 			absPath = filepath.Join(wd, absPath)
 		}
-		changedFiles = append(changedFiles, absPath)
+		// a moved file's new content lives at its destination
+		targetPath := absPath
+		if change.MovePath != nil {
+			targetPath = *change.MovePath
+			if !filepath.IsAbs(targetPath) {
+				targetPath = filepath.Join(config.WorkingDirectory(), targetPath)
+			}
+		}
+		changedFiles = append(changedFiles, targetPath)
 
 		oldContent := ""
 		if change.OldContent != nil {
@@ -331,9 +365,16 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		}
 
 		// Store new version
-		if change.Type == diff.ActionDelete {
+		switch {
+		case change.Type == diff.ActionDelete:
 			_, err = p.files.CreateVersion(ctx, sessionID, absPath, "")
-		} else {
+		case change.MovePath != nil:
+			// the source is gone and the destination starts with the new content
+			if _, err := p.files.CreateVersion(ctx, sessionID, absPath, ""); err != nil {
+				logging.Debug("Error creating file history version", "error", err)
+			}
+			_, err = p.files.CreateVersion(ctx, sessionID, targetPath, newContent)
+		default:
 			_, err = p.files.CreateVersion(ctx, sessionID, absPath, newContent)
 		}
 		if err != nil {
@@ -341,8 +382,8 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		}
 
 		// Record file operations
-		recordFileWrite(absPath)
-		recordFileRead(absPath)
+		recordFileWrite(targetPath)
+		recordFileRead(targetPath)
 	}
 
 	// Run LSP diagnostics on all changed files
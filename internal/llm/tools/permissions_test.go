@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/history"
+	"github.com/opencode-ai/opencode/internal/permission"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fileToolCall runs tool on input in a session, as the agent would.
+func fileToolCall(t *testing.T, tool BaseTool, input any) (ToolResponse, error) {
+	t.Helper()
+	data, err := json.Marshal(input)
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "session")
+	ctx = context.WithValue(ctx, MessageIDContextKey, "message")
+	return tool.Run(ctx, ToolCall{ID: "call", Input: string(data)})
+}
+
+// loadTestConfig loads a config working in dir, with no user config.
+func loadTestConfig(t *testing.T, dir string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	_, err := config.Load(dir, false)
+	require.NoError(t, err)
+}
+
+// newTestHistory returns a file history backed by a fresh database holding
+// the session "session".
+func newTestHistory(t *testing.T) history.Service {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "opencode.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	goose.SetBaseFS(db.FS)
+	goose.SetLogger(goose.NopLogger())
+	require.NoError(t, goose.SetDialect("sqlite3"))
+	require.NoError(t, goose.Up(conn, "migrations"))
+
+	_, err = conn.Exec(`INSERT INTO sessions (id, title, created_at, updated_at) VALUES ('session', 'session', 0, 0)`)
+	require.NoError(t, err)
+	return history.NewService(db.New(conn), conn)
+}
+
+// denyPrompts denies every permission prompt the service publishes and
+// passes it on, so requests nothing covers fail instead of waiting.
+func denyPrompts(t *testing.T, s permission.Service) <-chan permission.PermissionRequest {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	events := s.Subscribe(ctx)
+	prompted := make(chan permission.PermissionRequest, 10)
+	go func() {
+		for event := range events {
+			prompted <- event.Payload
+			s.Deny(event.Payload)
+		}
+	}()
+	return prompted
+}
+
+func TestFileToolsHonourDenyRules(t *testing.T) {
+	dir := t.TempDir()
+	loadTestConfig(t, dir)
+	existing := filepath.Join(dir, ".env")
+	require.NoError(t, os.WriteFile(existing, []byte("TOKEN=secret\n"), 0o644))
+	recordFileRead(existing)
+
+	permissions := permission.NewPermissionService()
+	permissions.AutoApproveSession("session")
+	require.NoError(t, permissions.AddDenyRule(permission.DenyRule{Path: existing}))
+	require.NoError(t, permissions.AddDenyRule(permission.DenyRule{Path: "**/*.lock"}))
+
+	edit := NewEditTool(nil, permissions, nil)
+	write := NewWriteTool(nil, permissions, nil)
+	tests := []struct {
+		name  string
+		tool  BaseTool
+		input any
+	}{
+		{"edit replace", edit, EditParams{FilePath: existing, OldString: "secret", NewString: "leaked"}},
+		{"edit delete", edit, EditParams{FilePath: existing, OldString: "TOKEN=secret\n"}},
+		{"edit create", edit, EditParams{FilePath: filepath.Join(dir, "go.lock"), NewString: "locked"}},
+		{"write existing", write, WriteParams{FilePath: existing, Content: "TOKEN=leaked\n"}},
+		{"write new", write, WriteParams{FilePath: filepath.Join(dir, "deps", "yarn.lock"), Content: "locked"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fileToolCall(t, tt.tool, tt.input)
+			assert.ErrorIs(t, err, permission.ErrorPermissionDenied)
+		})
+	}
+
+	content, err := os.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, "TOKEN=secret\n", string(content))
+	assert.NoFileExists(t, filepath.Join(dir, "go.lock"))
+	assert.NoFileExists(t, filepath.Join(dir, "deps", "yarn.lock"))
+}
+
+func TestFileToolsHonourGlobGrants(t *testing.T) {
+	dir := t.TempDir()
+	loadTestConfig(t, dir)
+	files := newTestHistory(t)
+
+	permissions := permission.NewPermissionService()
+	pattern := filepath.Join(dir, "src", "**", "*.go")
+	for _, tool := range []string{EditToolName, WriteToolName} {
+		permissions.GrantPersistant(permission.PermissionRequest{
+			SessionID: "session",
+			ToolName:  tool,
+			Action:    "write",
+			Path:      pattern,
+		})
+	}
+	prompted := denyPrompts(t, permissions)
+
+	edit := NewEditTool(nil, permissions, files)
+	write := NewWriteTool(nil, permissions, files)
+	main := filepath.Join(dir, "src", "main.go")
+	util := filepath.Join(dir, "src", "pkg", "util.go")
+
+	_, err := fileToolCall(t, write, WriteParams{FilePath: main, Content: "package main\n"})
+	require.NoError(t, err)
+	_, err = fileToolCall(t, edit, EditParams{FilePath: util, NewString: "package pkg\n"})
+	require.NoError(t, err)
+	_, err = fileToolCall(t, edit, EditParams{FilePath: util, OldString: "pkg", NewString: "util"})
+	require.NoError(t, err)
+	assert.Empty(t, prompted, "granted files were prompted")
+
+	content, err := os.ReadFile(util)
+	require.NoError(t, err)
+	assert.Equal(t, "package util\n", string(content))
+
+	// files the pattern doesn't cover still need the user's approval
+	readme := filepath.Join(dir, "src", "README.md")
+	_, err = fileToolCall(t, write, WriteParams{FilePath: readme, Content: "# src\n"})
+	assert.ErrorIs(t, err, permission.ErrorPermissionDenied)
+	_, err = fileToolCall(t, edit, EditParams{FilePath: readme, NewString: "# src\n"})
+	assert.ErrorIs(t, err, permission.ErrorPermissionDenied)
+	assert.Len(t, prompted, 2)
+	assert.NoFileExists(t, readme)
+}
@@ -12,6 +12,25 @@ type ToolInfo struct {
 	Required    []string
 }
 
+// InputSchema returns the JSON Schema of the tool's input: an object with the
+// tool's parameters as its properties.
+func (i ToolInfo) InputSchema() json.RawMessage {
+	required := i.Required
+	if required == nil {
+		required = []string{}
+	}
+	schema, err := json.Marshal(map[string]any{
+		"type":       "object",
+		"properties": i.Parameters,
+		"required":   required,
+	})
+	if err != nil {
+		// parameters that can't be encoded aren't checked
+		return json.RawMessage(`{"type":"object"}`)
+	}
+	return schema
+}
+
 type toolResponseType string
 
 type (
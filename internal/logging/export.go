@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logfmt/logfmt"
+)
+
+// Formats accepted by Export
+const (
+	ExportFormatText = "text"
+	ExportFormatJSON = "json"
+)
+
+type exportedMessage struct {
+	ID          string `json:"id"`
+	Time        string `json:"time"`
+	Level       string `json:"level"`
+	Message     string `json:"msg"`
+	Attributes  []Attr `json:"attributes"`
+	Persist     bool   `json:"persist"`
+	PersistTime string `json:"persist_time,omitempty"`
+	SessionID   string `json:"session_id,omitempty"`
+}
+
+// Export writes every log message collected so far to path, either as logfmt
+// lines ("text") or as a JSON array ("json"). Parent directories are created
+// as needed.
+func (l *LogData) Export(path string, format string) error {
+	messages := l.List()
+
+	var data []byte
+	var err error
+	switch format {
+	case ExportFormatText:
+		data, err = encodeLogfmt(messages)
+	case ExportFormatJSON:
+		data, err = encodeJSON(messages)
+	default:
+		return fmt.Errorf("unsupported log export format: %s", format)
+	}
+	if err != nil {
+		return fmt.Errorf("encoding logs: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating log export directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing log export: %w", err)
+	}
+	return nil
+}
+
+// Export writes the application log to path; see LogData.Export.
+func Export(path string, format string) error {
+	return defaultLogData.Export(path, format)
+}
+
+func encodeLogfmt(messages []LogMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := logfmt.NewEncoder(&buf)
+	for _, msg := range messages {
+		keyvals := []any{
+			"time", msg.Time.Format(time.RFC3339),
+			"level", msg.Level,
+			"msg", msg.Message,
+		}
+		for _, attr := range msg.Attributes {
+			keyvals = append(keyvals, attr.Key, attr.Value)
+		}
+		if msg.Persist {
+			keyvals = append(keyvals, persistKeyArg, true)
+		}
+		if msg.PersistTime > 0 {
+			keyvals = append(keyvals, PersistTimeArg, msg.PersistTime.String())
+		}
+		if msg.SessionID != "" {
+			keyvals = append(keyvals, SessionIDArg, msg.SessionID)
+		}
+		if err := enc.EncodeKeyvals(keyvals...); err != nil {
+			return nil, err
+		}
+		if err := enc.EndRecord(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeJSON(messages []LogMessage) ([]byte, error) {
+	exported := make([]exportedMessage, len(messages))
+	for i, msg := range messages {
+		exported[i] = exportedMessage{
+			ID:         msg.ID,
+			Time:       msg.Time.Format(time.RFC3339),
+			Level:      msg.Level,
+			Message:    msg.Message,
+			Attributes: msg.Attributes,
+			Persist:    msg.Persist,
+			SessionID:  msg.SessionID,
+		}
+		if exported[i].Attributes == nil {
+			exported[i].Attributes = []Attr{}
+		}
+		if msg.PersistTime > 0 {
+			exported[i].PersistTime = msg.PersistTime.String()
+		}
+	}
+	return json.MarshalIndent(exported, "", "  ")
+}
@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	logs := &LogData{Broker: pubsub.NewBroker[LogMessage]()}
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	logs.Add(LogMessage{ID: "1", Time: at, Level: "info", Message: "started", Attributes: []Attr{{Key: "source", Value: "main.go:10"}}})
+	logs.Add(LogMessage{ID: "2", Time: at, Level: "warn", Message: "disk nearly full", Persist: true, PersistTime: 5 * time.Second})
+
+	dir := t.TempDir()
+
+	t.Run("text", func(t *testing.T) {
+		path := filepath.Join(dir, "nested", "logs.txt")
+		require.NoError(t, logs.Export(path, ExportFormatText))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.Len(t, lines, 2)
+		assert.Equal(t, "time=2024-01-02T03:04:05Z level=info msg=started source=main.go:10", lines[0])
+		assert.Equal(t, `time=2024-01-02T03:04:05Z level=warn msg="disk nearly full" $_persist=true $_persist_time=5s`, lines[1])
+	})
+
+	t.Run("json", func(t *testing.T) {
+		path := filepath.Join(dir, "logs.json")
+		require.NoError(t, logs.Export(path, ExportFormatJSON))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var exported []exportedMessage
+		require.NoError(t, json.Unmarshal(data, &exported))
+		require.Len(t, exported, 2)
+		assert.Equal(t, "started", exported[0].Message)
+		assert.Equal(t, []Attr{{Key: "source", Value: "main.go:10"}}, exported[0].Attributes)
+		assert.True(t, exported[1].Persist)
+		assert.Equal(t, "5s", exported[1].PersistTime)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		assert.Error(t, logs.Export(filepath.Join(dir, "logs.xml"), "xml"))
+
+		blocker := filepath.Join(dir, "file")
+		require.NoError(t, os.WriteFile(blocker, nil, 0o644))
+		assert.Error(t, logs.Export(filepath.Join(blocker, "logs.txt"), ExportFormatText))
+	})
+}
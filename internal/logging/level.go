@@ -0,0 +1,25 @@
+package logging
+
+import "log/slog"
+
+// level is shared by every handler created through HandlerOptions so the
+// active log level can change at runtime without rebuilding the logger.
+var level = new(slog.LevelVar)
+
+// SetLevel changes the minimum level of messages that are logged. The swap is
+// atomic, so messages being logged concurrently are never dropped because of it.
+func SetLevel(l slog.Level) {
+	level.Set(l)
+}
+
+// GetLevel returns the minimum level of messages that are currently logged.
+func GetLevel() slog.Level {
+	return level.Level()
+}
+
+// HandlerOptions returns handler options bound to the dynamic log level.
+func HandlerOptions() *slog.HandlerOptions {
+	return &slog.HandlerOptions{
+		Level: level,
+	}
+}
@@ -0,0 +1,36 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLevel(t *testing.T) {
+	original := GetLevel()
+	t.Cleanup(func() { SetLevel(original) })
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, HandlerOptions()))
+
+	SetLevel(slog.LevelInfo)
+	logger.Debug("hidden debug")
+	logger.Info("visible info")
+	assert.NotContains(t, buf.String(), "hidden debug")
+	assert.Contains(t, buf.String(), "visible info")
+
+	buf.Reset()
+	SetLevel(slog.LevelWarn)
+	assert.Equal(t, slog.LevelWarn, GetLevel())
+	logger.Info("dropped info")
+	logger.Warn("kept warning")
+	assert.NotContains(t, buf.String(), "dropped info")
+	assert.Contains(t, buf.String(), "kept warning")
+
+	buf.Reset()
+	SetLevel(slog.LevelDebug)
+	logger.Debug("verbose debug")
+	assert.Contains(t, buf.String(), "verbose debug")
+}
@@ -1,15 +1,18 @@
 package logging
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
-	// "path/filepath"
-	"encoding/json"
+	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/opencode-ai/opencode/internal/version"
 )
 
 func getCaller() string {
@@ -87,6 +90,12 @@ func RecoverPanic(name string, cleanup func()) {
 			InfoPersist(fmt.Sprintf("Panic details written to %s", filename))
 		}
 
+		// Write a machine-readable companion for crash collection
+		reportFile := strings.TrimSuffix(filename, ".log") + ".json"
+		if err := writePanicReport(reportFile, name, r, callerFrames(3)); err != nil {
+			ErrorPersist(fmt.Sprintf("Failed to create panic report: %v", err))
+		}
+
 		// Execute cleanup function if provided
 		if cleanup != nil {
 			cleanup()
@@ -94,25 +103,108 @@ func RecoverPanic(name string, cleanup func()) {
 	}
 }
 
+// panicReport is the JSON form of a recovered panic.
+type panicReport struct {
+	Function  string          `json:"function"`
+	Value     json.RawMessage `json:"value"`
+	Stack     []stackFrame    `json:"stack"`
+	Timestamp string          `json:"timestamp"`
+	Version   string          `json:"version"`
+}
+
+type stackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+func writePanicReport(filename string, name string, r any, stack []stackFrame) error {
+	data, err := json.MarshalIndent(panicReport{
+		Function:  name,
+		Value:     panicValue(r),
+		Stack:     stack,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Version:   version.Version,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644)
+}
+
+// panicValue encodes the recovered value as JSON, falling back to its
+// formatted string when it can't be encoded faithfully.
+func panicValue(r any) json.RawMessage {
+	if err, ok := r.(error); ok {
+		r = err.Error()
+	}
+	if data, err := json.Marshal(r); err == nil {
+		return data
+	}
+	data, _ := json.Marshal(fmt.Sprintf("%v", r))
+	return data
+}
+
+// callerFrames returns the stack above the caller, skipping skip frames.
+func callerFrames(skip int) []stackFrame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	stack := []stackFrame{}
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, stackFrame{
+			Function: frame.Function,
+			File:     frame.File,
+			Line:     frame.Line,
+		})
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
 // Message Logging for Debug
 var MessageDir string
 
+// PerSessionDir controls how session log files are laid out in MessageDir.
+// When true, which is the default, each session's files go in a subdirectory
+// named after the session prefix. When false they are written directly to
+// MessageDir, with the session prefix at the start of each file name.
+var PerSessionDir = true
+
 func GetSessionPrefix(sessionId string) string {
 	return sessionId[:8]
 }
 
-var sessionLogMutex sync.Mutex
+var (
+	sessionLogMutex sync.Mutex
+	// activeSessionPrefix is the prefix of the session logged to most recently
+	activeSessionPrefix string
+)
+
+// sessionLogPath returns the directory a session's log file is written to and
+// the file's path, according to PerSessionDir.
+func sessionLogPath(sessionId string, filename string) (string, string) {
+	sessionPrefix := GetSessionPrefix(sessionId)
+	if PerSessionDir {
+		sessionPath := filepath.Join(MessageDir, sessionPrefix)
+		return sessionPath, filepath.Join(sessionPath, filename)
+	}
+	return MessageDir, filepath.Join(MessageDir, sessionPrefix+"_"+filename)
+}
 
 func AppendToSessionLogFile(sessionId string, filename string, content string) string {
 	if MessageDir == "" || sessionId == "" {
 		return ""
 	}
-	sessionPrefix := GetSessionPrefix(sessionId)
 
 	sessionLogMutex.Lock()
 	defer sessionLogMutex.Unlock()
 
-	sessionPath := fmt.Sprintf("%s/%s", MessageDir, sessionPrefix)
+	activeSessionPrefix = GetSessionPrefix(sessionId)
+	sessionPath, filePath := sessionLogPath(sessionId, filename)
 	if _, err := os.Stat(sessionPath); os.IsNotExist(err) {
 		if err := os.MkdirAll(sessionPath, 0o766); err != nil {
 			Error("Failed to create session directory", "dirpath", sessionPath, "error", err)
@@ -120,8 +212,6 @@ func AppendToSessionLogFile(sessionId string, filename string, content string) s
 		}
 	}
 
-	filePath := fmt.Sprintf("%s/%s", sessionPath, filename)
-
 	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		Error("Failed to open session log file", "filepath", filePath, "error", err)
@@ -138,6 +228,74 @@ func AppendToSessionLogFile(sessionId string, filename string, content string) s
 	return filePath
 }
 
+// PruneSessionLogs removes the session logs in MessageDir that haven't been
+// modified within olderThan and returns how many it removed. A session's
+// directory counts as modified when any file in it was. Logs of the session
+// being logged to are always kept.
+func PruneSessionLogs(olderThan time.Duration) (int, error) {
+	if MessageDir == "" {
+		return 0, nil
+	}
+
+	sessionLogMutex.Lock()
+	defer sessionLogMutex.Unlock()
+
+	entries, err := os.ReadDir(MessageDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read message directory: %w", err)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	removed := 0
+	for _, entry := range entries {
+		// session directories are named after the prefix, and files written
+		// without PerSessionDir start with it
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+		if activeSessionPrefix != "" && prefix == activeSessionPrefix {
+			continue
+		}
+		path := filepath.Join(MessageDir, entry.Name())
+		modified, err := lastModified(path)
+		if err != nil {
+			return removed, err
+		}
+		if !modified.Before(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return removed, fmt.Errorf("failed to remove session log: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// lastModified returns the latest modification time of path and, for a
+// directory, anything in it.
+func lastModified(path string) (time.Time, error) {
+	var latest time.Time
+	err := filepath.WalkDir(path, func(_ string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read session log: %w", err)
+	}
+	return latest, nil
+}
+
 func WriteRequestMessageJson(sessionId string, requestSeqId int, message any) string {
 	if MessageDir == "" || sessionId == "" || requestSeqId <= 0 {
 		return ""
@@ -0,0 +1,156 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverPanicReport(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	cleaned := false
+	func() {
+		defer RecoverPanic("worker", func() { cleaned = true })
+		panic(map[string]any{"fn": func() {}})
+	}()
+	assert.True(t, cleaned)
+
+	logs, err := filepath.Glob(filepath.Join(dir, "opencode-panic-worker-*.log"))
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+
+	data, err := os.ReadFile(strings.TrimSuffix(logs[0], ".log") + ".json")
+	require.NoError(t, err)
+
+	var report struct {
+		Function  string `json:"function"`
+		Value     string `json:"value"`
+		Timestamp string `json:"timestamp"`
+		Version   string `json:"version"`
+		Stack     []struct {
+			Function string `json:"function"`
+			Line     int    `json:"line"`
+		} `json:"stack"`
+	}
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, "worker", report.Function)
+	assert.Contains(t, report.Value, "map[fn:")
+	assert.NotEmpty(t, report.Timestamp)
+	assert.NotEmpty(t, report.Version)
+	found := false
+	for _, frame := range report.Stack {
+		if strings.Contains(frame.Function, "TestRecoverPanicReport") {
+			found = true
+		}
+	}
+	assert.True(t, found, "stack should include the panicking function")
+}
+
+func TestPanicValue(t *testing.T) {
+	t.Parallel()
+
+	assert.JSONEq(t, `{"code": 3}`, string(panicValue(map[string]int{"code": 3})))
+	assert.JSONEq(t, `"boom"`, string(panicValue("boom")))
+	assert.JSONEq(t, `"file already closed"`, string(panicValue(os.ErrClosed)))
+	assert.True(t, json.Valid(panicValue(make(chan int))))
+}
+
+func TestSessionLogPath(t *testing.T) {
+	oldDir, oldPerSession := MessageDir, PerSessionDir
+	t.Cleanup(func() { MessageDir, PerSessionDir = oldDir, oldPerSession })
+
+	MessageDir = filepath.Join("data", "messages")
+	sessionID := "0123456789abcdef"
+
+	PerSessionDir = true
+	dir, path := sessionLogPath(sessionID, "1_request.json")
+	assert.Equal(t, filepath.Join("data", "messages", "01234567"), dir)
+	assert.Equal(t, filepath.Join("data", "messages", "01234567", "1_request.json"), path)
+
+	PerSessionDir = false
+	dir, path = sessionLogPath(sessionID, "1_request.json")
+	assert.Equal(t, filepath.Join("data", "messages"), dir)
+	assert.Equal(t, filepath.Join("data", "messages", "01234567_1_request.json"), path)
+}
+
+func TestAppendToSessionLogFile(t *testing.T) {
+	oldDir, oldPerSession := MessageDir, PerSessionDir
+	t.Cleanup(func() { MessageDir, PerSessionDir = oldDir, oldPerSession })
+
+	MessageDir = t.TempDir()
+	sessionID := "0123456789abcdef"
+
+	for _, perSession := range []bool{true, false} {
+		PerSessionDir = perSession
+		path := WriteChatResponseJson(sessionID, 1, map[string]string{"text": "hi"})
+		_, want := sessionLogPath(sessionID, "1_response.json")
+		require.Equal(t, want, path)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"text": "hi"}`, string(data))
+	}
+}
+
+func TestPruneSessionLogs(t *testing.T) {
+	oldDir, oldPerSession, oldActive := MessageDir, PerSessionDir, activeSessionPrefix
+	t.Cleanup(func() { MessageDir, PerSessionDir, activeSessionPrefix = oldDir, oldPerSession, oldActive })
+
+	MessageDir = t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	age := func(path string) {
+		require.NoError(t, os.Chtimes(path, old, old))
+	}
+
+	PerSessionDir = true
+	oldPath := WriteChatResponseJson("aaaaaaaa-old", 1, "old")
+	age(oldPath)
+	age(filepath.Dir(oldPath))
+	// a directory with a recently modified file is kept
+	recentPath := WriteChatResponseJson("bbbbbbbb-recent", 1, "recent")
+	age(filepath.Dir(recentPath))
+
+	PerSessionDir = false
+	flatOldPath := WriteChatResponseJson("cccccccc-old", 1, "old")
+	age(flatOldPath)
+	flatNewPath := WriteChatResponseJson("dddddddd-new", 1, "new")
+
+	// the session logged to last is kept however old its logs are
+	PerSessionDir = true
+	activePath := WriteChatResponseJson("eeeeeeee-active", 1, "active")
+	age(activePath)
+	age(filepath.Dir(activePath))
+
+	removed, err := PruneSessionLogs(24 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	assert.NoDirExists(t, filepath.Dir(oldPath))
+	assert.NoFileExists(t, flatOldPath)
+	assert.FileExists(t, recentPath)
+	assert.FileExists(t, flatNewPath)
+	assert.FileExists(t, activePath)
+}
+
+func TestPruneSessionLogsWithoutMessageDir(t *testing.T) {
+	oldDir := MessageDir
+	t.Cleanup(func() { MessageDir = oldDir })
+
+	MessageDir = ""
+	removed, err := PruneSessionLogs(time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+
+	MessageDir = filepath.Join(t.TempDir(), "missing")
+	removed, err = PruneSessionLogs(time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+}
@@ -1,6 +1,8 @@
 package logging
 
 import (
+	"math"
+	"strconv"
 	"time"
 )
 
@@ -11,11 +13,34 @@ type LogMessage struct {
 	Level       string
 	Persist     bool          // used when we want to show the mesage in the status bar
 	PersistTime time.Duration // used when we want to show the mesage in the status bar
+	SessionID   string        // set when the line was logged with SessionIDArg
+	Count       int           // how many identical messages were coalesced into this one
 	Message     string        `json:"msg"`
 	Attributes  []Attr
 }
 
 type Attr struct {
-	Key   string
-	Value string
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Typed is Value as an int64, float64 or bool when it is written as one,
+	// and Value itself otherwise, so attributes can be compared numerically.
+	Typed any `json:"-"`
+}
+
+// parseAttrValue returns the typed form of a logged attribute value.
+func parseAttrValue(value string) any {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n
+	}
+	// ParseFloat also accepts names like "inf" and "nan", which are left as text
+	if f, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f
+	}
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return value
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -15,30 +16,81 @@ import (
 const (
 	persistKeyArg  = "$_persist"
 	PersistTimeArg = "$_persist_time"
+	// SessionIDArg tags a log line with the session it belongs to
+	SessionIDArg = "$_session"
 )
 
+// DefaultDedupWindow is how close together identical messages must arrive to
+// be coalesced in the application log.
+const DefaultDedupWindow = time.Second
+
 type LogData struct {
 	messages []LogMessage
 	*pubsub.Broker[LogMessage]
 	lock sync.Mutex
+	// dedupWindow coalesces a message into the previous one when both have
+	// the same level and text and arrive within the window. Zero disables it.
+	dedupWindow time.Duration
 }
 
 func (l *LogData) Add(msg LogMessage) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
+	if msg.Count == 0 {
+		msg.Count = 1
+	}
+	if n := len(l.messages); n > 0 && l.isRepeat(l.messages[n-1], msg) {
+		last := &l.messages[n-1]
+		last.Count += msg.Count
+		last.Time = msg.Time
+		l.Publish(pubsub.UpdatedEvent, *last)
+		return
+	}
 	l.messages = append(l.messages, msg)
 	l.Publish(pubsub.CreatedEvent, msg)
 }
 
+func (l *LogData) isRepeat(last, msg LogMessage) bool {
+	if l.dedupWindow <= 0 {
+		return false
+	}
+	return last.Level == msg.Level &&
+		last.Message == msg.Message &&
+		last.SessionID == msg.SessionID &&
+		msg.Time.Sub(last.Time) <= l.dedupWindow
+}
+
+// SetDedupWindow changes how close together identical messages must be to be
+// coalesced; zero or less turns coalescing off.
+func (l *LogData) SetDedupWindow(window time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.dedupWindow = window
+}
+
 func (l *LogData) List() []LogMessage {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	return l.messages
+	return slices.Clone(l.messages)
+}
+
+// ListBySession returns the messages logged with the given session ID.
+func (l *LogData) ListBySession(sessionID string) []LogMessage {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	var messages []LogMessage
+	for _, msg := range l.messages {
+		if msg.SessionID == sessionID {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
 }
 
 var defaultLogData = &LogData{
-	messages: make([]LogMessage, 0),
-	Broker:   pubsub.NewBroker[LogMessage](),
+	messages:    make([]LogMessage, 0),
+	Broker:      pubsub.NewBroker[LogMessage](),
+	dedupWindow: DefaultDedupWindow,
 }
 
 type writer struct{}
@@ -72,10 +124,14 @@ func (w *writer) Write(p []byte) (int, error) {
 						continue
 					}
 					msg.PersistTime = parsed
+				} else if string(d.Key()) == SessionIDArg {
+					msg.SessionID = string(d.Value())
 				} else {
+					value := string(d.Value())
 					msg.Attributes = append(msg.Attributes, Attr{
 						Key:   string(d.Key()),
-						Value: string(d.Value()),
+						Value: value,
+						Typed: parseAttrValue(value),
 					})
 				}
 			}
@@ -93,10 +149,18 @@ func NewWriter() *writer {
 	return w
 }
 
-func Subscribe(ctx context.Context) <-chan pubsub.Event[LogMessage] {
-	return defaultLogData.Subscribe(ctx)
+func Subscribe(ctx context.Context, opts ...pubsub.SubscribeOption) <-chan pubsub.Event[LogMessage] {
+	return defaultLogData.Subscribe(ctx, opts...)
 }
 
 func List() []LogMessage {
 	return defaultLogData.List()
 }
+
+func ListBySession(sessionID string) []LogMessage {
+	return defaultLogData.ListBySession(sessionID)
+}
+
+func SetDedupWindow(window time.Duration) {
+	defaultLogData.SetDedupWindow(window)
+}
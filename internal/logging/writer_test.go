@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterSessionID(t *testing.T) {
+	original := defaultLogData
+	t.Cleanup(func() { defaultLogData = original })
+	defaultLogData = &LogData{Broker: pubsub.NewBroker[LogMessage]()}
+
+	w := NewWriter()
+	_, err := w.Write([]byte(
+		"time=2024-01-02T03:04:05Z level=INFO msg=one $_session=abc tool=bash\n" +
+			"time=2024-01-02T03:04:06Z level=INFO msg=two $_session=def\n" +
+			"time=2024-01-02T03:04:07Z level=INFO msg=three\n" +
+			"time=2024-01-02T03:04:08Z level=WARN msg=four $_session=abc $_persist=true\n",
+	))
+	require.NoError(t, err)
+
+	msgs := ListBySession("abc")
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "one", msgs[0].Message)
+	assert.Equal(t, "abc", msgs[0].SessionID)
+	assert.Equal(t, []Attr{{Key: "tool", Value: "bash", Typed: "bash"}}, msgs[0].Attributes)
+	assert.Equal(t, "four", msgs[1].Message)
+	assert.True(t, msgs[1].Persist)
+	assert.Empty(t, msgs[1].Attributes)
+
+	assert.Len(t, ListBySession("def"), 1)
+	assert.Empty(t, ListBySession("missing"))
+	assert.Len(t, List(), 4)
+}
+
+func TestWriterTypedAttributes(t *testing.T) {
+	original := defaultLogData
+	t.Cleanup(func() { defaultLogData = original })
+	defaultLogData = &LogData{Broker: pubsub.NewBroker[LogMessage]()}
+
+	w := NewWriter()
+	_, err := w.Write([]byte(
+		`time=2024-01-02T03:04:05Z level=INFO msg=done duration_ms=150 offset=-3 ratio=0.25 big=1e3 ok=true retried=false ` +
+			`tool=bash elapsed=1.5s version=1.2.3 limit=inf flag=t path="a b"` + "\n",
+	))
+	require.NoError(t, err)
+
+	msgs := List()
+	require.Len(t, msgs, 1)
+	typed := make(map[string]any)
+	for _, attr := range msgs[0].Attributes {
+		typed[attr.Key] = attr.Typed
+	}
+	assert.Equal(t, map[string]any{
+		"duration_ms": int64(150),
+		"offset":      int64(-3),
+		"ratio":       0.25,
+		"big":         1000.0,
+		"ok":          true,
+		"retried":     false,
+		"tool":        "bash",
+		"elapsed":     "1.5s",
+		"version":     "1.2.3",
+		"limit":       "inf",
+		"flag":        "t",
+		"path":        "a b",
+	}, typed)
+	assert.Equal(t, "150", msgs[0].Attributes[0].Value, "the string form is kept")
+	assert.Equal(t, "0.25", msgs[0].Attributes[2].Value)
+}
+
+func TestLogDataDedup(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	msg := func(level, text string, offset time.Duration) LogMessage {
+		return LogMessage{ID: text + offset.String(), Level: level, Message: text, Time: base.Add(offset)}
+	}
+
+	data := &LogData{Broker: pubsub.NewBroker[LogMessage]()}
+	data.SetDedupWindow(time.Second)
+	data.Add(msg("error", "disk full", 0))
+	data.Add(msg("error", "disk full", 500*time.Millisecond))
+	data.Add(msg("error", "disk full", 1400*time.Millisecond))
+	data.Add(msg("warn", "disk full", 1500*time.Millisecond))
+	data.Add(msg("warn", "disk nearly full", 1600*time.Millisecond))
+	data.Add(msg("warn", "disk nearly full", 5*time.Second))
+
+	logs := data.List()
+	require.Len(t, logs, 4)
+	assert.Equal(t, 3, logs[0].Count)
+	assert.Equal(t, base.Add(1400*time.Millisecond), logs[0].Time)
+	assert.Equal(t, 1, logs[1].Count)
+	assert.Equal(t, "warn", logs[1].Level)
+	assert.Equal(t, 1, logs[2].Count)
+	assert.Equal(t, 1, logs[3].Count)
+
+	// List hands out a copy, so callers sorting it can't disturb coalescing
+	logs[0].Message = "changed"
+	assert.Equal(t, "disk full", data.List()[0].Message)
+
+	data.SetDedupWindow(0)
+	data.Add(msg("warn", "disk nearly full", 5*time.Second))
+	assert.Len(t, data.List(), 5)
+}
@@ -1,11 +1,19 @@
 package message
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"slices"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/rivo/uniseg"
 )
 
 type MessageRole string
@@ -73,12 +81,29 @@ type BinaryContent struct {
 
 func (bc BinaryContent) String(provider models.ModelProvider) string {
 	base64Encoded := base64.StdEncoding.EncodeToString(bc.Data)
-	if provider == models.ProviderOpenAI {
+	switch provider {
+	case models.ProviderOpenAI:
 		return "data:" + bc.MIMEType + ";base64," + base64Encoded
+	case models.ProviderGemini:
+		// Gemini takes inline data as a blob carrying its full MIME type
+		blob, _ := json.Marshal(geminiInlineData{InlineData: geminiBlob{
+			MIMEType: bc.MIMEType,
+			Data:     base64Encoded,
+		}})
+		return string(blob)
 	}
 	return base64Encoded
 }
 
+type geminiInlineData struct {
+	InlineData geminiBlob `json:"inlineData"`
+}
+
+type geminiBlob struct {
+	MIMEType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
 func (BinaryContent) isPart() {}
 
 type ToolCall struct {
@@ -97,23 +122,63 @@ type ToolResult struct {
 	Content    string `json:"content"`
 	Metadata   string `json:"metadata"`
 	IsError    bool   `json:"is_error"`
+	// Canceled is set when the tool was stopped by the user rather than
+	// failing. Canceled results are also errors.
+	Canceled bool `json:"canceled,omitempty"`
 }
 
+// ToolCanceledContent is the content of a result for a canceled tool call.
+const ToolCanceledContent = "Tool execution canceled by user"
+
 func (ToolResult) isPart() {}
 
+// Truncate returns a copy of the result with Content cut to at most maxBytes,
+// on a UTF-8 boundary, followed by a marker saying how much was dropped. Zero
+// or less means no limit.
+func (tr ToolResult) Truncate(maxBytes int) ToolResult {
+	if maxBytes <= 0 || len(tr.Content) <= maxBytes {
+		return tr
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(tr.Content[cut]) {
+		cut--
+	}
+	tr.Content = fmt.Sprintf("%s…[truncated %d bytes]", tr.Content[:cut], len(tr.Content)-cut)
+	return tr
+}
+
 type Finish struct {
 	Reason FinishReason `json:"reason"`
 	Time   int64        `json:"time"`
+
+	// Token usage of the response that produced the message, when the
+	// provider reported it. InputTokens includes tokens written to the prompt
+	// cache; CachedTokens are those read from it.
+	InputTokens  int64 `json:"input_tokens,omitempty"`
+	OutputTokens int64 `json:"output_tokens,omitempty"`
+	CachedTokens int64 `json:"cached_tokens,omitempty"`
 }
 
 func (Finish) isPart() {}
 
+// UnknownContent holds a stored part of a type this version doesn't know, so
+// the rest of its message can still be loaded. Raw is the part as stored,
+// which is written back unchanged when the message is saved again.
+type UnknownContent struct {
+	Type string
+	Raw  json.RawMessage
+}
+
+func (UnknownContent) isPart() {}
+
 type Message struct {
 	ID        string
 	Role      MessageRole
 	SessionID string
 	Parts     []ContentPart
 	Model     models.ModelID
+	// Pinned messages are kept when the conversation is trimmed or compacted.
+	Pinned    bool
 	CreatedAt int64
 	UpdatedAt int64
 }
@@ -210,6 +275,72 @@ func (m *Message) IsThinking() bool {
 	return false
 }
 
+// ReasoningFinished reports whether the model has moved on from reasoning:
+// the message has reasoning followed by text or a tool call. It is false for
+// messages without reasoning.
+func (m *Message) ReasoningFinished() bool {
+	reasoned := false
+	for _, part := range m.Parts {
+		switch c := part.(type) {
+		case ReasoningContent:
+			reasoned = reasoned || c.Thinking != ""
+		case TextContent:
+			if reasoned && c.Text != "" {
+				return true
+			}
+		case ToolCall:
+			if reasoned {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WordCount returns the number of words across the text and reasoning parts,
+// using Unicode word boundaries so text without spaces is counted correctly.
+func (m *Message) WordCount() int {
+	count := 0
+	for _, text := range m.proseParts() {
+		state := -1
+		var word string
+		for len(text) > 0 {
+			word, text, state = uniseg.FirstWordInString(text, state)
+			if strings.IndexFunc(word, func(r rune) bool {
+				return unicode.IsLetter(r) || unicode.IsNumber(r)
+			}) >= 0 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// CharCount returns the number of user-perceived characters across the text
+// and reasoning parts.
+func (m *Message) CharCount() int {
+	count := 0
+	for _, text := range m.proseParts() {
+		count += uniseg.GraphemeClusterCount(text)
+	}
+	return count
+}
+
+// proseParts returns the text of every text and reasoning part, leaving out
+// tool calls and results.
+func (m *Message) proseParts() []string {
+	texts := make([]string, 0, len(m.Parts))
+	for _, part := range m.Parts {
+		switch c := part.(type) {
+		case TextContent:
+			texts = append(texts, c.Text)
+		case ReasoningContent:
+			texts = append(texts, c.Thinking)
+		}
+	}
+	return texts
+}
+
 func (m *Message) AppendContent(delta string) {
 	found := false
 	for i, part := range m.Parts {
@@ -307,7 +438,42 @@ func (m *Message) SetToolResults(tr []ToolResult) {
 	}
 }
 
+// MarkToolCanceled marks the result for toolCallID as canceled, adding one if
+// the tool hadn't produced a result yet.
+func (m *Message) MarkToolCanceled(toolCallID string) {
+	for i, part := range m.Parts {
+		if tr, ok := part.(ToolResult); ok && tr.ToolCallID == toolCallID {
+			tr.IsError = true
+			tr.Canceled = true
+			m.Parts[i] = tr
+			return
+		}
+	}
+	m.Parts = append(m.Parts, ToolResult{
+		ToolCallID: toolCallID,
+		Content:    ToolCanceledContent,
+		IsError:    true,
+		Canceled:   true,
+	})
+}
+
+// TruncateToolResults applies ToolResult.Truncate to every tool result in
+// the message.
+func (m *Message) TruncateToolResults(maxBytes int) {
+	for i, part := range m.Parts {
+		if tr, ok := part.(ToolResult); ok {
+			m.Parts[i] = tr.Truncate(maxBytes)
+		}
+	}
+}
+
 func (m *Message) AddFinish(reason FinishReason) {
+	m.AddFinishWithUsage(reason, 0, 0, 0)
+}
+
+// AddFinishWithUsage ends the message like AddFinish, recording the token
+// usage of the response on the Finish part.
+func (m *Message) AddFinishWithUsage(reason FinishReason, inputTokens, outputTokens, cachedTokens int64) {
 	// remove any existing finish part
 	for i, part := range m.Parts {
 		if _, ok := part.(Finish); ok {
@@ -315,7 +481,13 @@ func (m *Message) AddFinish(reason FinishReason) {
 			break
 		}
 	}
-	m.Parts = append(m.Parts, Finish{Reason: reason, Time: time.Now().Unix()})
+	m.Parts = append(m.Parts, Finish{
+		Reason:       reason,
+		Time:         time.Now().Unix(),
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+		CachedTokens: cachedTokens,
+	})
 }
 
 func (m *Message) AddImageURL(url, detail string) {
@@ -325,3 +497,134 @@ func (m *Message) AddImageURL(url, detail string) {
 func (m *Message) AddBinary(mimeType string, data []byte) {
 	m.Parts = append(m.Parts, BinaryContent{MIMEType: mimeType, Data: data})
 }
+
+// ToMarkdown renders the message as a Markdown section headed by its role.
+// Binary attachments are referenced by path rather than inlined.
+func (m *Message) ToMarkdown() string {
+	var sb strings.Builder
+	role := string(m.Role)
+	if role != "" {
+		role = strings.ToUpper(role[:1]) + role[1:]
+	}
+	fmt.Fprintf(&sb, "### %s\n", role)
+
+	for _, part := range m.Parts {
+		switch c := part.(type) {
+		case ReasoningContent:
+			if c.Thinking == "" {
+				continue
+			}
+			sb.WriteString("\n> " + strings.ReplaceAll(strings.TrimSpace(c.Thinking), "\n", "\n> ") + "\n")
+		case TextContent:
+			if c.Text == "" {
+				continue
+			}
+			sb.WriteString("\n" + strings.TrimSpace(c.Text) + "\n")
+		case ImageURLContent:
+			fmt.Fprintf(&sb, "\n![image](%s)\n", c.URL)
+		case BinaryContent:
+			name := c.Path
+			if name == "" {
+				name = "attachment"
+			}
+			fmt.Fprintf(&sb, "\n[%s] (%s, %d bytes)\n", name, c.MIMEType, len(c.Data))
+		case ToolCall:
+			fmt.Fprintf(&sb, "\n**Tool call:** `%s`\n\n```json\n%s\n```\n", c.Name, c.Input)
+		case ToolResult:
+			label := "Tool result"
+			if c.IsError {
+				label = "Tool error"
+			}
+			fmt.Fprintf(&sb, "\n**%s:** `%s`\n\n```\n%s\n```\n", label, c.Name, strings.TrimRight(c.Content, "\n"))
+		}
+	}
+	return sb.String()
+}
+
+// MergeMessages combines two messages of the same role into one. Text and
+// reasoning are joined, every other part from b is appended after a's, and
+// the result keeps a single Finish part, preferring b's.
+func MergeMessages(a, b Message) (Message, error) {
+	if a.Role != b.Role {
+		return Message{}, fmt.Errorf("cannot merge %s message with %s message", a.Role, b.Role)
+	}
+	if a.SessionID != b.SessionID {
+		return Message{}, fmt.Errorf("cannot merge messages from different sessions")
+	}
+
+	merged := a
+	merged.Parts = make([]ContentPart, 0, len(a.Parts)+len(b.Parts))
+	finish := a.FinishPart()
+	for _, part := range a.Parts {
+		if _, ok := part.(Finish); ok {
+			continue
+		}
+		merged.Parts = append(merged.Parts, part)
+	}
+
+	for _, part := range b.Parts {
+		switch c := part.(type) {
+		case TextContent:
+			merged.joinText(c.Text)
+		case ReasoningContent:
+			merged.joinReasoning(c.Thinking)
+		case Finish:
+			finish = &c
+		default:
+			merged.Parts = append(merged.Parts, part)
+		}
+	}
+	if finish != nil {
+		merged.Parts = append(merged.Parts, *finish)
+	}
+
+	if merged.Model == "" {
+		merged.Model = b.Model
+	}
+	if b.UpdatedAt > merged.UpdatedAt {
+		merged.UpdatedAt = b.UpdatedAt
+	}
+	return merged, nil
+}
+
+func (m *Message) joinText(text string) {
+	if text == "" {
+		return
+	}
+	if existing := m.Content().Text; existing != "" {
+		text = "\n" + text
+	}
+	m.AppendContent(text)
+}
+
+func (m *Message) joinReasoning(thinking string) {
+	if thinking == "" {
+		return
+	}
+	if existing := m.ReasoningContent().Thinking; existing != "" {
+		thinking = "\n" + thinking
+	}
+	m.AppendReasoningContent(thinking)
+}
+
+// Fingerprint returns a stable hash of text that ignores case and differences
+// in whitespace, so near-identical prompts produce the same value.
+func Fingerprint(text string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// ContentHash returns a hex SHA-256 digest of the message's parts as they are
+// stored, so unchanged content can be detected without comparing it. Messages
+// with equal parts in the same order hash equally, and any change to the parts
+// changes the hash; the role and other fields aren't included. It is empty if
+// the parts can't be marshalled.
+func (m *Message) ContentHash() string {
+	data, err := MarshalParts(m.Parts)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,296 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeMessages(t *testing.T) {
+	t.Parallel()
+
+	t.Run("merges two assistant messages", func(t *testing.T) {
+		a := Message{
+			ID:        "a",
+			Role:      Assistant,
+			SessionID: "session",
+			Parts: []ContentPart{
+				ReasoningContent{Thinking: "first thought"},
+				TextContent{Text: "first"},
+				ToolCall{ID: "call-1", Name: "view", Input: `{}`, Finished: true},
+				Finish{Reason: FinishReasonToolUse, Time: 1},
+			},
+			UpdatedAt: 10,
+		}
+		b := Message{
+			ID:        "b",
+			Role:      Assistant,
+			SessionID: "session",
+			Parts: []ContentPart{
+				TextContent{Text: "second"},
+				ToolResult{ToolCallID: "call-1", Content: "ok"},
+				Finish{Reason: FinishReasonEndTurn, Time: 2},
+			},
+			UpdatedAt: 20,
+		}
+
+		merged, err := MergeMessages(a, b)
+		require.NoError(t, err)
+
+		assert.Equal(t, "a", merged.ID)
+		assert.Equal(t, int64(20), merged.UpdatedAt)
+		assert.Equal(t, "first\nsecond", merged.Content().Text)
+		assert.Equal(t, "first thought", merged.ReasoningContent().Thinking)
+		assert.Len(t, merged.ToolCalls(), 1)
+		assert.Len(t, merged.ToolResults(), 1)
+
+		finishes := 0
+		for _, part := range merged.Parts {
+			if _, ok := part.(Finish); ok {
+				finishes++
+			}
+		}
+		assert.Equal(t, 1, finishes)
+		assert.Equal(t, FinishReasonEndTurn, merged.FinishReason())
+		_, last := merged.Parts[len(merged.Parts)-1].(Finish)
+		assert.True(t, last)
+
+		// the inputs must be left untouched
+		assert.Equal(t, "first", a.Content().Text)
+		assert.Equal(t, FinishReasonToolUse, a.FinishReason())
+	})
+
+	t.Run("rejects messages with different roles", func(t *testing.T) {
+		a := Message{Role: User, SessionID: "session", Parts: []ContentPart{TextContent{Text: "hi"}}}
+		b := Message{Role: Assistant, SessionID: "session", Parts: []ContentPart{TextContent{Text: "hello"}}}
+
+		_, err := MergeMessages(a, b)
+		assert.Error(t, err)
+	})
+}
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	a := Fingerprint("Refactor the   config loader\nto use viper")
+	b := Fingerprint("refactor the config loader to use viper ")
+	c := Fingerprint("refactor the config loader to use cobra")
+
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+	assert.Len(t, a, 64)
+}
+
+func TestWordAndCharCount(t *testing.T) {
+	t.Parallel()
+
+	t.Run("counts text and reasoning but not tool parts", func(t *testing.T) {
+		msg := Message{Parts: []ContentPart{
+			ReasoningContent{Thinking: "Let me think."},
+			TextContent{Text: "Hello, world! It's 2024."},
+			ToolCall{ID: "1", Name: "bash", Input: `{"command": "ls -la"}`},
+			ToolResult{ToolCallID: "1", Content: "file1 file2 file3"},
+		}}
+
+		assert.Equal(t, 7, msg.WordCount())
+		assert.Equal(t, 13+24, msg.CharCount())
+	})
+
+	t.Run("handles multibyte text", func(t *testing.T) {
+		msg := Message{Parts: []ContentPart{
+			TextContent{Text: "naïve café 👍🏽"},
+		}}
+
+		assert.Equal(t, 2, msg.WordCount())
+		assert.Equal(t, 12, msg.CharCount())
+	})
+
+	t.Run("counts words without spaces", func(t *testing.T) {
+		msg := Message{Parts: []ContentPart{
+			TextContent{Text: "日本語"},
+		}}
+
+		assert.Equal(t, 3, msg.WordCount())
+		assert.Equal(t, 3, msg.CharCount())
+	})
+
+	t.Run("empty message", func(t *testing.T) {
+		msg := Message{}
+		assert.Equal(t, 0, msg.WordCount())
+		assert.Equal(t, 0, msg.CharCount())
+	})
+}
+
+func TestToMarkdown(t *testing.T) {
+	t.Parallel()
+
+	msg := Message{
+		Role: Assistant,
+		Parts: []ContentPart{
+			ReasoningContent{Thinking: "check the file\nthen answer"},
+			TextContent{Text: "Here it is."},
+			BinaryContent{Path: "shot.png", MIMEType: "image/png", Data: make([]byte, 2048)},
+			ToolCall{ID: "1", Name: "view", Input: `{"file_path": "main.go"}`},
+			ToolResult{ToolCallID: "1", Name: "view", Content: "package main\n"},
+			Finish{Reason: FinishReasonEndTurn},
+		},
+	}
+
+	want := "### Assistant\n" +
+		"\n> check the file\n> then answer\n" +
+		"\nHere it is.\n" +
+		"\n[shot.png] (image/png, 2048 bytes)\n" +
+		"\n**Tool call:** `view`\n\n```json\n{\"file_path\": \"main.go\"}\n```\n" +
+		"\n**Tool result:** `view`\n\n```\npackage main\n```\n"
+	assert.Equal(t, want, msg.ToMarkdown())
+}
+
+func TestBinaryContentString(t *testing.T) {
+	t.Parallel()
+
+	bc := BinaryContent{Path: "cat.png", MIMEType: "image/png", Data: []byte("png bytes")}
+	encoded := "cG5nIGJ5dGVz"
+
+	openai := bc.String(models.ProviderOpenAI)
+	anthropic := bc.String(models.ProviderAnthropic)
+	gemini := bc.String(models.ProviderGemini)
+
+	assert.Equal(t, "data:image/png;base64,"+encoded, openai)
+	assert.Equal(t, encoded, anthropic)
+	assert.JSONEq(t, `{"inlineData": {"mimeType": "image/png", "data": "`+encoded+`"}}`, gemini)
+	assert.NotEqual(t, openai, gemini)
+	assert.NotEqual(t, anthropic, gemini)
+}
+
+func TestToolResultTruncate(t *testing.T) {
+	t.Parallel()
+
+	result := ToolResult{ToolCallID: "call-1", Name: "view", Content: "héllo wörld", Metadata: "{}", IsError: true}
+
+	tests := []struct {
+		name     string
+		maxBytes int
+		want     string
+	}{
+		{"no limit", 0, "héllo wörld"},
+		{"within the limit", 13, "héllo wörld"},
+		{"ascii boundary", 1, "h…[truncated 12 bytes]"},
+		{"inside a multibyte rune", 2, "h…[truncated 12 bytes]"},
+		{"after a multibyte rune", 3, "hé…[truncated 10 bytes]"},
+		{"inside a later multibyte rune", 9, "héllo w…[truncated 5 bytes]"},
+		{"just before the end", 12, "héllo wörl…[truncated 1 bytes]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := result.Truncate(tt.maxBytes)
+			assert.Equal(t, tt.want, got.Content)
+			assert.Equal(t, result.ToolCallID, got.ToolCallID)
+			assert.Equal(t, result.Name, got.Name)
+			assert.True(t, got.IsError)
+		})
+	}
+	assert.Equal(t, "héllo wörld", result.Content, "the receiver is not modified")
+
+	msg := Message{Role: Tool, Parts: []ContentPart{
+		ToolResult{ToolCallID: "a", Content: "日本語のテキスト"},
+		TextContent{Text: "untouched text"},
+		ToolResult{ToolCallID: "b", Content: "ok"},
+	}}
+	msg.TruncateToolResults(4)
+	results := msg.ToolResults()
+	require.Len(t, results, 2)
+	assert.Equal(t, "日…[truncated 21 bytes]", results[0].Content)
+	assert.Equal(t, "ok", results[1].Content)
+	assert.Equal(t, "untouched text", msg.Content().Text)
+}
+
+func TestMarkToolCanceled(t *testing.T) {
+	t.Parallel()
+
+	msg := Message{Role: Tool, Parts: []ContentPart{
+		ToolResult{ToolCallID: "done", Content: "ok"},
+		ToolResult{ToolCallID: "running", Content: "partial"},
+	}}
+	msg.MarkToolCanceled("running")
+	msg.MarkToolCanceled("pending")
+
+	results := msg.ToolResults()
+	require.Len(t, results, 3)
+	assert.False(t, results[0].Canceled)
+	assert.Equal(t, ToolResult{ToolCallID: "running", Content: "partial", IsError: true, Canceled: true}, results[1])
+	assert.Equal(t, ToolResult{ToolCallID: "pending", Content: ToolCanceledContent, IsError: true, Canceled: true}, results[2])
+
+	data, err := MarshalParts(msg.Parts)
+	require.NoError(t, err)
+	parts, err := UnmarshalParts(data)
+	require.NoError(t, err)
+	assert.Equal(t, msg.Parts, parts)
+
+	t.Run("older results without the field", func(t *testing.T) {
+		parts, err := UnmarshalParts([]byte(`[{"type":"tool_result","data":{"tool_call_id":"a","name":"bash","content":"boom","metadata":"","is_error":true}}]`))
+		require.NoError(t, err)
+		require.Len(t, parts, 1)
+		assert.False(t, parts[0].(ToolResult).Canceled)
+	})
+}
+
+func TestContentHash(t *testing.T) {
+	t.Parallel()
+
+	newMessage := func(parts ...ContentPart) *Message {
+		return &Message{ID: "id", Role: Assistant, Parts: parts}
+	}
+	text := TextContent{Text: "hello"}
+	call := ToolCall{ID: "call", Name: "bash", Input: `{"command":"ls"}`, Type: "function", Finished: true}
+
+	hash := newMessage(text, call).ContentHash()
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, newMessage(text, call).ContentHash())
+	assert.Equal(t, hash, (&Message{ID: "other", Role: User, Parts: []ContentPart{text, call}}).ContentHash(), "only parts are hashed")
+
+	assert.NotEqual(t, hash, newMessage(call, text).ContentHash(), "order matters")
+	assert.NotEqual(t, hash, newMessage(TextContent{Text: "hello!"}, call).ContentHash())
+	call.Finished = false
+	assert.NotEqual(t, hash, newMessage(text, call).ContentHash())
+	assert.NotEqual(t, hash, newMessage(text).ContentHash())
+	assert.NotEqual(t, newMessage().ContentHash(), newMessage(TextContent{}).ContentHash())
+}
+
+func TestThinkingState(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		parts    []ContentPart
+		thinking bool
+		finished bool
+	}{
+		{"empty", nil, false, false},
+		{"text only", []ContentPart{TextContent{Text: "hi"}}, false, false},
+		{"reasoning", []ContentPart{ReasoningContent{Thinking: "hmm"}}, true, false},
+		{"empty reasoning then text", []ContentPart{ReasoningContent{}, TextContent{Text: "hi"}}, false, false},
+		{"reasoning then empty text", []ContentPart{ReasoningContent{Thinking: "hmm"}, TextContent{}}, true, false},
+		{"reasoning then text", []ContentPart{ReasoningContent{Thinking: "hmm"}, TextContent{Text: "hi"}}, false, true},
+		{"reasoning then tool call", []ContentPart{ReasoningContent{Thinking: "hmm"}, ToolCall{ID: "1", Name: "ls"}}, true, true},
+		{"reasoning finished without text", []ContentPart{ReasoningContent{Thinking: "hmm"}, Finish{Reason: FinishReasonEndTurn}}, false, false},
+	}
+	for _, tt := range tests {
+		msg := Message{Parts: tt.parts}
+		assert.Equal(t, tt.thinking, msg.IsThinking(), tt.name)
+		assert.Equal(t, tt.finished, msg.ReasoningFinished(), tt.name)
+	}
+
+	t.Run("streamed", func(t *testing.T) {
+		var msg Message
+		msg.AppendReasoningContent("Let me ")
+		msg.AppendReasoningContent("think.")
+		assert.True(t, msg.IsThinking())
+		assert.False(t, msg.ReasoningFinished())
+
+		msg.AppendContent("Done.")
+		assert.False(t, msg.IsThinking())
+		assert.True(t, msg.ReasoningFinished())
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -25,22 +26,54 @@ type Service interface {
 	Update(ctx context.Context, message Message) error
 	Get(ctx context.Context, id string) (Message, error)
 	List(ctx context.Context, sessionID string) ([]Message, error)
+	ListAfter(ctx context.Context, sessionID, messageID string) ([]Message, error)
 	Delete(ctx context.Context, id string) error
+	Pin(ctx context.Context, id string) (Message, error)
+	Unpin(ctx context.Context, id string) (Message, error)
 	DeleteSessionMessages(ctx context.Context, sessionID string) error
+	Search(ctx context.Context, query string) ([]SearchResult, error)
+	// SubscribeDeltas receives the deltas of messages as they stream in. The
+	// message's own UpdatedEvent follows periodically while it streams and
+	// once a streamed part is complete.
+	SubscribeDeltas(ctx context.Context, opts ...pubsub.SubscribeOption) <-chan pubsub.Event[MessageDelta]
+	// DeltaPublisher is where streams publish their deltas for SubscribeDeltas.
+	DeltaPublisher() pubsub.Publisher[MessageDelta]
 }
 
+// SearchResult is a message whose text matched a search, with a snippet of
+// the matching text.
+type SearchResult struct {
+	SessionID string
+	MessageID string
+	Snippet   string
+	CreatedAt int64
+}
+
+// searchLimit caps the number of results returned by Search.
+const searchLimit = 50
+
 type service struct {
 	*pubsub.Broker[Message]
-	q db.Querier
+	deltas *pubsub.Broker[MessageDelta]
+	q      db.Querier
 }
 
 func NewService(q db.Querier) Service {
 	return &service{
 		Broker: pubsub.NewBroker[Message](),
+		deltas: pubsub.NewBroker[MessageDelta](),
 		q:      q,
 	}
 }
 
+func (s *service) SubscribeDeltas(ctx context.Context, opts ...pubsub.SubscribeOption) <-chan pubsub.Event[MessageDelta] {
+	return s.deltas.Subscribe(ctx, opts...)
+}
+
+func (s *service) DeltaPublisher() pubsub.Publisher[MessageDelta] {
+	return s.deltas
+}
+
 func (s *service) Delete(ctx context.Context, id string) error {
 	message, err := s.Get(ctx, id)
 	if err != nil {
@@ -60,7 +93,7 @@ func (s *service) Create(ctx context.Context, sessionID string, params CreateMes
 			Reason: "stop",
 		})
 	}
-	partsJSON, err := marshallParts(params.Parts)
+	partsJSON, err := MarshalParts(params.Parts)
 	if err != nil {
 		return Message{}, err
 	}
@@ -74,7 +107,7 @@ func (s *service) Create(ctx context.Context, sessionID string, params CreateMes
 	if err != nil {
 		return Message{}, err
 	}
-	message, err := s.fromDBItem(dbMessage)
+	message, err := FromDBItem(dbMessage)
 	if err != nil {
 		return Message{}, err
 	}
@@ -99,7 +132,7 @@ func (s *service) DeleteSessionMessages(ctx context.Context, sessionID string) e
 }
 
 func (s *service) Update(ctx context.Context, message Message) error {
-	parts, err := marshallParts(message.Parts)
+	parts, err := MarshalParts(message.Parts)
 	if err != nil {
 		return err
 	}
@@ -121,12 +154,39 @@ func (s *service) Update(ctx context.Context, message Message) error {
 	return nil
 }
 
+// Pin marks a message so trimming and compaction always keep it in the
+// conversation.
+func (s *service) Pin(ctx context.Context, id string) (Message, error) {
+	return s.setPinned(ctx, id, true)
+}
+
+// Unpin lets a pinned message be trimmed and compacted again.
+func (s *service) Unpin(ctx context.Context, id string) (Message, error) {
+	return s.setPinned(ctx, id, false)
+}
+
+func (s *service) setPinned(ctx context.Context, id string, pinned bool) (Message, error) {
+	err := s.q.SetMessagePinned(ctx, db.SetMessagePinnedParams{
+		ID:     id,
+		Pinned: pinned,
+	})
+	if err != nil {
+		return Message{}, err
+	}
+	message, err := s.Get(ctx, id)
+	if err != nil {
+		return Message{}, err
+	}
+	s.Publish(pubsub.UpdatedEvent, message)
+	return message, nil
+}
+
 func (s *service) Get(ctx context.Context, id string) (Message, error) {
 	dbMessage, err := s.q.GetMessage(ctx, id)
 	if err != nil {
 		return Message{}, err
 	}
-	return s.fromDBItem(dbMessage)
+	return FromDBItem(dbMessage)
 }
 
 func (s *service) List(ctx context.Context, sessionID string) ([]Message, error) {
@@ -136,7 +196,27 @@ func (s *service) List(ctx context.Context, sessionID string) ([]Message, error)
 	}
 	messages := make([]Message, len(dbMessages))
 	for i, dbMessage := range dbMessages {
-		messages[i], err = s.fromDBItem(dbMessage)
+		messages[i], err = FromDBItem(dbMessage)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+// ListAfter returns the messages of a session that come after the given
+// message, oldest first.
+func (s *service) ListAfter(ctx context.Context, sessionID, messageID string) ([]Message, error) {
+	dbMessages, err := s.q.ListMessagesAfter(ctx, db.ListMessagesAfterParams{
+		SessionID: sessionID,
+		MessageID: messageID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]Message, len(dbMessages))
+	for i, dbMessage := range dbMessages {
+		messages[i], err = FromDBItem(dbMessage)
 		if err != nil {
 			return nil, err
 		}
@@ -144,8 +224,36 @@ func (s *service) List(ctx context.Context, sessionID string) ([]Message, error)
 	return messages, nil
 }
 
-func (s *service) fromDBItem(item db.Message) (Message, error) {
-	parts, err := unmarshallParts([]byte(item.Parts))
+// Search finds messages across all sessions whose text contains the query,
+// most recent first. The query is matched as a phrase, so punctuation such as
+// dots in identifiers is taken literally.
+func (s *service) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return []SearchResult{}, nil
+	}
+	rows, err := s.q.SearchMessages(ctx, db.SearchMessagesParams{
+		Query: `"` + strings.ReplaceAll(query, `"`, `""`) + `"`,
+		Limit: searchLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = SearchResult{
+			SessionID: row.SessionID,
+			MessageID: row.ID,
+			Snippet:   row.Snippet,
+			CreatedAt: row.CreatedAt,
+		}
+	}
+	return results, nil
+}
+
+// FromDBItem converts a stored message row into a Message.
+func FromDBItem(item db.Message) (Message, error) {
+	parts, err := UnmarshalPartsLenient([]byte(item.Parts))
 	if err != nil {
 		return Message{}, err
 	}
@@ -155,6 +263,7 @@ func (s *service) fromDBItem(item db.Message) (Message, error) {
 		Role:      MessageRole(item.Role),
 		Parts:     parts,
 		Model:     models.ModelID(item.Model.String),
+		Pinned:    item.Pinned,
 		CreatedAt: item.CreatedAt,
 		UpdatedAt: item.UpdatedAt,
 	}, nil
@@ -177,13 +286,22 @@ type partWrapper struct {
 	Data ContentPart `json:"data"`
 }
 
-func marshallParts(parts []ContentPart) ([]byte, error) {
-	wrappedParts := make([]partWrapper, len(parts))
+// MarshalParts encodes parts in the tagged form used for storage, so they can
+// be decoded again with UnmarshalParts. Equal parts always encode to the same
+// bytes, which content hashing relies on: parts are plain structs, so their
+// keys are written in field order. A part type given its own MarshalJSON must
+// keep that guarantee.
+func MarshalParts(parts []ContentPart) ([]byte, error) {
+	wrappedParts := make([]any, len(parts))
 
 	for i, part := range parts {
 		var typ partType
 
-		switch part.(type) {
+		switch part := part.(type) {
+		case UnknownContent:
+			// written back exactly as it was read
+			wrappedParts[i] = part.Raw
+			continue
 		case ReasoningContent:
 			typ = reasoningType
 		case TextContent:
@@ -210,7 +328,19 @@ func marshallParts(parts []ContentPart) ([]byte, error) {
 	return json.Marshal(wrappedParts)
 }
 
-func unmarshallParts(data []byte) ([]ContentPart, error) {
+// UnmarshalParts decodes parts encoded with MarshalParts.
+func UnmarshalParts(data []byte) ([]ContentPart, error) {
+	return unmarshalParts(data, false)
+}
+
+// UnmarshalPartsLenient decodes parts like UnmarshalParts, but keeps parts of
+// a type this version doesn't know, such as ones written by a newer version,
+// as UnknownContent instead of failing.
+func UnmarshalPartsLenient(data []byte) ([]ContentPart, error) {
+	return unmarshalParts(data, true)
+}
+
+func unmarshalParts(data []byte, lenient bool) ([]ContentPart, error) {
 	temp := []json.RawMessage{}
 
 	if err := json.Unmarshal(data, &temp); err != nil {
@@ -247,6 +377,7 @@ func unmarshallParts(data []byte) ([]ContentPart, error) {
 			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
 				return nil, err
 			}
+			parts = append(parts, part)
 		case binaryType:
 			part := BinaryContent{}
 			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
@@ -272,7 +403,10 @@ func unmarshallParts(data []byte) ([]ContentPart, error) {
 			}
 			parts = append(parts, part)
 		default:
-			return nil, fmt.Errorf("unknown part type: %s", wrapper.Type)
+			if !lenient {
+				return nil, fmt.Errorf("unknown part type: %s", wrapper.Type)
+			}
+			parts = append(parts, UnknownContent{Type: string(wrapper.Type), Raw: rawPart})
 		}
 
 	}
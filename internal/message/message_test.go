@@ -0,0 +1,192 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "opencode.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	goose.SetBaseFS(db.FS)
+	goose.SetLogger(goose.NopLogger())
+	require.NoError(t, goose.SetDialect("sqlite3"))
+	require.NoError(t, goose.Up(conn, "migrations"))
+	return conn
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	for _, id := range []string{"first", "second"} {
+		_, err := conn.Exec(`INSERT INTO sessions (id, title, created_at, updated_at) VALUES (?, ?, 0, 0)`, id, id)
+		require.NoError(t, err)
+	}
+	s := NewService(db.New(conn))
+
+	older, err := s.Create(ctx, "first", CreateMessageParams{
+		Role:  User,
+		Parts: []ContentPart{TextContent{Text: "Why does parseConfig return nil here?"}},
+	})
+	require.NoError(t, err)
+	_, err = s.Create(ctx, "first", CreateMessageParams{
+		Role: Assistant,
+		Parts: []ContentPart{
+			ToolCall{ID: "1", Name: "view", Input: `{"file_path": "parseConfig.go"}`},
+		},
+	})
+	require.NoError(t, err)
+	newer, err := s.Create(ctx, "second", CreateMessageParams{
+		Role:  Assistant,
+		Parts: []ContentPart{TextContent{Text: "draft"}},
+	})
+	require.NoError(t, err)
+
+	// the updated text must be indexed rather than the original
+	newer.Parts = []ContentPart{TextContent{Text: "Renamed parseConfig to loadConfig."}, Finish{Reason: FinishReasonEndTurn}}
+	require.NoError(t, s.Update(ctx, newer))
+	_, err = conn.Exec(`UPDATE messages SET created_at = created_at + 10 WHERE id = ?`, newer.ID)
+	require.NoError(t, err)
+
+	results, err := s.Search(ctx, "parseConfig")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, newer.ID, results[0].MessageID)
+	assert.Equal(t, "second", results[0].SessionID)
+	assert.Contains(t, results[0].Snippet, "parseConfig")
+	assert.Equal(t, older.ID, results[1].MessageID)
+
+	results, err = s.Search(ctx, "draft")
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	// FTS syntax in the query is taken literally
+	results, err = s.Search(ctx, `loadConfig." OR`)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	require.NoError(t, s.Delete(ctx, older.ID))
+	results, err = s.Search(ctx, "parseConfig")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+}
+
+func TestPin(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	_, err := conn.Exec(`INSERT INTO sessions (id, title, created_at, updated_at) VALUES ('session', 'session', 0, 0)`)
+	require.NoError(t, err)
+	s := NewService(db.New(conn))
+
+	created, err := s.Create(ctx, "session", CreateMessageParams{
+		Role:  User,
+		Parts: []ContentPart{TextContent{Text: "project context"}},
+	})
+	require.NoError(t, err)
+	assert.False(t, created.Pinned)
+
+	pinned, err := s.Pin(ctx, created.ID)
+	require.NoError(t, err)
+	assert.True(t, pinned.Pinned)
+	got, err := s.Get(ctx, created.ID)
+	require.NoError(t, err)
+	assert.True(t, got.Pinned)
+
+	unpinned, err := s.Unpin(ctx, created.ID)
+	require.NoError(t, err)
+	assert.False(t, unpinned.Pinned)
+
+	_, err = s.Pin(ctx, "missing")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestMarshalPartsIsStable(t *testing.T) {
+	t.Parallel()
+
+	parts := []ContentPart{
+		ReasoningContent{Thinking: "hmm"},
+		TextContent{Text: "hello"},
+		ImageURLContent{URL: "https://example.com/a.png", Detail: "low"},
+		BinaryContent{Path: "a.png", MIMEType: "image/png", Data: []byte{1, 2, 3}},
+		ToolCall{ID: "call", Name: "bash", Input: `{"command":"ls"}`, Type: "function", Finished: true},
+		ToolResult{ToolCallID: "call", Name: "bash", Content: "a.png", Metadata: `{"b":1,"a":2}`},
+		Finish{Reason: FinishReasonEndTurn, Time: 1700000000},
+	}
+
+	first, err := MarshalParts(parts)
+	require.NoError(t, err)
+	for range 20 {
+		again, err := MarshalParts(slices.Clone(parts))
+		require.NoError(t, err)
+		require.Equal(t, first, again)
+	}
+
+	data, err := MarshalParts([]ContentPart{ToolCall{ID: "call", Name: "bash", Input: "{}", Type: "function"}})
+	require.NoError(t, err)
+	assert.Equal(t, `[{"type":"tool_call","data":{"id":"call","name":"bash","input":"{}","type":"function","finished":false}}]`, string(data))
+}
+
+func TestUnmarshalPartsLenient(t *testing.T) {
+	t.Parallel()
+
+	stored := `[{"type":"text","data":{"text":"hi"}},{"type":"citation","data":{"url":"https://example.com","spans":[1,2]}},{"type":"finish","data":{"reason":"end_turn","time":1}}]`
+
+	_, err := UnmarshalParts([]byte(stored))
+	assert.ErrorContains(t, err, "unknown part type: citation")
+
+	parts, err := UnmarshalPartsLenient([]byte(stored))
+	require.NoError(t, err)
+	require.Len(t, parts, 3)
+	assert.Equal(t, TextContent{Text: "hi"}, parts[0])
+	unknown, ok := parts[1].(UnknownContent)
+	require.True(t, ok)
+	assert.Equal(t, "citation", unknown.Type)
+	assert.Equal(t, FinishReasonEndTurn, parts[2].(Finish).Reason)
+
+	data, err := MarshalParts(parts)
+	require.NoError(t, err)
+	assert.Equal(t, stored, string(data), "unknown parts are written back unchanged")
+}
+
+func TestFinishUsage(t *testing.T) {
+	t.Parallel()
+
+	msg := Message{Parts: []ContentPart{TextContent{Text: "done"}}}
+	msg.AddFinishWithUsage(FinishReasonEndTurn, 120, 30, 80)
+	finish := msg.FinishPart()
+	require.NotNil(t, finish)
+	assert.Equal(t, int64(120), finish.InputTokens)
+	assert.Equal(t, int64(30), finish.OutputTokens)
+	assert.Equal(t, int64(80), finish.CachedTokens)
+
+	data, err := MarshalParts(msg.Parts)
+	require.NoError(t, err)
+	parts, err := UnmarshalParts(data)
+	require.NoError(t, err)
+	assert.Equal(t, *finish, parts[1])
+
+	msg.AddFinish(FinishReasonCanceled)
+	require.Len(t, msg.Parts, 2)
+	assert.Zero(t, msg.FinishPart().InputTokens, "a finish without usage replaces the earlier one")
+
+	data, err = MarshalParts([]ContentPart{Finish{Reason: FinishReasonEndTurn, Time: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, `[{"type":"finish","data":{"reason":"end_turn","time":1}}]`, string(data), "usage is omitted when not reported")
+
+	parts, err = UnmarshalParts([]byte(`[{"type":"finish","data":{"reason":"max_tokens","time":5}}]`))
+	require.NoError(t, err)
+	assert.Equal(t, Finish{Reason: FinishReasonMaxTokens, Time: 5}, parts[0])
+}
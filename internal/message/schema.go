@@ -0,0 +1,203 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+)
+
+// ValidateInput checks that the tool call's input is a single JSON value that
+// conforms to schema, so malformed arguments can be reported to the model
+// before the tool runs. Empty input is treated as an empty object, which is
+// what providers send for calls without arguments.
+//
+// The schema keywords checked are type, properties, required,
+// additionalProperties, items and enum; any others are ignored.
+func (tc ToolCall) ValidateInput(schema json.RawMessage) error {
+	var s map[string]any
+	if err := decodeJSON([]byte(schema), &s); err != nil {
+		return fmt.Errorf("invalid schema for tool %s: %w", tc.Name, err)
+	}
+
+	input := strings.TrimSpace(tc.Input)
+	if input == "" {
+		input = "{}"
+	}
+	var value any
+	if err := decodeJSON([]byte(input), &value); err != nil {
+		return fmt.Errorf("input for tool %s is not valid JSON: %w", tc.Name, err)
+	}
+	if err := validateSchema("input", value, s); err != nil {
+		return fmt.Errorf("invalid input for tool %s: %w", tc.Name, err)
+	}
+	return nil
+}
+
+// IsInputComplete reports whether the input streamed so far is a complete
+// JSON value. It is false while the input is empty or cut off partway.
+func (tc ToolCall) IsInputComplete() bool {
+	input := strings.TrimSpace(tc.Input)
+	return input != "" && json.Valid([]byte(input))
+}
+
+// decodeJSON decodes a single JSON value, keeping numbers exact.
+func decodeJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("unexpected data after the value")
+	}
+	return nil
+}
+
+func validateSchema(path string, value any, schema map[string]any) error {
+	if err := checkType(path, value, schema["type"]); err != nil {
+		return err
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(allowed any) bool {
+		return jsonEqual(allowed, value)
+	}) {
+		return fmt.Errorf("%s must be one of %s", path, formatJSON(enum))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if name, ok := name.(string); ok {
+					if _, ok := v[name]; !ok {
+						return fmt.Errorf("%s is missing required property %q", path, name)
+					}
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			childPath := path + "." + key
+			if property, ok := properties[key].(map[string]any); ok {
+				if err := validateSchema(childPath, v[key], property); err != nil {
+					return err
+				}
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					return fmt.Errorf("%s is not an allowed property", childPath)
+				}
+			case map[string]any:
+				if err := validateSchema(childPath, v[key], additional); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateSchema(fmt.Sprintf("%s[%d]", path, i), item, items); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkType checks value against a schema type, which is a type name or a
+// list of them.
+func checkType(path string, value any, schemaType any) error {
+	var allowed []string
+	switch t := schemaType.(type) {
+	case string:
+		allowed = []string{t}
+	case []any:
+		for _, name := range t {
+			if name, ok := name.(string); ok {
+				allowed = append(allowed, name)
+			}
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, name := range allowed {
+		if hasType(value, name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s must be %s, got %s", path, strings.Join(allowed, " or "), jsonTypeName(value))
+}
+
+func hasType(value any, name string) bool {
+	switch name {
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	default:
+		return jsonTypeName(value) == name
+	}
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// jsonEqual compares decoded JSON values, treating numbers as equal when they
+// have the same value.
+func jsonEqual(a, b any) bool {
+	if an, ok := a.(json.Number); ok {
+		bn, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, aErr := an.Float64()
+		bf, bErr := bn.Float64()
+		return an == bn || (aErr == nil && bErr == nil && af == bf)
+	}
+	switch a.(type) {
+	case map[string]any, []any:
+		return formatJSON(a) == formatJSON(b)
+	}
+	return a == b
+}
+
+func formatJSON(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
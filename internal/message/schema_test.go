@@ -0,0 +1,83 @@
+package message
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateInput(t *testing.T) {
+	t.Parallel()
+
+	schema := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"command": {"type": "string"},
+			"timeout": {"type": "integer"},
+			"mode": {"type": "string", "enum": ["fast", "safe"]},
+			"paths": {"type": "array", "items": {"type": "string"}},
+			"env": {"type": "object", "additionalProperties": {"type": "string"}},
+			"limit": {"type": ["number", "null"]}
+		},
+		"required": ["command"],
+		"additionalProperties": false
+	}`)
+	call := func(input string) ToolCall {
+		return ToolCall{ID: "call", Name: "bash", Input: input}
+	}
+
+	valid := []string{
+		`{"command": "ls"}`,
+		`{"command": "ls", "timeout": 30, "mode": "safe", "paths": ["a", "b"], "env": {"A": "1"}, "limit": null}`,
+		`{"command": "ls", "timeout": 30.0, "limit": 2.5}`,
+	}
+	for _, input := range valid {
+		assert.NoError(t, call(input).ValidateInput(schema), input)
+	}
+
+	invalid := map[string]string{
+		`{"command": "ls"`:                     "not valid JSON",
+		`{"command": "ls"} {}`:                 "not valid JSON",
+		`[]`:                                   "input must be object, got array",
+		`{}`:                                   `input is missing required property "command"`,
+		`{"command": 1}`:                       "input.command must be string, got number",
+		`{"command": "ls", "timeout": 1.5}`:    "input.timeout must be integer, got number",
+		`{"command": "ls", "mode": "slow"}`:    `input.mode must be one of ["fast","safe"]`,
+		`{"command": "ls", "paths": ["a", 2]}`: "input.paths[1] must be string, got number",
+		`{"command": "ls", "env": {"A": 1}}`:   "input.env.A must be string, got number",
+		`{"command": "ls", "limit": "all"}`:    "input.limit must be number or null, got string",
+		`{"command": "ls", "extra": true}`:     "input.extra is not an allowed property",
+	}
+	for input, message := range invalid {
+		err := call(input).ValidateInput(schema)
+		if assert.Error(t, err, input) {
+			assert.Contains(t, err.Error(), message, input)
+			assert.Contains(t, err.Error(), "tool bash", input)
+		}
+	}
+
+	t.Run("empty input is an empty object", func(t *testing.T) {
+		assert.NoError(t, call("").ValidateInput(json.RawMessage(`{"type": "object", "properties": {}}`)))
+		assert.ErrorContains(t, call("").ValidateInput(schema), "missing required property")
+	})
+
+	t.Run("invalid schema", func(t *testing.T) {
+		assert.ErrorContains(t, call(`{}`).ValidateInput(json.RawMessage(`{`)), "invalid schema")
+	})
+}
+
+func TestIsInputComplete(t *testing.T) {
+	t.Parallel()
+
+	for input, complete := range map[string]bool{
+		``:                          false,
+		`{"command": "l`:            false,
+		`{"command": "ls"`:          false,
+		`{"command": "ls"}`:         true,
+		" {\"paths\": [1, 2]}\n ":   true,
+		`{"command": "ls"}{"a": 1}`: false,
+	} {
+		assert.Equal(t, complete, ToolCall{Input: input}.IsInputComplete(), input)
+	}
+}
@@ -0,0 +1,173 @@
+package message
+
+import (
+	"slices"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/pubsub"
+)
+
+// DeltaKind is the part of a message a MessageDelta adds to.
+type DeltaKind string
+
+const (
+	DeltaText      DeltaKind = "text"
+	DeltaReasoning DeltaKind = "reasoning"
+	DeltaToolInput DeltaKind = "tool_input"
+)
+
+// MessageDelta is a piece of a message as it streams in, so subscribers can
+// render it without waiting for the next full update of the message.
+type MessageDelta struct {
+	MessageID string
+	SessionID string
+	Kind      DeltaKind
+	// ToolCallID is set for DeltaToolInput.
+	ToolCallID string
+	// Offset is the length of the content the delta adds to, before it was
+	// added.
+	Offset int
+	Delta  string
+}
+
+// Apply adds the delta to msg, which should be the message it belongs to. A
+// delta that doesn't continue msg's content where it ends, because an earlier
+// one was missed or a newer save of the message already holds it, is skipped
+// and Apply reports false.
+func (d MessageDelta) Apply(msg *Message) bool {
+	if deltaTarget(*msg, d.Kind, d.ToolCallID) != d.Offset {
+		return false
+	}
+	switch d.Kind {
+	case DeltaText:
+		msg.AppendContent(d.Delta)
+	case DeltaReasoning:
+		msg.AppendReasoningContent(d.Delta)
+	case DeltaToolInput:
+		msg.AppendToolCallInput(d.ToolCallID, d.Delta)
+	}
+	return true
+}
+
+// deltaTarget returns the length of the content of msg a delta of kind adds
+// to, or -1 if msg has no such content to add to.
+func deltaTarget(msg Message, kind DeltaKind, toolCallID string) int {
+	switch kind {
+	case DeltaText:
+		return len(msg.Content().Text)
+	case DeltaReasoning:
+		return len(msg.ReasoningContent().Thinking)
+	case DeltaToolInput:
+		for _, tc := range msg.ToolCalls() {
+			if tc.ID == toolCallID {
+				return len(tc.Input)
+			}
+		}
+	}
+	return -1
+}
+
+// StreamAssembler builds up a message from the deltas a provider streams. It
+// changes the message it was created with in place, so the caller can keep
+// persisting that message while the stream is in progress.
+type StreamAssembler struct {
+	msg       *Message
+	publisher pubsub.Publisher[MessageDelta]
+	lastSave  time.Time
+}
+
+// NewStreamAssembler returns an assembler that adds to msg. A nil msg starts
+// an empty assistant message.
+func NewStreamAssembler(msg *Message) *StreamAssembler {
+	if msg == nil {
+		msg = &Message{Role: Assistant}
+	}
+	return &StreamAssembler{msg: msg, lastSave: time.Now()}
+}
+
+// SaveDue reports whether interval has passed since it last returned true, or
+// since the assembler was created, so streamed content can be persisted
+// periodically rather than on every delta.
+func (s *StreamAssembler) SaveDue(interval time.Duration) bool {
+	if time.Since(s.lastSave) < interval {
+		return false
+	}
+	s.lastSave = time.Now()
+	return true
+}
+
+// PublishDeltas publishes every delta added from now on to publisher.
+func (s *StreamAssembler) PublishDeltas(publisher pubsub.Publisher[MessageDelta]) {
+	s.publisher = publisher
+}
+
+func (s *StreamAssembler) publish(delta MessageDelta) {
+	if s.publisher == nil || delta.Delta == "" {
+		return
+	}
+	delta.MessageID = s.msg.ID
+	delta.SessionID = s.msg.SessionID
+	s.publisher.Publish(pubsub.CreatedEvent, delta)
+}
+
+// AppendTextDelta adds streamed text to the message's text content.
+func (s *StreamAssembler) AppendTextDelta(delta string) {
+	offset := len(s.msg.Content().Text)
+	s.msg.AppendContent(delta)
+	s.publish(MessageDelta{Kind: DeltaText, Offset: offset, Delta: delta})
+}
+
+// AppendReasoningDelta adds streamed thinking to the message's reasoning
+// content.
+func (s *StreamAssembler) AppendReasoningDelta(delta string) {
+	offset := len(s.msg.ReasoningContent().Thinking)
+	s.msg.AppendReasoningContent(delta)
+	s.publish(MessageDelta{Kind: DeltaReasoning, Offset: offset, Delta: delta})
+}
+
+// StartToolCall adds a tool call whose input may still be streaming. A call
+// with the same ID replaces the earlier one.
+func (s *StreamAssembler) StartToolCall(tc ToolCall) {
+	s.msg.AddToolCall(tc)
+}
+
+// AppendToolCallInputDelta adds streamed input to a started tool call. Deltas
+// for unknown calls are ignored.
+func (s *StreamAssembler) AppendToolCallInputDelta(toolCallID, delta string) {
+	for _, tc := range s.msg.ToolCalls() {
+		if tc.ID == toolCallID {
+			s.msg.AppendToolCallInput(toolCallID, delta)
+			s.publish(MessageDelta{Kind: DeltaToolInput, ToolCallID: toolCallID, Offset: len(tc.Input), Delta: delta})
+			return
+		}
+	}
+}
+
+// FinishToolCall marks a tool call's input as complete.
+func (s *StreamAssembler) FinishToolCall(toolCallID string) {
+	s.msg.FinishToolCall(toolCallID)
+}
+
+// SetToolCalls replaces the streamed tool calls with the final list the
+// provider reported when the response completed.
+func (s *StreamAssembler) SetToolCalls(calls []ToolCall) {
+	s.msg.SetToolCalls(calls)
+}
+
+// Finish ends the message with reason, replacing any earlier finish.
+func (s *StreamAssembler) Finish(reason FinishReason) {
+	s.msg.AddFinish(reason)
+}
+
+// FinishWithUsage ends the message like Finish, recording the token usage
+// of the response.
+func (s *StreamAssembler) FinishWithUsage(reason FinishReason, inputTokens, outputTokens, cachedTokens int64) {
+	s.msg.AddFinishWithUsage(reason, inputTokens, outputTokens, cachedTokens)
+}
+
+// Message returns a copy of the message assembled so far.
+func (s *StreamAssembler) Message() Message {
+	msg := *s.msg
+	msg.Parts = slices.Clone(s.msg.Parts)
+	return msg
+}
@@ -0,0 +1,126 @@
+package message
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamAssembler(t *testing.T) {
+	t.Parallel()
+
+	msg := Message{ID: "msg", Role: Assistant}
+	stream := NewStreamAssembler(&msg)
+
+	stream.AppendReasoningDelta("let me ")
+	stream.AppendReasoningDelta("check")
+	stream.AppendTextDelta("Reading ")
+	stream.StartToolCall(ToolCall{ID: "call-1", Name: "view"})
+	stream.AppendToolCallInputDelta("call-1", `{"file_path":`)
+	stream.AppendTextDelta("the file")
+	stream.AppendToolCallInputDelta("call-1", `"main.go"}`)
+	stream.AppendToolCallInputDelta("unknown", "ignored")
+
+	partial := stream.Message()
+	assert.False(t, partial.ToolCalls()[0].Finished)
+	assert.False(t, partial.IsFinished())
+
+	stream.FinishToolCall("call-1")
+	stream.Finish(FinishReasonToolUse)
+
+	got := stream.Message()
+	assert.Equal(t, "msg", got.ID)
+	assert.Equal(t, "let me check", got.ReasoningContent().Thinking)
+	assert.Equal(t, "Reading the file", got.Content().Text)
+	require.Len(t, got.ToolCalls(), 1)
+	assert.Equal(t, ToolCall{ID: "call-1", Name: "view", Input: `{"file_path":"main.go"}`, Finished: true}, got.ToolCalls()[0])
+	assert.Equal(t, FinishReasonToolUse, got.FinishReason())
+	assert.Equal(t, got.Parts, msg.Parts, "the message is assembled in place")
+
+	// earlier snapshots don't change as the stream continues
+	assert.False(t, partial.ToolCalls()[0].Finished)
+	stream.Finish(FinishReasonEndTurn)
+	assert.Equal(t, FinishReasonToolUse, got.FinishReason())
+	final := stream.Message()
+	assert.Equal(t, FinishReasonEndTurn, final.FinishReason())
+
+	empty := NewStreamAssembler(nil).Message()
+	assert.Equal(t, Assistant, empty.Role)
+}
+
+type deltaRecorder []MessageDelta
+
+func (r *deltaRecorder) Publish(_ pubsub.EventType, delta MessageDelta) {
+	*r = append(*r, delta)
+}
+
+func TestStreamAssemblerPublishesDeltas(t *testing.T) {
+	t.Parallel()
+
+	msg := Message{ID: "msg", SessionID: "session", Role: Assistant}
+	stream := NewStreamAssembler(&msg)
+	var deltas deltaRecorder
+	stream.PublishDeltas(&deltas)
+
+	stream.AppendReasoningDelta("hmm")
+	stream.AppendTextDelta("")
+	stream.AppendTextDelta("Hi")
+	stream.StartToolCall(ToolCall{ID: "call-1", Name: "view"})
+	stream.AppendToolCallInputDelta("call-1", "{}")
+	stream.AppendToolCallInputDelta("call-1", "x")
+	stream.AppendToolCallInputDelta("unknown", "ignored")
+
+	assert.Equal(t, deltaRecorder{
+		{MessageID: "msg", SessionID: "session", Kind: DeltaReasoning, Delta: "hmm"},
+		{MessageID: "msg", SessionID: "session", Kind: DeltaText, Delta: "Hi"},
+		{MessageID: "msg", SessionID: "session", Kind: DeltaToolInput, ToolCallID: "call-1", Delta: "{}"},
+		{MessageID: "msg", SessionID: "session", Kind: DeltaToolInput, ToolCallID: "call-1", Offset: 2, Delta: "x"},
+	}, deltas)
+
+	// a subscriber applying the deltas ends up with the same message
+	replica := Message{ID: "msg", Parts: []ContentPart{ToolCall{ID: "call-1", Name: "view"}}}
+	for _, delta := range deltas {
+		assert.True(t, delta.Apply(&replica))
+	}
+	assert.Equal(t, "hmm", replica.ReasoningContent().Thinking)
+	assert.Equal(t, "Hi", replica.Content().Text)
+	assert.Equal(t, "{}x", replica.ToolCalls()[0].Input)
+}
+
+func TestMessageDeltaApplySkipsOutOfOrderDeltas(t *testing.T) {
+	t.Parallel()
+
+	msg := Message{ID: "msg", SessionID: "session", Role: Assistant}
+	stream := NewStreamAssembler(&msg)
+	var deltas deltaRecorder
+	stream.PublishDeltas(&deltas)
+	stream.AppendTextDelta("Hel")
+	stream.AppendTextDelta("lo")
+	stream.AppendTextDelta("!")
+
+	// a missed delta leaves the rest unapplied until the message is saved
+	replica := Message{ID: "msg"}
+	assert.True(t, deltas[0].Apply(&replica))
+	assert.False(t, deltas[2].Apply(&replica))
+	assert.Equal(t, "Hel", replica.Content().Text)
+
+	// a delta the saved message already holds isn't appended twice
+	saved := stream.Message()
+	assert.False(t, deltas[1].Apply(&saved))
+	assert.Equal(t, "Hello!", saved.Content().Text)
+}
+
+func TestStreamAssemblerSaveDue(t *testing.T) {
+	t.Parallel()
+
+	stream := NewStreamAssembler(nil)
+	assert.False(t, stream.SaveDue(time.Hour))
+	assert.True(t, stream.SaveDue(0))
+
+	stream.lastSave = time.Now().Add(-2 * time.Second)
+	assert.True(t, stream.SaveDue(time.Second))
+	assert.False(t, stream.SaveDue(time.Second))
+}
@@ -0,0 +1,122 @@
+package message
+
+import (
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/llm/tokenize"
+)
+
+// messageOverheadTokens approximates the tokens a provider spends on a
+// message's role and framing on top of its content.
+const messageOverheadTokens = 4
+
+// TrimMessagesToFit drops the oldest messages until the conversation leaves
+// reserveTokens of model's context window free for the response. An
+// assistant message and the tool results that follow it are kept or dropped
+// together, since providers reject a tool call without its result and a
+// result without its call. Pinned messages, along with the rest of their
+// tool exchange, and a leading system message are always kept, as is
+// everything from the latest user message on, even if that alone doesn't
+// fit. Tool results with no call before them are dropped with the messages
+// before them. Messages are returned in their original order; with no context
+// window known they are returned unchanged.
+func TrimMessagesToFit(messages []Message, model models.Model, reserveTokens int) []Message {
+	if model.ContextWindow <= 0 || len(messages) == 0 {
+		return messages
+	}
+	budget := int(model.ContextWindow) - reserveTokens
+
+	units := toolExchanges(messages)
+	tokens := func(u []Message) int {
+		total := 0
+		for _, msg := range u {
+			total += EstimateTokens(msg, model.Provider)
+		}
+		return total
+	}
+	keep := func(i int) bool {
+		if i == 0 && units[i][0].Role == System {
+			return true
+		}
+		for _, msg := range units[i] {
+			if msg.Pinned {
+				return true
+			}
+		}
+		return false
+	}
+	for i := range units {
+		if keep(i) {
+			budget -= tokens(units[i])
+		}
+	}
+
+	// the latest user message and what followed it are never dropped
+	start := len(units)
+	for i := len(units) - 1; i >= 0; i-- {
+		if units[i][0].Role == User {
+			start = i
+			break
+		}
+	}
+	for i := start; i < len(units); i++ {
+		if !keep(i) {
+			budget -= tokens(units[i])
+		}
+	}
+	for ; start > 0; start-- {
+		if keep(start - 1) {
+			continue
+		}
+		cost := tokens(units[start-1])
+		if cost > budget {
+			break
+		}
+		budget -= cost
+	}
+	for start < len(units) && units[start][0].Role == Tool && !keep(start) {
+		start++
+	}
+
+	trimmed := make([]Message, 0, len(messages))
+	for i, u := range units {
+		if i >= start || keep(i) {
+			trimmed = append(trimmed, u...)
+		}
+	}
+	return trimmed
+}
+
+// toolExchanges splits messages into the units trimming keeps or drops
+// whole: an assistant message together with the tool results that follow
+// it, and every other message on its own.
+func toolExchanges(messages []Message) [][]Message {
+	var units [][]Message
+	for i, msg := range messages {
+		if n := len(units); n > 0 && msg.Role == Tool && units[n-1][0].Role == Assistant {
+			units[n-1] = messages[i-len(units[n-1]) : i+1]
+			continue
+		}
+		units = append(units, messages[i:i+1])
+	}
+	return units
+}
+
+// EstimateTokens estimates the tokens msg takes up in a request to provider,
+// using the tokenizer registered for it.
+func EstimateTokens(msg Message, provider models.ModelProvider) int {
+	tokenizer := tokenize.For(provider)
+	tokens := messageOverheadTokens
+	for _, part := range msg.Parts {
+		switch c := part.(type) {
+		case TextContent:
+			tokens += tokenizer.Count(c.Text)
+		case ReasoningContent:
+			tokens += tokenizer.Count(c.Thinking)
+		case ToolCall:
+			tokens += tokenizer.Count(c.Name) + tokenizer.Count(c.Input)
+		case ToolResult:
+			tokens += tokenizer.Count(c.Content)
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,120 @@
+package message
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrimMessagesToFit(t *testing.T) {
+	t.Parallel()
+
+	// with the fallback tokenizer each of these costs 2 tokens of text plus
+	// the per-message overhead
+	msg := func(id string, role MessageRole) Message {
+		return Message{ID: id, Role: role, Parts: []ContentPart{TextContent{Text: "12345678"}}}
+	}
+	const perMessage = 2 + messageOverheadTokens
+	ids := func(messages []Message) []string {
+		var out []string
+		for _, m := range messages {
+			out = append(out, m.ID)
+		}
+		return out
+	}
+	model := func(window int) models.Model {
+		return models.Model{Provider: models.ProviderMock, ContextWindow: int64(window)}
+	}
+
+	conversation := []Message{
+		msg("system", System),
+		msg("u1", User),
+		msg("a1", Assistant),
+		msg("u2", User),
+		msg("a2", Assistant),
+		msg("u3", User),
+	}
+
+	t.Run("fits", func(t *testing.T) {
+		got := TrimMessagesToFit(conversation, model(100), 10)
+		assert.Equal(t, ids(conversation), ids(got))
+	})
+
+	t.Run("drops the oldest and keeps the system message", func(t *testing.T) {
+		got := TrimMessagesToFit(conversation, model(4*perMessage+5), 5)
+		assert.Equal(t, []string{"system", "u2", "a2", "u3"}, ids(got))
+	})
+
+	t.Run("never drops the latest user message", func(t *testing.T) {
+		got := TrimMessagesToFit(conversation, model(1), 0)
+		assert.Equal(t, []string{"system", "u3"}, ids(got))
+
+		withReply := []Message{msg("u1", User), msg("a1", Assistant), msg("u2", User), msg("a3", Assistant)}
+		got = TrimMessagesToFit(withReply, model(perMessage), 0)
+		assert.Equal(t, []string{"u2", "a3"}, ids(got))
+	})
+
+	t.Run("drops orphaned tool results", func(t *testing.T) {
+		toolConversation := []Message{
+			msg("u1", User),
+			msg("a1", Assistant),
+			msg("tool", Tool),
+			msg("a2", Assistant),
+			msg("u2", User),
+		}
+		got := TrimMessagesToFit(toolConversation, model(3*perMessage), 0)
+		assert.Equal(t, []string{"a2", "u2"}, ids(got))
+	})
+
+	t.Run("keeps pinned messages", func(t *testing.T) {
+		pinned := msg("a1", Assistant)
+		pinned.Pinned = true
+		withPinned := []Message{msg("u1", User), pinned, msg("u2", User), msg("a2", Assistant), msg("u3", User)}
+		got := TrimMessagesToFit(withPinned, model(3*perMessage), 0)
+		assert.Equal(t, []string{"a1", "a2", "u3"}, ids(got))
+	})
+
+	t.Run("keeps tool calls with their results", func(t *testing.T) {
+		call := msg("call", Assistant)
+		call.Parts = append(call.Parts, ToolCall{ID: "1", Name: "view", Finished: true})
+		result := func(id string) Message {
+			m := msg(id, Tool)
+			m.Parts = []ContentPart{ToolResult{ToolCallID: "1", Content: "12345678"}}
+			return m
+		}
+		toolConversation := []Message{
+			msg("u1", User),
+			call,
+			result("r1"),
+			result("r2"),
+			msg("a1", Assistant),
+			msg("u2", User),
+		}
+
+		// only the result would fit, but it goes with its call
+		got := TrimMessagesToFit(toolConversation, model(3*perMessage), 0)
+		assert.Equal(t, []string{"a1", "u2"}, ids(got))
+
+		// the call's tool name costs one token on top of its text
+		got = TrimMessagesToFit(toolConversation, model(5*perMessage+3), 0)
+		assert.Equal(t, []string{"call", "r1", "r2", "a1", "u2"}, ids(got))
+
+		// pinning either side keeps the whole exchange
+		pinnedResult := slices.Clone(toolConversation)
+		pinnedResult[3].Pinned = true
+		got = TrimMessagesToFit(pinnedResult, model(1), 0)
+		assert.Equal(t, []string{"call", "r1", "r2", "u2"}, ids(got))
+
+		pinnedCall := slices.Clone(toolConversation)
+		pinnedCall[1].Pinned = true
+		got = TrimMessagesToFit(pinnedCall, model(1), 0)
+		assert.Equal(t, []string{"call", "r1", "r2", "u2"}, ids(got))
+	})
+
+	t.Run("unknown context window", func(t *testing.T) {
+		got := TrimMessagesToFit(conversation, model(0), 0)
+		assert.Equal(t, ids(conversation), ids(got))
+	})
+}
@@ -0,0 +1,67 @@
+package permission
+
+import (
+	"context"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/pubsub"
+)
+
+// maxAuditEntries bounds the in-memory audit log, dropping the oldest entries
+// once it is full.
+const maxAuditEntries = 1000
+
+type AuditDecision string
+
+const (
+	AuditAutoApproved    AuditDecision = "auto_approved"
+	AuditPersistentGrant AuditDecision = "persistent_grant"
+	AuditUserGranted     AuditDecision = "user_granted"
+	AuditDenied          AuditDecision = "denied"
+	AuditTimedOut        AuditDecision = "timed_out"
+)
+
+// AuditEntry records the outcome of a single permission request.
+type AuditEntry struct {
+	SessionID string        `json:"session_id"`
+	ToolName  string        `json:"tool_name"`
+	Action    string        `json:"action"`
+	Path      string        `json:"path"`
+	Decision  AuditDecision `json:"decision"`
+	Time      time.Time     `json:"time"`
+}
+
+func (s *permissionService) recordDecision(opts CreatePermissionRequest, decision AuditDecision) {
+	entry := AuditEntry{
+		SessionID: opts.SessionID,
+		ToolName:  opts.ToolName,
+		Action:    opts.Action,
+		Path:      opts.Path,
+		Decision:  decision,
+		Time:      time.Now(),
+	}
+
+	s.auditMu.Lock()
+	s.auditLog = append(s.auditLog, entry)
+	if len(s.auditLog) > maxAuditEntries {
+		s.auditLog = s.auditLog[len(s.auditLog)-maxAuditEntries:]
+	}
+	s.auditMu.Unlock()
+
+	s.auditBroker.Publish(pubsub.CreatedEvent, entry)
+}
+
+// AuditLog returns a copy of the recorded permission decisions, oldest first.
+func (s *permissionService) AuditLog() []AuditEntry {
+	s.auditMu.Lock()
+	defer s.auditMu.Unlock()
+	entries := make([]AuditEntry, len(s.auditLog))
+	copy(entries, s.auditLog)
+	return entries
+}
+
+// SubscribeAudit streams permission decisions as they are recorded. Pass
+// pubsub.WithDeliveryPolicy(pubsub.DeliveryBlock) to never miss a decision.
+func (s *permissionService) SubscribeAudit(ctx context.Context, opts ...pubsub.SubscribeOption) <-chan pubsub.Event[AuditEntry] {
+	return s.auditBroker.Subscribe(ctx, opts...)
+}
@@ -1,11 +1,16 @@
 package permission
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"path/filepath"
 	"slices"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/google/uuid"
 	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/pubsub"
@@ -13,13 +18,20 @@ import (
 
 var ErrorPermissionDenied = errors.New("permission denied")
 
+// ErrPermissionTimeout is returned by RequestWithTimeout when nobody answered
+// the request in time.
+var ErrPermissionTimeout = errors.New("permission request timed out")
+
 type CreatePermissionRequest struct {
 	SessionID   string `json:"session_id"`
 	ToolName    string `json:"tool_name"`
 	Description string `json:"description"`
 	Action      string `json:"action"`
 	Params      any    `json:"params"`
-	Path        string `json:"path"`
+	// Path is the file the request is about, or the directory for requests
+	// that aren't about one file. Deny rules and glob grants are matched
+	// against it, literal grants against its directory.
+	Path string `json:"path"`
 }
 
 type PermissionRequest struct {
@@ -32,13 +44,30 @@ type PermissionRequest struct {
 	Path        string `json:"path"`
 }
 
+// DenyRule blocks matching requests regardless of any approval. An empty
+// field matches everything, but at least one of ToolName or Path must be set.
+// Path is either a directory, which covers everything below it, or a glob
+// pattern.
+type DenyRule struct {
+	ToolName string `json:"tool_name,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
 type Service interface {
 	pubsub.Suscriber[PermissionRequest]
 	GrantPersistant(permission PermissionRequest)
 	Grant(permission PermissionRequest)
 	Deny(permission PermissionRequest)
 	Request(opts CreatePermissionRequest) bool
+	RequestWithTimeout(opts CreatePermissionRequest, timeout time.Duration) (bool, error)
+	RequestBatch(reqs []CreatePermissionRequest) (map[string]bool, error)
 	AutoApproveSession(sessionID string)
+	AutoApproveTool(sessionID, toolName string)
+	Revoke(permission PermissionRequest)
+	RevokeSession(sessionID string)
+	AddDenyRule(rule DenyRule) error
+	AuditLog() []AuditEntry
+	SubscribeAudit(ctx context.Context, opts ...pubsub.SubscribeOption) <-chan pubsub.Event[AuditEntry]
 }
 
 type permissionService struct {
@@ -47,13 +76,35 @@ type permissionService struct {
 	sessionPermissions  []PermissionRequest
 	pendingRequests     sync.Map
 	autoApproveSessions []string
+	mu                  sync.RWMutex
+
+	autoApproveTools   map[string]map[string]struct{}
+	autoApproveToolsMu sync.RWMutex
+	// defaultAutoApproveTools are approved in every session; they come from
+	// the configured policy and are not affected by revocation.
+	defaultAutoApproveTools map[string]struct{}
+
+	denyRules   []DenyRule
+	denyRulesMu sync.RWMutex
+
+	auditLog    []AuditEntry
+	auditMu     sync.Mutex
+	auditBroker *pubsub.Broker[AuditEntry]
 }
 
+// GrantPersistant approves the request and every later one like it in the
+// session. Approving a batch grants each request in it persistently.
 func (s *permissionService) GrantPersistant(permission PermissionRequest) {
 	respCh, ok := s.pendingRequests.Load(permission.ID)
 	if ok {
 		respCh.(chan bool) <- true
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if batch, ok := permission.Params.(BatchPermissionParams); ok {
+		s.sessionPermissions = append(s.sessionPermissions, batch.Requests...)
+		return
+	}
 	s.sessionPermissions = append(s.sessionPermissions, permission)
 }
 
@@ -71,15 +122,145 @@ func (s *permissionService) Deny(permission PermissionRequest) {
 	}
 }
 
+// Request asks for permission and waits for an answer for as long as it
+// takes.
 func (s *permissionService) Request(opts CreatePermissionRequest) bool {
-	if slices.Contains(s.autoApproveSessions, opts.SessionID) {
-		return true
+	granted, _ := s.RequestWithTimeout(opts, 0)
+	return granted
+}
+
+// RequestWithTimeout asks for permission like Request, but if nobody answers
+// within timeout the request is withdrawn, with a DeletedEvent so prompts can
+// be dismissed, and it is denied with ErrPermissionTimeout. A timeout of zero
+// or less waits forever.
+func (s *permissionService) RequestWithTimeout(opts CreatePermissionRequest, timeout time.Duration) (bool, error) {
+	permission, granted, decided := s.preDecide(opts)
+	if decided {
+		return granted, nil
 	}
-	dir := filepath.Dir(opts.Path)
-	if dir == "." {
-		dir = config.WorkingDirectory()
+
+	resp, err := s.prompt(permission, timeout)
+	if err != nil {
+		s.recordDecision(opts, AuditTimedOut)
+		return false, err
+	}
+	s.recordUserDecision(opts, resp)
+	return resp, nil
+}
+
+// RequestBatch asks for several permissions at once, such as for a tool
+// changing many files. Each request is first checked against the deny rules,
+// auto-approvals and persistent grants on its own; the rest are put to the
+// user as one prompt whose answer applies to all of them. The outcomes are
+// keyed by request path, so the paths must be unique, and all requests must
+// belong to the same session.
+func (s *permissionService) RequestBatch(reqs []CreatePermissionRequest) (map[string]bool, error) {
+	results := make(map[string]bool, len(reqs))
+	for i, opts := range reqs {
+		if opts.SessionID != reqs[0].SessionID {
+			return nil, fmt.Errorf("batch permission requests span sessions %s and %s", reqs[0].SessionID, opts.SessionID)
+		}
+		for _, other := range reqs[:i] {
+			if other.Path == opts.Path {
+				return nil, fmt.Errorf("batch permission requests share the path %s", opts.Path)
+			}
+		}
+	}
+
+	var pending []CreatePermissionRequest
+	var prompts []PermissionRequest
+	for _, opts := range reqs {
+		permission, granted, decided := s.preDecide(opts)
+		if decided {
+			results[opts.Path] = granted
+			continue
+		}
+		pending = append(pending, opts)
+		prompts = append(prompts, permission)
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+	if len(pending) == 1 {
+		// no need to group a single prompt
+		resp, _ := s.prompt(prompts[0], 0)
+		s.recordUserDecision(pending[0], resp)
+		results[pending[0].Path] = resp
+		return results, nil
+	}
+
+	batch := PermissionRequest{
+		ID:        uuid.New().String(),
+		SessionID: reqs[0].SessionID,
+		Action:    batchAction,
+		Params:    BatchPermissionParams{Requests: prompts},
+	}
+	descriptions := make([]string, len(prompts))
+	for i, permission := range prompts {
+		descriptions[i] = "- " + permission.Description
+		if i == 0 || permission.ToolName == batch.ToolName {
+			batch.ToolName = permission.ToolName
+		} else {
+			batch.ToolName = ""
+		}
+		if i == 0 {
+			batch.Path = permission.Path
+		} else {
+			batch.Path = commonDir(batch.Path, permission.Path)
+		}
 	}
-	permission := PermissionRequest{
+	batch.Description = fmt.Sprintf("%d changes:\n%s", len(prompts), strings.Join(descriptions, "\n"))
+
+	resp, _ := s.prompt(batch, 0)
+	for _, opts := range pending {
+		s.recordUserDecision(opts, resp)
+		results[opts.Path] = resp
+	}
+	return results, nil
+}
+
+// AnyAction as the action of a persistent grant approves every action of
+// the grant's tool on its path.
+const AnyAction = "*"
+
+// batchAction is the action of a request grouping others.
+const batchAction = "batch"
+
+// BatchPermissionParams are the params of a request that groups several
+// requests into one decision.
+type BatchPermissionParams struct {
+	Requests []PermissionRequest `json:"requests"`
+}
+
+// commonDir returns the deepest directory containing both a and b.
+func commonDir(a, b string) string {
+	for a != b {
+		if len(a) < len(b) {
+			a, b = b, a
+		}
+		parent := filepath.Dir(a)
+		if parent == a {
+			return a
+		}
+		a = parent
+	}
+	return a
+}
+
+// preDecide builds the prompt for a request and settles it without asking
+// when a deny rule, auto-approval or persistent grant applies, recording that
+// decision.
+func (s *permissionService) preDecide(opts CreatePermissionRequest) (permission PermissionRequest, granted, decided bool) {
+	if s.isDenied(opts) {
+		s.recordDecision(opts, AuditDenied)
+		return PermissionRequest{}, false, true
+	}
+	if s.isSessionAutoApproved(opts.SessionID) || s.isToolAutoApproved(opts.SessionID, opts.ToolName) {
+		s.recordDecision(opts, AuditAutoApproved)
+		return PermissionRequest{}, true, true
+	}
+	dir := config.ResolvePath(filepath.Dir(opts.Path))
+	permission = PermissionRequest{
 		ID:          uuid.New().String(),
 		Path:        dir,
 		SessionID:   opts.SessionID,
@@ -88,13 +269,16 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 		Action:      opts.Action,
 		Params:      opts.Params,
 	}
-
-	for _, p := range s.sessionPermissions {
-		if p.ToolName == permission.ToolName && p.Action == permission.Action && p.SessionID == permission.SessionID && p.Path == permission.Path {
-			return true
-		}
+	if s.hasPersistentGrant(permission, opts.Path) {
+		s.recordDecision(opts, AuditPersistentGrant)
+		return permission, true, true
 	}
+	return permission, false, false
+}
 
+// prompt publishes the request and waits for the user's answer, or until the
+// timeout when there is one.
+func (s *permissionService) prompt(permission PermissionRequest, timeout time.Duration) (bool, error) {
 	respCh := make(chan bool, 1)
 
 	s.pendingRequests.Store(permission.ID, respCh)
@@ -102,18 +286,206 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 
 	s.Publish(pubsub.CreatedEvent, permission)
 
-	// Wait for the response with a timeout
-	resp := <-respCh
-	return resp
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case resp := <-respCh:
+		return resp, nil
+	case <-expired:
+		s.pendingRequests.Delete(permission.ID)
+		s.Publish(pubsub.DeletedEvent, permission)
+		return false, ErrPermissionTimeout
+	}
+}
+
+func (s *permissionService) recordUserDecision(opts CreatePermissionRequest, granted bool) {
+	if granted {
+		s.recordDecision(opts, AuditUserGranted)
+	} else {
+		s.recordDecision(opts, AuditDenied)
+	}
+}
+
+func (s *permissionService) hasPersistentGrant(permission PermissionRequest, path string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, p := range s.sessionPermissions {
+		if p.ToolName == permission.ToolName && actionMatches(p.Action, permission.Action) && p.SessionID == permission.SessionID && pathMatches(p.Path, permission.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// actionMatches reports whether a granted action covers a requested one:
+// AnyAction covers all of them, any other action only itself.
+func actionMatches(granted, requested string) bool {
+	return granted == AnyAction || granted == requested
+}
+
+// pathMatches reports whether a granted path covers a request. Literal grants
+// must equal the requested directory, while grants containing glob syntax are
+// matched with doublestar semantics against the directory or the full path.
+func pathMatches(granted, dir, path string) bool {
+	if granted == dir {
+		return true
+	}
+	if !strings.ContainsAny(granted, "*?[{") {
+		return false
+	}
+	for _, candidate := range []string{path, dir} {
+		if candidate == "" {
+			continue
+		}
+		if ok, err := doublestar.PathMatch(granted, candidate); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AddDenyRule registers a rule that rejects matching requests before any
+// auto-approval or persistent grant is considered.
+func (s *permissionService) AddDenyRule(rule DenyRule) error {
+	if rule.ToolName == "" && rule.Path == "" {
+		return errors.New("deny rule must set a tool name or a path")
+	}
+	if rule.Path != "" && !doublestar.ValidatePathPattern(rule.Path) {
+		return fmt.Errorf("invalid deny rule path pattern: %s", rule.Path)
+	}
+	s.denyRulesMu.Lock()
+	defer s.denyRulesMu.Unlock()
+	s.denyRules = append(s.denyRules, rule)
+	return nil
+}
+
+func (s *permissionService) isDenied(opts CreatePermissionRequest) bool {
+	s.denyRulesMu.RLock()
+	defer s.denyRulesMu.RUnlock()
+	for _, rule := range s.denyRules {
+		if rule.ToolName != "" && rule.ToolName != opts.ToolName {
+			continue
+		}
+		if rule.Path != "" && !denyPathMatches(rule.Path, opts.Path) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// denyPathMatches reports whether path is the denied path, lies below it, or
+// matches it as a glob pattern.
+func denyPathMatches(denied, path string) bool {
+	if path == "" {
+		return false
+	}
+	denied = filepath.Clean(denied)
+	path = filepath.Clean(path)
+	if path == denied || strings.HasPrefix(path, denied+string(filepath.Separator)) {
+		return true
+	}
+	ok, err := doublestar.PathMatch(denied, path)
+	return err == nil && ok
 }
 
 func (s *permissionService) AutoApproveSession(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.autoApproveSessions = append(s.autoApproveSessions, sessionID)
 }
 
+func (s *permissionService) isSessionAutoApproved(sessionID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Contains(s.autoApproveSessions, sessionID)
+}
+
+// AutoApproveTool approves every future request from the given tool in the
+// session without prompting, while other tools keep asking.
+func (s *permissionService) AutoApproveTool(sessionID, toolName string) {
+	s.autoApproveToolsMu.Lock()
+	defer s.autoApproveToolsMu.Unlock()
+	tools, ok := s.autoApproveTools[sessionID]
+	if !ok {
+		tools = make(map[string]struct{})
+		s.autoApproveTools[sessionID] = tools
+	}
+	tools[toolName] = struct{}{}
+}
+
+func (s *permissionService) isToolAutoApproved(sessionID, toolName string) bool {
+	if _, ok := s.defaultAutoApproveTools[toolName]; ok {
+		return true
+	}
+	s.autoApproveToolsMu.RLock()
+	defer s.autoApproveToolsMu.RUnlock()
+	_, ok := s.autoApproveTools[sessionID][toolName]
+	return ok
+}
+
+// Revoke removes the persistent grants matching the permission's session,
+// tool, action and path, along with any auto-approval of that tool in the
+// session, so that later requests prompt again.
+func (s *permissionService) Revoke(permission PermissionRequest) {
+	s.mu.Lock()
+	s.sessionPermissions = slices.DeleteFunc(s.sessionPermissions, func(p PermissionRequest) bool {
+		return p.SessionID == permission.SessionID &&
+			p.ToolName == permission.ToolName &&
+			p.Action == permission.Action &&
+			p.Path == permission.Path
+	})
+	s.mu.Unlock()
+
+	s.autoApproveToolsMu.Lock()
+	defer s.autoApproveToolsMu.Unlock()
+	delete(s.autoApproveTools[permission.SessionID], permission.ToolName)
+}
+
+// RevokeSession removes every persistent grant and auto-approval held by the
+// session.
+func (s *permissionService) RevokeSession(sessionID string) {
+	s.mu.Lock()
+	s.sessionPermissions = slices.DeleteFunc(s.sessionPermissions, func(p PermissionRequest) bool {
+		return p.SessionID == sessionID
+	})
+	s.autoApproveSessions = slices.DeleteFunc(s.autoApproveSessions, func(id string) bool {
+		return id == sessionID
+	})
+	s.mu.Unlock()
+
+	s.autoApproveToolsMu.Lock()
+	defer s.autoApproveToolsMu.Unlock()
+	delete(s.autoApproveTools, sessionID)
+}
+
 func NewPermissionService() Service {
 	return &permissionService{
 		Broker:             pubsub.NewBroker[PermissionRequest](),
 		sessionPermissions: make([]PermissionRequest, 0),
+		autoApproveTools:   make(map[string]map[string]struct{}),
+		auditBroker:        pubsub.NewBroker[AuditEntry](),
+
+		defaultAutoApproveTools: make(map[string]struct{}),
+	}
+}
+
+// NewPermissionServiceFromConfig creates a permission service seeded with the
+// configured policy: the listed tools are approved in every session and the
+// deny rules are installed up front.
+func NewPermissionServiceFromConfig(cfg config.PermissionsConfig) (Service, error) {
+	s := NewPermissionService().(*permissionService)
+	for _, tool := range cfg.AutoApproveTools {
+		s.defaultAutoApproveTools[tool] = struct{}{}
+	}
+	for _, rule := range cfg.Deny {
+		if err := s.AddDenyRule(DenyRule{ToolName: rule.Tool, Path: rule.Path}); err != nil {
+			return nil, err
+		}
 	}
+	return s, nil
 }
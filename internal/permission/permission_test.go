@@ -0,0 +1,434 @@
+package permission
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// respondWith answers the next permission prompt published by the service.
+func respondWith(t *testing.T, s Service, grant bool) <-chan PermissionRequest {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	events := s.Subscribe(ctx)
+	prompted := make(chan PermissionRequest, 1)
+	go func() {
+		select {
+		case event := <-events:
+			prompted <- event.Payload
+			if grant {
+				s.Grant(event.Payload)
+			} else {
+				s.Deny(event.Payload)
+			}
+		case <-ctx.Done():
+		}
+	}()
+	return prompted
+}
+
+func TestAutoApproveTool(t *testing.T) {
+	s := NewPermissionService()
+	s.AutoApproveTool("session", "view")
+
+	approved := s.Request(CreatePermissionRequest{
+		SessionID: "session",
+		ToolName:  "view",
+		Action:    "read",
+		Path:      "/project/main.go",
+	})
+	assert.True(t, approved)
+
+	prompted := respondWith(t, s, false)
+	approved = s.Request(CreatePermissionRequest{
+		SessionID: "session",
+		ToolName:  "write",
+		Action:    "write",
+		Path:      "/project/main.go",
+	})
+	assert.False(t, approved)
+
+	select {
+	case req := <-prompted:
+		assert.Equal(t, "write", req.ToolName)
+	case <-time.After(time.Second):
+		require.Fail(t, "write tool was not prompted")
+	}
+
+	// approval is scoped to the session it was granted for
+	prompted = respondWith(t, s, true)
+	approved = s.Request(CreatePermissionRequest{
+		SessionID: "other-session",
+		ToolName:  "view",
+		Action:    "read",
+		Path:      "/project/main.go",
+	})
+	assert.True(t, approved)
+	select {
+	case <-prompted:
+	case <-time.After(time.Second):
+		require.Fail(t, "view tool in another session was not prompted")
+	}
+}
+
+func TestPathMatches(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		granted string
+		path    string
+		want    bool
+	}{
+		{"literal directory", "/project/src", "/project/src/main.go", true},
+		{"literal different directory", "/project/src", "/project/src/pkg/util.go", false},
+		{"recursive go files", "/project/src/**/*.go", "/project/src/pkg/util.go", true},
+		{"recursive go files at root", "/project/src/**/*.go", "/project/src/main.go", true},
+		{"recursive pattern other extension", "/project/src/**/*.go", "/project/src/README.md", false},
+		{"recursive pattern outside tree", "/project/src/**/*.go", "/project/vendor/lib.go", false},
+		{"directory pattern", "/project/*", "/project/docs/guide.md", true},
+		{"single level pattern", "/project/*/*.md", "/project/docs/deep/guide.md", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Dir(tt.path)
+			assert.Equal(t, tt.want, pathMatches(tt.granted, dir, tt.path))
+		})
+	}
+}
+
+func TestRequestWithGlobGrant(t *testing.T) {
+	s := NewPermissionService()
+	s.GrantPersistant(PermissionRequest{
+		SessionID: "session",
+		ToolName:  "edit",
+		Action:    "write",
+		Path:      "/project/src/**/*.go",
+	})
+
+	approved := s.Request(CreatePermissionRequest{
+		SessionID: "session",
+		ToolName:  "edit",
+		Action:    "write",
+		Path:      "/project/src/pkg/util.go",
+	})
+	assert.True(t, approved)
+
+	prompted := respondWith(t, s, false)
+	approved = s.Request(CreatePermissionRequest{
+		SessionID: "session",
+		ToolName:  "edit",
+		Action:    "write",
+		Path:      "/project/src/README.md",
+	})
+	assert.False(t, approved)
+	select {
+	case <-prompted:
+	case <-time.After(time.Second):
+		require.Fail(t, "non-matching path was not prompted")
+	}
+}
+
+func TestRequestWithAnyActionGrant(t *testing.T) {
+	s := NewPermissionService()
+	s.GrantPersistant(PermissionRequest{
+		SessionID: "session",
+		ToolName:  "edit",
+		Action:    AnyAction,
+		Path:      "/project/src",
+	})
+
+	for _, action := range []string{"read", "write", "list"} {
+		approved := s.Request(CreatePermissionRequest{
+			SessionID: "session",
+			ToolName:  "edit",
+			Action:    action,
+			Path:      "/project/src/main.go",
+		})
+		assert.True(t, approved, action)
+	}
+
+	for _, opts := range []CreatePermissionRequest{
+		{SessionID: "session", ToolName: "bash", Action: "write", Path: "/project/src/main.go"},
+		{SessionID: "session", ToolName: "edit", Action: "write", Path: "/project/docs/README.md"},
+		{SessionID: "other", ToolName: "edit", Action: "write", Path: "/project/src/main.go"},
+	} {
+		prompted := respondWith(t, s, false)
+		assert.False(t, s.Request(opts))
+		select {
+		case <-prompted:
+		case <-time.After(time.Second):
+			require.Fail(t, "request outside the grant was not prompted", "%+v", opts)
+		}
+	}
+}
+
+func TestRequestWithSpecificActionGrant(t *testing.T) {
+	s := NewPermissionService()
+	s.GrantPersistant(PermissionRequest{
+		SessionID: "session",
+		ToolName:  "edit",
+		Action:    "read",
+		Path:      "/project/src",
+	})
+
+	assert.True(t, s.Request(CreatePermissionRequest{
+		SessionID: "session",
+		ToolName:  "edit",
+		Action:    "read",
+		Path:      "/project/src/main.go",
+	}))
+
+	for _, action := range []string{"write", AnyAction} {
+		prompted := respondWith(t, s, false)
+		assert.False(t, s.Request(CreatePermissionRequest{
+			SessionID: "session",
+			ToolName:  "edit",
+			Action:    action,
+			Path:      "/project/src/main.go",
+		}), action)
+		select {
+		case <-prompted:
+		case <-time.After(time.Second):
+			require.Fail(t, "a read grant approved another action", action)
+		}
+	}
+}
+
+func TestDenyRulesTakePrecedence(t *testing.T) {
+	s := NewPermissionService()
+	require.NoError(t, s.AddDenyRule(DenyRule{Path: "/etc"}))
+	require.NoError(t, s.AddDenyRule(DenyRule{ToolName: "rm"}))
+	require.NoError(t, s.AddDenyRule(DenyRule{ToolName: "write", Path: "/project/**/*.lock"}))
+	assert.Error(t, s.AddDenyRule(DenyRule{}))
+
+	s.AutoApproveSession("auto")
+	s.AutoApproveTool("tool", "write")
+	s.GrantPersistant(PermissionRequest{SessionID: "grant", ToolName: "write", Action: "write", Path: "/etc"})
+
+	denied := []CreatePermissionRequest{
+		{SessionID: "auto", ToolName: "view", Action: "read", Path: "/etc/passwd"},
+		{SessionID: "auto", ToolName: "rm", Action: "execute", Path: "/project/main.go"},
+		{SessionID: "tool", ToolName: "write", Action: "write", Path: "/project/deps/go.lock"},
+		{SessionID: "grant", ToolName: "write", Action: "write", Path: "/etc/hosts"},
+	}
+	for _, req := range denied {
+		assert.False(t, s.Request(req), "expected %s on %s to be denied", req.ToolName, req.Path)
+	}
+
+	allowed := []CreatePermissionRequest{
+		{SessionID: "auto", ToolName: "view", Action: "read", Path: "/etcetera/file"},
+		{SessionID: "tool", ToolName: "write", Action: "write", Path: "/project/main.go"},
+	}
+	for _, req := range allowed {
+		assert.True(t, s.Request(req), "expected %s on %s to be allowed", req.ToolName, req.Path)
+	}
+
+	log := s.AuditLog()
+	require.Len(t, log, len(denied)+len(allowed))
+	for i := range denied {
+		assert.Equal(t, AuditDenied, log[i].Decision)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	s := NewPermissionService()
+	req := CreatePermissionRequest{
+		SessionID: "session",
+		ToolName:  "write",
+		Action:    "write",
+		Path:      "/project/main.go",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := s.Subscribe(ctx)
+	granted := make(chan PermissionRequest, 1)
+	go func() {
+		event := <-events
+		s.GrantPersistant(event.Payload)
+		granted <- event.Payload
+	}()
+
+	require.True(t, s.Request(req))
+	permission := <-granted
+
+	// the persistent grant now covers the same request
+	assert.True(t, s.Request(req))
+
+	s.Revoke(permission)
+	prompted := respondWith(t, s, false)
+	assert.False(t, s.Request(req))
+	select {
+	case <-prompted:
+	case <-time.After(time.Second):
+		require.Fail(t, "revoked permission was not prompted")
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	s := NewPermissionService()
+	s.AutoApproveSession("session")
+	s.AutoApproveTool("session", "view")
+	s.GrantPersistant(PermissionRequest{SessionID: "session", ToolName: "write", Action: "write", Path: "/project"})
+	s.AutoApproveSession("other")
+
+	s.RevokeSession("session")
+
+	for _, req := range []CreatePermissionRequest{
+		{SessionID: "session", ToolName: "view", Action: "read", Path: "/project/main.go"},
+		{SessionID: "session", ToolName: "write", Action: "write", Path: "/project/main.go"},
+	} {
+		prompted := respondWith(t, s, false)
+		assert.False(t, s.Request(req))
+		select {
+		case <-prompted:
+		case <-time.After(time.Second):
+			require.Fail(t, "revoked session was not prompted", req.ToolName)
+		}
+	}
+
+	assert.True(t, s.Request(CreatePermissionRequest{SessionID: "other", ToolName: "view", Action: "read", Path: "/project/main.go"}))
+}
+
+func TestNewPermissionServiceFromConfig(t *testing.T) {
+	s, err := NewPermissionServiceFromConfig(config.PermissionsConfig{
+		AutoApproveTools: []string{"view"},
+		Deny:             []config.PermissionDenyRule{{Tool: "bash"}},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, s.Request(CreatePermissionRequest{SessionID: "a", ToolName: "view", Action: "read", Path: "/project/main.go"}))
+	assert.True(t, s.Request(CreatePermissionRequest{SessionID: "b", ToolName: "view", Action: "read", Path: "/project/main.go"}))
+	assert.False(t, s.Request(CreatePermissionRequest{SessionID: "a", ToolName: "bash", Action: "execute", Path: "/project"}))
+
+	_, err = NewPermissionServiceFromConfig(config.PermissionsConfig{
+		Deny: []config.PermissionDenyRule{{}},
+	})
+	assert.Error(t, err)
+}
+
+func TestRequestWithTimeout(t *testing.T) {
+	req := CreatePermissionRequest{
+		SessionID: "session",
+		ToolName:  "bash",
+		Action:    "execute",
+		Path:      "/project",
+	}
+
+	t.Run("nobody answers", func(t *testing.T) {
+		s := NewPermissionService()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events := s.Subscribe(ctx)
+
+		granted, err := s.RequestWithTimeout(req, 20*time.Millisecond)
+		assert.False(t, granted)
+		assert.ErrorIs(t, err, ErrPermissionTimeout)
+
+		created := <-events
+		withdrawn := <-events
+		assert.Equal(t, pubsub.CreatedEvent, created.Type)
+		assert.Equal(t, pubsub.DeletedEvent, withdrawn.Type)
+		assert.Equal(t, created.Payload.ID, withdrawn.Payload.ID)
+
+		// answering a withdrawn request does nothing
+		s.Grant(withdrawn.Payload)
+
+		log := s.AuditLog()
+		require.Len(t, log, 1)
+		assert.Equal(t, AuditTimedOut, log[0].Decision)
+	})
+
+	t.Run("answered in time", func(t *testing.T) {
+		s := NewPermissionService()
+		respondWith(t, s, true)
+		granted, err := s.RequestWithTimeout(req, 5*time.Second)
+		assert.NoError(t, err)
+		assert.True(t, granted)
+	})
+
+	t.Run("decided without prompting", func(t *testing.T) {
+		s := NewPermissionService()
+		s.AutoApproveSession("session")
+		granted, err := s.RequestWithTimeout(req, time.Nanosecond)
+		assert.NoError(t, err)
+		assert.True(t, granted)
+	})
+}
+
+func TestRequestBatch(t *testing.T) {
+	s := NewPermissionService()
+	require.NoError(t, s.AddDenyRule(DenyRule{Path: "/project/.env"}))
+	s.GrantPersistant(PermissionRequest{SessionID: "session", ToolName: "patch", Action: "update", Path: "/project/docs"})
+
+	edit := func(path string) CreatePermissionRequest {
+		return CreatePermissionRequest{
+			SessionID:   "session",
+			ToolName:    "patch",
+			Action:      "update",
+			Description: "Update file " + path,
+			Path:        path,
+		}
+	}
+	reqs := []CreatePermissionRequest{
+		edit("/project/.env"),
+		edit("/project/docs/README.md"),
+		edit("/project/cmd/main.go"),
+		edit("/project/internal/app.go"),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := s.Subscribe(ctx)
+	prompted := make(chan PermissionRequest, 1)
+	go func() {
+		event := <-events
+		prompted <- event.Payload
+		s.GrantPersistant(event.Payload)
+	}()
+
+	results, err := s.RequestBatch(reqs)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{
+		"/project/.env":            false,
+		"/project/docs/README.md":  true,
+		"/project/cmd/main.go":     true,
+		"/project/internal/app.go": true,
+	}, results)
+
+	batch := <-prompted
+	assert.Equal(t, "patch", batch.ToolName)
+	assert.Equal(t, "/project", batch.Path)
+	assert.Equal(t, "2 changes:\n- Update file /project/cmd/main.go\n- Update file /project/internal/app.go", batch.Description)
+	params, ok := batch.Params.(BatchPermissionParams)
+	require.True(t, ok)
+	assert.Len(t, params.Requests, 2)
+
+	// approving the batch for the session granted each of its requests
+	assert.True(t, s.Request(edit("/project/cmd/other.go")))
+
+	log := s.AuditLog()
+	require.Len(t, log, 5)
+	assert.Equal(t, []AuditDecision{AuditDenied, AuditPersistentGrant, AuditUserGranted, AuditUserGranted, AuditPersistentGrant},
+		[]AuditDecision{log[0].Decision, log[1].Decision, log[2].Decision, log[3].Decision, log[4].Decision})
+
+	t.Run("invalid batches", func(t *testing.T) {
+		_, err := s.RequestBatch([]CreatePermissionRequest{edit("/a"), edit("/a")})
+		assert.ErrorContains(t, err, "share the path /a")
+
+		other := edit("/b")
+		other.SessionID = "other"
+		_, err = s.RequestBatch([]CreatePermissionRequest{edit("/a"), other})
+		assert.ErrorContains(t, err, "span sessions")
+	})
+}
@@ -3,16 +3,105 @@ package pubsub
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 const bufferSize = 64
 
+// DeliveryPolicy decides what Publish does when a subscriber's buffer is full.
+type DeliveryPolicy int
+
+const (
+	// DeliveryDrop discards the event for that subscriber. This is the default.
+	DeliveryDrop DeliveryPolicy = iota
+	// DeliveryBlock waits for room in the buffer, for subscribers that must
+	// not miss events. A slow subscriber slows down every publisher.
+	DeliveryBlock
+	// DeliveryLatestOnly keeps only the most recent undelivered event, for
+	// subscribers that only care about the current state.
+	DeliveryLatestOnly
+)
+
+// SubscribeOption configures a single subscription.
+type SubscribeOption func(*subscribeConfig)
+
+type subscribeConfig struct {
+	policy DeliveryPolicy
+}
+
+// WithDeliveryPolicy sets what happens to events the subscriber can't keep up
+// with.
+func WithDeliveryPolicy(policy DeliveryPolicy) SubscribeOption {
+	return func(c *subscribeConfig) {
+		c.policy = policy
+	}
+}
+
+type subscription[T any] struct {
+	ch     chan Event[T]
+	policy DeliveryPolicy
+
+	// mu serializes sends with closing the channel
+	mu     sync.Mutex
+	closed bool
+	// done is closed first when unsubscribing, to release a blocked send
+	done chan struct{}
+}
+
+func (s *subscription[T]) deliver(event Event[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+
+	switch s.policy {
+	case DeliveryBlock:
+		select {
+		case s.ch <- event:
+		case <-s.done:
+		}
+	case DeliveryLatestOnly:
+		select {
+		case s.ch <- event:
+		default:
+			// replace the undelivered event with this one
+			select {
+			case <-s.ch:
+			default:
+			}
+			select {
+			case s.ch <- event:
+			default:
+			}
+		}
+	default:
+		select {
+		case s.ch <- event:
+		default:
+		}
+	}
+}
+
+func (s *subscription[T]) close() {
+	close(s.done)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	close(s.ch)
+}
+
 type Broker[T any] struct {
-	subs      map[chan Event[T]]struct{}
-	mu        sync.RWMutex
-	done      chan struct{}
-	subCount  int
-	maxEvents int
+	subs map[*subscription[T]]struct{}
+	mu   sync.RWMutex
+	// publishMu serializes fan-out, so every subscriber receives events in
+	// the same order they were published in
+	publishMu  sync.Mutex
+	done       chan struct{}
+	inflight   sync.WaitGroup
+	subCount   int
+	maxEvents  int
+	bufferSize int
 }
 
 func NewBroker[T any]() *Broker[T] {
@@ -21,15 +110,19 @@ func NewBroker[T any]() *Broker[T] {
 
 func NewBrokerWithOptions[T any](channelBufferSize, maxEvents int) *Broker[T] {
 	b := &Broker[T]{
-		subs:      make(map[chan Event[T]]struct{}),
-		done:      make(chan struct{}),
-		subCount:  0,
-		maxEvents: maxEvents,
+		subs:       make(map[*subscription[T]]struct{}),
+		done:       make(chan struct{}),
+		subCount:   0,
+		maxEvents:  maxEvents,
+		bufferSize: channelBufferSize,
 	}
 	return b
 }
 
 func (b *Broker[T]) Shutdown() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	select {
 	case <-b.done: // Already closed
 		return
@@ -37,18 +130,90 @@ func (b *Broker[T]) Shutdown() {
 		close(b.done)
 	}
 
+	b.closeSubscriptions()
+}
+
+// Drain shuts the broker down without losing events: it stops accepting new
+// publishes, waits until subscribers have received everything already
+// published, then closes their channels. If ctx ends first, whatever is still
+// undelivered is dropped and ctx's error is returned.
+func (b *Broker[T]) Drain(ctx context.Context) error {
+	b.mu.Lock()
+	select {
+	case <-b.done: // Already closed
+		b.mu.Unlock()
+		return nil
+	default:
+		close(b.done)
+	}
+	subscribers := make([]*subscription[T], 0, len(b.subs))
+	for sub := range b.subs {
+		subscribers = append(subscribers, sub)
+	}
+	b.mu.Unlock()
+
+	err := b.waitDelivered(ctx, subscribers)
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	b.closeSubscriptions()
+	return err
+}
 
-	for ch := range b.subs {
-		delete(b.subs, ch)
-		close(ch)
+// drainPollInterval is how often Drain checks whether subscribers have caught
+// up.
+const drainPollInterval = 5 * time.Millisecond
+
+func (b *Broker[T]) waitDelivered(ctx context.Context, subscribers []*subscription[T]) error {
+	published := make(chan struct{})
+	go func() {
+		b.inflight.Wait()
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		pending := false
+		for _, sub := range subscribers {
+			if len(sub.ch) > 0 {
+				pending = true
+				break
+			}
+		}
+		if !pending {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// closeSubscriptions closes every subscriber channel. The caller must hold
+// b.mu.
+func (b *Broker[T]) closeSubscriptions() {
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		sub.close()
 	}
 
 	b.subCount = 0
 }
 
-func (b *Broker[T]) Subscribe(ctx context.Context) <-chan Event[T] {
+func (b *Broker[T]) Subscribe(ctx context.Context, opts ...SubscribeOption) <-chan Event[T] {
+	config := subscribeConfig{policy: DeliveryDrop}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
@@ -60,28 +225,38 @@ func (b *Broker[T]) Subscribe(ctx context.Context) <-chan Event[T] {
 	default:
 	}
 
-	sub := make(chan Event[T], bufferSize)
+	size := b.bufferSize
+	if config.policy == DeliveryLatestOnly {
+		size = 1
+	}
+	sub := &subscription[T]{
+		ch:     make(chan Event[T], size),
+		policy: config.policy,
+		done:   make(chan struct{}),
+	}
 	b.subs[sub] = struct{}{}
 	b.subCount++
 
 	go func() {
-		<-ctx.Done()
+		select {
+		case <-ctx.Done():
+		case <-b.done:
+			// the broker closes its subscriptions itself
+			return
+		}
 
 		b.mu.Lock()
 		defer b.mu.Unlock()
 
-		select {
-		case <-b.done:
+		if _, ok := b.subs[sub]; !ok {
 			return
-		default:
 		}
-
 		delete(b.subs, sub)
-		close(sub)
+		sub.close()
 		b.subCount--
 	}()
 
-	return sub
+	return sub.ch
 }
 
 func (b *Broker[T]) GetSubscriberCount() int {
@@ -90,7 +265,15 @@ func (b *Broker[T]) GetSubscriberCount() int {
 	return b.subCount
 }
 
+// Publish delivers an event to every subscriber. Publishes are fanned out one
+// at a time, so events published by a single goroutine reach each subscriber
+// in publish order, and events from concurrent publishers reach all
+// subscribers in the same order. Subscribers may still miss events, as their
+// delivery policy allows.
 func (b *Broker[T]) Publish(t EventType, payload T) {
+	b.publishMu.Lock()
+	defer b.publishMu.Unlock()
+
 	b.mu.RLock()
 	select {
 	case <-b.done:
@@ -99,18 +282,17 @@ func (b *Broker[T]) Publish(t EventType, payload T) {
 	default:
 	}
 
-	subscribers := make([]chan Event[T], 0, len(b.subs))
+	subscribers := make([]*subscription[T], 0, len(b.subs))
 	for sub := range b.subs {
 		subscribers = append(subscribers, sub)
 	}
+	b.inflight.Add(1)
+	defer b.inflight.Done()
 	b.mu.RUnlock()
 
 	event := Event[T]{Type: t, Payload: payload}
 
 	for _, sub := range subscribers {
-		select {
-		case sub <- event:
-		default:
-		}
+		sub.deliver(event)
 	}
 }
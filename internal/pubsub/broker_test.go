@@ -0,0 +1,221 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveAll[T any](ch <-chan Event[T]) []T {
+	var payloads []T
+	for {
+		select {
+		case event := <-ch:
+			payloads = append(payloads, event.Payload)
+		default:
+			return payloads
+		}
+	}
+}
+
+func TestDeliveryPolicies(t *testing.T) {
+	t.Parallel()
+
+	t.Run("drop discards events once the buffer is full", func(t *testing.T) {
+		b := NewBrokerWithOptions[int](2, 100)
+		defer b.Shutdown()
+		ch := b.Subscribe(context.Background())
+
+		for i := range 5 {
+			b.Publish(CreatedEvent, i)
+		}
+		assert.Equal(t, []int{0, 1}, receiveAll(ch))
+	})
+
+	t.Run("latest only keeps the newest event", func(t *testing.T) {
+		b := NewBroker[int]()
+		defer b.Shutdown()
+		ch := b.Subscribe(context.Background(), WithDeliveryPolicy(DeliveryLatestOnly))
+
+		for i := range 5 {
+			b.Publish(CreatedEvent, i)
+		}
+		assert.Equal(t, []int{4}, receiveAll(ch))
+
+		b.Publish(UpdatedEvent, 5)
+		assert.Equal(t, []int{5}, receiveAll(ch))
+	})
+
+	t.Run("block waits for the subscriber", func(t *testing.T) {
+		b := NewBrokerWithOptions[int](1, 100)
+		defer b.Shutdown()
+		ch := b.Subscribe(context.Background(), WithDeliveryPolicy(DeliveryBlock))
+
+		published := make(chan struct{})
+		go func() {
+			defer close(published)
+			for i := range 5 {
+				b.Publish(CreatedEvent, i)
+			}
+		}()
+
+		var got []int
+		for range 5 {
+			select {
+			case event := <-ch:
+				got = append(got, event.Payload)
+			case <-time.After(time.Second):
+				t.Fatal("timed out waiting for event")
+			}
+		}
+		<-published
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, got)
+	})
+
+	t.Run("block is released when the subscriber leaves", func(t *testing.T) {
+		b := NewBrokerWithOptions[int](1, 100)
+		defer b.Shutdown()
+		ctx, cancel := context.WithCancel(context.Background())
+		b.Subscribe(ctx, WithDeliveryPolicy(DeliveryBlock))
+
+		published := make(chan struct{})
+		go func() {
+			defer close(published)
+			b.Publish(CreatedEvent, 1)
+			b.Publish(CreatedEvent, 2)
+		}()
+		cancel()
+
+		select {
+		case <-published:
+		case <-time.After(time.Second):
+			t.Fatal("publish stayed blocked after the subscriber left")
+		}
+		require.Eventually(t, func() bool { return b.GetSubscriberCount() == 0 }, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestDrain(t *testing.T) {
+	t.Parallel()
+
+	t.Run("delivers buffered events before closing", func(t *testing.T) {
+		b := NewBroker[int]()
+		ch := b.Subscribe(context.Background())
+		for i := range 5 {
+			b.Publish(CreatedEvent, i)
+		}
+
+		drained := make(chan error, 1)
+		go func() { drained <- b.Drain(context.Background()) }()
+
+		var got []int
+		for event := range ch {
+			got = append(got, event.Payload)
+		}
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, got)
+		require.NoError(t, <-drained)
+
+		// nothing is accepted after draining
+		b.Publish(CreatedEvent, 5)
+		_, ok := <-b.Subscribe(context.Background())
+		assert.False(t, ok)
+	})
+
+	t.Run("gives up at the deadline", func(t *testing.T) {
+		b := NewBroker[int]()
+		ch := b.Subscribe(context.Background())
+		b.Publish(CreatedEvent, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, b.Drain(ctx), context.DeadlineExceeded)
+
+		assert.Equal(t, 0, b.GetSubscriberCount())
+		<-ch // the undelivered event is still buffered
+		_, ok := <-ch
+		assert.False(t, ok)
+	})
+}
+
+func TestPublishOrder(t *testing.T) {
+	t.Parallel()
+
+	t.Run("a single publisher's events arrive in order", func(t *testing.T) {
+		b := NewBrokerWithOptions[int](4, 100)
+		defer b.Shutdown()
+		subscribers := []<-chan Event[int]{
+			b.Subscribe(context.Background(), WithDeliveryPolicy(DeliveryBlock)),
+			b.Subscribe(context.Background(), WithDeliveryPolicy(DeliveryBlock)),
+		}
+
+		const count = 1000
+		go func() {
+			for i := range count {
+				b.Publish(CreatedEvent, i)
+			}
+		}()
+
+		// subscribers are read concurrently, as a blocked one holds up the rest
+		received := make([][]int, len(subscribers))
+		var wg sync.WaitGroup
+		for i, ch := range subscribers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for len(received[i]) < count {
+					select {
+					case event := <-ch:
+						received[i] = append(received[i], event.Payload)
+					case <-time.After(time.Second):
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		for _, got := range received {
+			require.Len(t, got, count)
+			for i, v := range got {
+				require.Equal(t, i, v)
+			}
+		}
+	})
+
+	t.Run("concurrent publishers are seen in the same order", func(t *testing.T) {
+		const publishers, perPublisher = 4, 250
+		b := NewBrokerWithOptions[int](publishers*perPublisher, 100)
+		defer b.Shutdown()
+		first := b.Subscribe(context.Background(), WithDeliveryPolicy(DeliveryBlock))
+		second := b.Subscribe(context.Background(), WithDeliveryPolicy(DeliveryBlock))
+
+		var wg sync.WaitGroup
+		for p := range publishers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range perPublisher {
+					b.Publish(CreatedEvent, p*perPublisher+i)
+				}
+			}()
+		}
+		wg.Wait()
+
+		got := receiveAll(first)
+		assert.Len(t, got, publishers*perPublisher)
+		assert.Equal(t, got, receiveAll(second))
+		for p := range publishers {
+			last := -1
+			for _, v := range got {
+				if v/perPublisher == p {
+					assert.Greater(t, v, last, "publisher %d out of order", p)
+					last = v
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,279 @@
+package pubsub
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxFrameSize bounds a single event on the wire, so a corrupt length prefix
+// can't make a reader allocate without limit.
+const maxFrameSize = 16 << 20
+
+// peerWriteTimeout bounds how long sending one event to a peer may take, so a
+// process that stops reading is dropped instead of blocking every Publish.
+const peerWriteTimeout = 5 * time.Second
+
+// NetworkBroker is a Broker whose events are also shared with other processes
+// over a Unix socket. The first process to open the socket listens on it and
+// relays events between every process connected to it; later ones connect to
+// it. Events published in any process reach subscribers in all of them.
+//
+// When the socket can't be used, or the connection to it is lost, the broker
+// keeps working in-process only.
+type NetworkBroker[T any] struct {
+	*Broker[T]
+	addr         string
+	writeTimeout time.Duration
+
+	mu       sync.Mutex
+	listener net.Listener // set when this process relays for the others
+	peers    map[*networkPeer]struct{}
+	closed   bool
+}
+
+// networkPeer is a connection to another process.
+type networkPeer struct {
+	conn net.Conn
+	// writeMu keeps frames written by concurrent publishers whole
+	writeMu sync.Mutex
+}
+
+// networkEvent is an event as it is framed on the socket.
+type networkEvent[T any] struct {
+	Type    EventType `json:"type"`
+	Payload T         `json:"payload"`
+}
+
+// NewNetworkBroker returns a broker that shares its events with other
+// processes through the Unix socket at addr, listening on it when no other
+// process does.
+func NewNetworkBroker[T any](addr string) *NetworkBroker[T] {
+	b := &NetworkBroker[T]{
+		Broker:       NewBroker[T](),
+		addr:         addr,
+		writeTimeout: peerWriteTimeout,
+		peers:        make(map[*networkPeer]struct{}),
+	}
+	if err := b.open(); err != nil {
+		slog.Warn("event socket unavailable, sharing events in-process only", "addr", addr, "error", err)
+	}
+	return b
+}
+
+// open connects to the process listening on the socket, or listens on it
+// when there is none. A socket file left behind by a process that exited is
+// replaced.
+func (b *NetworkBroker[T]) open() error {
+	if conn, err := net.Dial("unix", b.addr); err == nil {
+		b.addPeer(conn)
+		return nil
+	}
+	listener, err := net.Listen("unix", b.addr)
+	if err != nil {
+		if _, statErr := os.Stat(b.addr); statErr != nil {
+			return err
+		}
+		// nothing answered on the existing socket, so it is stale
+		if removeErr := os.Remove(b.addr); removeErr != nil {
+			return err
+		}
+		if listener, err = net.Listen("unix", b.addr); err != nil {
+			return err
+		}
+	}
+
+	b.mu.Lock()
+	b.listener = listener
+	b.mu.Unlock()
+	go b.accept(listener)
+	return nil
+}
+
+// Networked reports whether events are currently shared with other processes
+// or could be, because this process listens for them.
+func (b *NetworkBroker[T]) Networked() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.listener != nil || len(b.peers) > 0
+}
+
+func (b *NetworkBroker[T]) accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		b.addPeer(conn)
+	}
+}
+
+func (b *NetworkBroker[T]) addPeer(conn net.Conn) {
+	peer := &networkPeer{conn: conn}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		conn.Close()
+		return
+	}
+	b.peers[peer] = struct{}{}
+	b.mu.Unlock()
+	go b.read(peer)
+}
+
+func (b *NetworkBroker[T]) removePeer(peer *networkPeer) {
+	b.mu.Lock()
+	_, ok := b.peers[peer]
+	delete(b.peers, peer)
+	lost := ok && !b.closed && b.listener == nil
+	b.mu.Unlock()
+
+	peer.conn.Close()
+	if lost {
+		slog.Warn("lost the event socket, sharing events in-process only", "addr", b.addr)
+	}
+}
+
+// read publishes the events received from peer locally and, when this process
+// relays, passes them on to the other peers.
+func (b *NetworkBroker[T]) read(peer *networkPeer) {
+	defer b.removePeer(peer)
+	r := bufio.NewReader(peer.conn)
+	for {
+		frame, err := readFrame(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				slog.Warn("failed to read from the event socket", "addr", b.addr, "error", err)
+			}
+			return
+		}
+		var event networkEvent[T]
+		if err := json.Unmarshal(frame, &event); err != nil {
+			slog.Warn("skipping malformed event from the event socket", "addr", b.addr, "error", err)
+			continue
+		}
+		b.Broker.Publish(event.Type, event.Payload)
+
+		b.mu.Lock()
+		relay := b.listener != nil
+		b.mu.Unlock()
+		if relay {
+			b.send(frame, peer)
+		}
+	}
+}
+
+// Publish delivers an event to the subscribers in this process and sends it
+// to the other processes.
+func (b *NetworkBroker[T]) Publish(t EventType, payload T) {
+	b.Broker.Publish(t, payload)
+
+	frame, err := json.Marshal(networkEvent[T]{Type: t, Payload: payload})
+	if err != nil {
+		slog.Warn("failed to encode event for the event socket", "addr", b.addr, "error", err)
+		return
+	}
+	b.send(frame, nil)
+}
+
+// send writes frame to every peer except the one it came from. Peers that
+// can't be written to, or don't take the frame within the write timeout, are
+// dropped.
+func (b *NetworkBroker[T]) send(frame []byte, from *networkPeer) {
+	b.mu.Lock()
+	peers := make([]*networkPeer, 0, len(b.peers))
+	for peer := range b.peers {
+		if peer != from {
+			peers = append(peers, peer)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, peer := range peers {
+		peer.writeMu.Lock()
+		err := peer.conn.SetWriteDeadline(time.Now().Add(b.writeTimeout))
+		if err == nil {
+			err = writeFrame(peer.conn, frame)
+		}
+		peer.writeMu.Unlock()
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				slog.Warn("dropping a process that stopped reading events", "addr", b.addr)
+			}
+			b.removePeer(peer)
+		}
+	}
+}
+
+// Shutdown disconnects from the other processes and shuts the broker down.
+func (b *NetworkBroker[T]) Shutdown() {
+	b.closeNetwork()
+	b.Broker.Shutdown()
+}
+
+// Drain disconnects from the other processes, then drains the broker.
+func (b *NetworkBroker[T]) Drain(ctx context.Context) error {
+	b.closeNetwork()
+	return b.Broker.Drain(ctx)
+}
+
+func (b *NetworkBroker[T]) closeNetwork() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	listener := b.listener
+	b.listener = nil
+	peers := b.peers
+	b.peers = make(map[*networkPeer]struct{})
+	b.mu.Unlock()
+
+	if listener != nil {
+		// closing a Unix listener also removes its socket file
+		listener.Close()
+	}
+	for peer := range peers {
+		peer.conn.Close()
+	}
+}
+
+// writeFrame writes data prefixed with its length as a 4-byte big-endian
+// integer.
+func writeFrame(w io.Writer, data []byte) error {
+	if len(data) > maxFrameSize {
+		return fmt.Errorf("event of %d bytes exceeds the %d byte limit", len(data), maxFrameSize)
+	}
+	frame := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	copy(frame[4:], data)
+	_, err := w.Write(frame)
+	return err
+}
+
+// readFrame reads one frame written by writeFrame.
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return nil, fmt.Errorf("event of %d bytes exceeds the %d byte limit", size, maxFrameSize)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,184 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPayload struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// socketPath returns a socket path short enough for the platform's limit.
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "pubsub")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "events.sock")
+}
+
+func peerCount[T any](b *NetworkBroker[T]) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.peers)
+}
+
+func receive[T any](t *testing.T, ch <-chan Event[T]) Event[T] {
+	t.Helper()
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for an event")
+		return Event[T]{}
+	}
+}
+
+func TestNetworkBroker(t *testing.T) {
+	t.Parallel()
+
+	addr := socketPath(t)
+	hub := NewNetworkBroker[testPayload](addr)
+	defer hub.Shutdown()
+	first := NewNetworkBroker[testPayload](addr)
+	defer first.Shutdown()
+	second := NewNetworkBroker[testPayload](addr)
+	defer second.Shutdown()
+	require.Eventually(t, func() bool { return peerCount(hub) == 2 }, 2*time.Second, 5*time.Millisecond)
+	require.True(t, hub.Networked())
+	require.True(t, first.Networked())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hubEvents := hub.Subscribe(ctx)
+	firstEvents := first.Subscribe(ctx)
+	secondEvents := second.Subscribe(ctx)
+
+	first.Publish(UpdatedEvent, testPayload{Name: "from first", Count: 1})
+	for _, ch := range []<-chan Event[testPayload]{hubEvents, firstEvents, secondEvents} {
+		event := receive(t, ch)
+		assert.Equal(t, UpdatedEvent, event.Type)
+		assert.Equal(t, testPayload{Name: "from first", Count: 1}, event.Payload)
+	}
+
+	hub.Publish(CreatedEvent, testPayload{Name: "from hub"})
+	for _, ch := range []<-chan Event[testPayload]{hubEvents, firstEvents, secondEvents} {
+		assert.Equal(t, "from hub", receive(t, ch).Payload.Name)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, receiveAll(firstEvents), "events aren't echoed back to the publisher")
+	assert.Empty(t, receiveAll(hubEvents))
+}
+
+func TestNetworkBrokerInProcessFallback(t *testing.T) {
+	t.Parallel()
+
+	t.Run("socket unavailable", func(t *testing.T) {
+		b := NewNetworkBroker[testPayload](filepath.Join(t.TempDir(), "missing", "events.sock"))
+		defer b.Shutdown()
+		assert.False(t, b.Networked())
+
+		events := b.Subscribe(context.Background())
+		b.Publish(CreatedEvent, testPayload{Name: "local"})
+		assert.Equal(t, "local", receive(t, events).Payload.Name)
+	})
+
+	t.Run("stale socket file is replaced", func(t *testing.T) {
+		addr := socketPath(t)
+		listener, err := net.Listen("unix", addr)
+		require.NoError(t, err)
+		listener.(*net.UnixListener).SetUnlinkOnClose(false)
+		listener.Close()
+		require.FileExists(t, addr)
+
+		b := NewNetworkBroker[testPayload](addr)
+		defer b.Shutdown()
+		assert.True(t, b.Networked())
+	})
+
+	t.Run("relay goes away", func(t *testing.T) {
+		addr := socketPath(t)
+		hub := NewNetworkBroker[testPayload](addr)
+		client := NewNetworkBroker[testPayload](addr)
+		defer client.Shutdown()
+		require.Eventually(t, func() bool { return peerCount(hub) == 1 }, 2*time.Second, 5*time.Millisecond)
+
+		hub.Shutdown()
+		require.Eventually(t, func() bool { return !client.Networked() }, 2*time.Second, 5*time.Millisecond)
+
+		events := client.Subscribe(context.Background())
+		client.Publish(CreatedEvent, testPayload{Name: "still local"})
+		assert.Equal(t, "still local", receive(t, events).Payload.Name)
+	})
+}
+
+func TestNetworkBrokerDropsPeerThatStopsReading(t *testing.T) {
+	t.Parallel()
+
+	addr := socketPath(t)
+	hub := NewNetworkBroker[testPayload](addr)
+	defer hub.Shutdown()
+	hub.writeTimeout = 50 * time.Millisecond
+	client := NewNetworkBroker[testPayload](addr)
+	defer client.Shutdown()
+	stuck, err := net.Dial("unix", addr)
+	require.NoError(t, err)
+	defer stuck.Close()
+	require.Eventually(t, func() bool { return peerCount(hub) == 2 }, 2*time.Second, 5*time.Millisecond)
+
+	// publish until the stuck peer's socket buffer fills and it is dropped
+	large := testPayload{Name: string(bytes.Repeat([]byte("x"), 64<<10))}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for peerCount(hub) == 2 {
+			hub.Publish(UpdatedEvent, large)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "publishing blocked on a peer that doesn't read")
+	}
+	assert.Equal(t, 1, peerCount(hub))
+
+	events := client.Subscribe(context.Background())
+	hub.Publish(CreatedEvent, testPayload{Name: "after"})
+	for {
+		if event := receive(t, events); event.Type == CreatedEvent {
+			assert.Equal(t, "after", event.Payload.Name)
+			break
+		}
+	}
+}
+
+func TestFrames(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, writeFrame(&buf, []byte(`{"a":1}`)))
+	require.NoError(t, writeFrame(&buf, nil))
+	assert.Equal(t, []byte{0, 0, 0, 7}, buf.Bytes()[:4])
+
+	frame, err := readFrame(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(frame))
+	frame, err = readFrame(&buf)
+	require.NoError(t, err)
+	assert.Empty(t, frame)
+
+	assert.Error(t, writeFrame(&buf, make([]byte, maxFrameSize+1)))
+	_, err = readFrame(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff}))
+	assert.ErrorContains(t, err, "exceeds")
+}
@@ -0,0 +1,26 @@
+package session
+
+import (
+	"context"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+)
+
+// ShouldCompact reports whether the session's prompt tokens have reached the
+// configured fraction of the model's context window, at which point older
+// messages should be summarized.
+func (s *service) ShouldCompact(ctx context.Context, id string, model models.Model) (bool, error) {
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if model.ContextWindow <= 0 {
+		return false, nil
+	}
+	threshold := config.DefaultAutoCompactThreshold
+	if cfg := config.Get(); cfg != nil && cfg.AutoCompactThreshold > 0 {
+		threshold = cfg.AutoCompactThreshold
+	}
+	return float64(session.PromptTokens) >= float64(model.ContextWindow)*threshold, nil
+}
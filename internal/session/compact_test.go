@@ -0,0 +1,47 @@
+package session
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldCompact(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	original := config.Get().AutoCompactThreshold
+	t.Cleanup(func() { config.Get().AutoCompactThreshold = original })
+
+	ctx := context.Background()
+	s := NewService(db.New(newTestDB(t)))
+	sess, err := s.Create(ctx, "compact")
+	require.NoError(t, err)
+	sess.PromptTokens = 790
+	sess.CompletionTokens = 500
+	_, err = s.Save(ctx, sess)
+	require.NoError(t, err)
+
+	model := models.Model{ContextWindow: 1000}
+
+	config.Get().AutoCompactThreshold = 0.8
+	compact, err := s.ShouldCompact(ctx, sess.ID, model)
+	require.NoError(t, err)
+	assert.False(t, compact, "only prompt tokens count toward the threshold")
+
+	config.Get().AutoCompactThreshold = 0.75
+	compact, err = s.ShouldCompact(ctx, sess.ID, model)
+	require.NoError(t, err)
+	assert.True(t, compact)
+
+	compact, err = s.ShouldCompact(ctx, sess.ID, models.Model{})
+	require.NoError(t, err)
+	assert.False(t, compact, "an unknown context window never triggers compaction")
+
+	_, err = s.ShouldCompact(ctx, "missing", model)
+	assert.Error(t, err)
+}
@@ -0,0 +1,239 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+)
+
+// ExportFormat selects how Export renders a session.
+type ExportFormat string
+
+const (
+	ExportFormatMarkdown ExportFormat = "markdown"
+	ExportFormatJSON     ExportFormat = "json"
+)
+
+const (
+	// exportVersion is bumped whenever the JSON document changes shape.
+	exportVersion = 1
+	// maxInlineBinarySize is the largest binary part embedded in a JSON
+	// export; bigger parts keep only their path and MIME type.
+	maxInlineBinarySize = 64 * 1024
+)
+
+// exportDocument is the JSON form of an exported session.
+type exportDocument struct {
+	Version  int               `json:"version"`
+	Session  exportedSession   `json:"session"`
+	Messages []exportedMessage `json:"messages"`
+}
+
+type exportedSession struct {
+	ID               string  `json:"id"`
+	Title            string  `json:"title"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	CreatedAt        int64   `json:"created_at"`
+	UpdatedAt        int64   `json:"updated_at"`
+}
+
+type exportedMessage struct {
+	ID        string          `json:"id"`
+	Role      string          `json:"role"`
+	Model     string          `json:"model,omitempty"`
+	Parts     json.RawMessage `json:"parts"`
+	CreatedAt int64           `json:"created_at"`
+	UpdatedAt int64           `json:"updated_at"`
+}
+
+// Export renders the session and all of its messages in the given format.
+func (s *service) Export(ctx context.Context, id string, format ExportFormat) ([]byte, error) {
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	dbMessages, err := s.q.ListMessagesBySession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]message.Message, len(dbMessages))
+	for i, dbMessage := range dbMessages {
+		messages[i], err = message.FromDBItem(dbMessage)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch format {
+	case ExportFormatMarkdown:
+		return exportMarkdown(session, messages), nil
+	case ExportFormatJSON:
+		return exportJSON(session, messages)
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+func exportMarkdown(session Session, messages []message.Message) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", session.Title)
+	fmt.Fprintf(&sb, "- Session: %s\n", session.ID)
+	fmt.Fprintf(&sb, "- Created: %s\n", time.Unix(session.CreatedAt, 0).UTC().Format(time.RFC3339))
+	fmt.Fprintf(&sb, "- Tokens: %d prompt, %d completion\n", session.PromptTokens, session.CompletionTokens)
+	fmt.Fprintf(&sb, "- Cost: $%.4f\n", session.Cost)
+
+	for _, msg := range messages {
+		sb.WriteString("\n---\n\n")
+		sb.WriteString(msg.ToMarkdown())
+	}
+	return []byte(sb.String())
+}
+
+func exportJSON(session Session, messages []message.Message) ([]byte, error) {
+	doc := exportDocument{
+		Version: exportVersion,
+		Session: exportedSession{
+			ID:               session.ID,
+			Title:            session.Title,
+			PromptTokens:     session.PromptTokens,
+			CompletionTokens: session.CompletionTokens,
+			Cost:             session.Cost,
+			CreatedAt:        session.CreatedAt,
+			UpdatedAt:        session.UpdatedAt,
+		},
+		Messages: make([]exportedMessage, len(messages)),
+	}
+	for i, msg := range messages {
+		parts, err := message.MarshalParts(referenceLargeBinaries(msg.Parts))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode message %s: %w", msg.ID, err)
+		}
+		doc.Messages[i] = exportedMessage{
+			ID:        msg.ID,
+			Role:      string(msg.Role),
+			Model:     string(msg.Model),
+			Parts:     parts,
+			CreatedAt: msg.CreatedAt,
+			UpdatedAt: msg.UpdatedAt,
+		}
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// referenceLargeBinaries drops the data of binary parts too large to inline,
+// leaving their path as a reference.
+func referenceLargeBinaries(parts []message.ContentPart) []message.ContentPart {
+	out := make([]message.ContentPart, len(parts))
+	for i, part := range parts {
+		if binary, ok := part.(message.BinaryContent); ok && len(binary.Data) > maxInlineBinarySize {
+			binary.Data = nil
+			part = binary
+		}
+		out[i] = part
+	}
+	return out
+}
+
+// Import recreates a session from a JSON export under a fresh ID, carrying
+// over its messages in order along with the recorded token counts and cost.
+// The whole document is validated before anything is written, and a failure
+// while writing removes the half-imported session again.
+func (s *service) Import(ctx context.Context, data []byte) (Session, error) {
+	doc, parts, err := parseExport(data)
+	if err != nil {
+		return Session{}, err
+	}
+
+	dbSession, err := s.q.CreateSession(ctx, db.CreateSessionParams{
+		ID:    uuid.New().String(),
+		Title: doc.Session.Title,
+	})
+	if err != nil {
+		return Session{}, err
+	}
+
+	if err := s.importMessages(ctx, dbSession.ID, doc.Messages, parts); err != nil {
+		if delErr := s.q.DeleteSession(ctx, dbSession.ID); delErr != nil {
+			err = errors.Join(err, delErr)
+		}
+		return Session{}, fmt.Errorf("failed to import session: %w", err)
+	}
+
+	dbSession, err = s.q.UpdateSession(ctx, db.UpdateSessionParams{
+		ID:               dbSession.ID,
+		Title:            doc.Session.Title,
+		PromptTokens:     doc.Session.PromptTokens,
+		CompletionTokens: doc.Session.CompletionTokens,
+		Cost:             doc.Session.Cost,
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.CreatedEvent, session)
+	return session, nil
+}
+
+func (s *service) importMessages(ctx context.Context, sessionID string, messages []exportedMessage, parts [][]message.ContentPart) error {
+	for i, msg := range messages {
+		if err := s.insertMessage(ctx, sessionID, message.MessageRole(msg.Role), msg.Model, parts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertMessage stores a message directly, without the bookkeeping the
+// message service adds for live conversations.
+func (s *service) insertMessage(ctx context.Context, sessionID string, role message.MessageRole, model string, parts []message.ContentPart) error {
+	encoded, err := message.MarshalParts(parts)
+	if err != nil {
+		return err
+	}
+	_, err = s.q.CreateMessage(ctx, db.CreateMessageParams{
+		ID:        uuid.New().String(),
+		SessionID: sessionID,
+		Role:      string(role),
+		Parts:     string(encoded),
+		Model:     sql.NullString{String: model, Valid: model != ""},
+	})
+	return err
+}
+
+// parseExport decodes and validates a JSON export, returning the decoded
+// parts of every message alongside the document.
+func parseExport(data []byte) (exportDocument, [][]message.ContentPart, error) {
+	var doc exportDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return exportDocument{}, nil, fmt.Errorf("invalid session export: %w", err)
+	}
+	if doc.Version != exportVersion {
+		return exportDocument{}, nil, fmt.Errorf("unsupported session export version %d, expected %d", doc.Version, exportVersion)
+	}
+
+	parts := make([][]message.ContentPart, len(doc.Messages))
+	for i, msg := range doc.Messages {
+		switch message.MessageRole(msg.Role) {
+		case message.User, message.Assistant, message.System, message.Tool:
+		default:
+			return exportDocument{}, nil, fmt.Errorf("invalid session export: message %d has unknown role %q", i, msg.Role)
+		}
+		decoded, err := message.UnmarshalParts(msg.Parts)
+		if err != nil {
+			return exportDocument{}, nil, fmt.Errorf("invalid session export: message %d: %w", i, err)
+		}
+		parts[i] = decoded
+	}
+	return doc, parts, nil
+}
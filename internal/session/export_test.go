@@ -0,0 +1,154 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	q := db.New(newTestDB(t))
+	s := NewService(q)
+	messages := message.NewService(q)
+
+	session, err := s.Create(ctx, "Fix the parser")
+	require.NoError(t, err)
+	session.PromptTokens = 1200
+	session.CompletionTokens = 340
+	session.Cost = 0.0123
+	session, err = s.Save(ctx, session)
+	require.NoError(t, err)
+
+	_, err = messages.Create(ctx, session.ID, message.CreateMessageParams{
+		Role: message.User,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: "Why does it panic?"},
+			message.BinaryContent{Path: "small.png", MIMEType: "image/png", Data: []byte("tiny")},
+			message.BinaryContent{Path: "large.png", MIMEType: "image/png", Data: make([]byte, maxInlineBinarySize+1)},
+		},
+	})
+	require.NoError(t, err)
+	_, err = messages.Create(ctx, session.ID, message.CreateMessageParams{
+		Role:  message.Assistant,
+		Parts: []message.ContentPart{message.TextContent{Text: "A nil map is written to."}},
+	})
+	require.NoError(t, err)
+
+	t.Run("markdown", func(t *testing.T) {
+		out, err := s.Export(ctx, session.ID, ExportFormatMarkdown)
+		require.NoError(t, err)
+
+		md := string(out)
+		assert.Contains(t, md, "# Fix the parser\n")
+		assert.Contains(t, md, "- Tokens: 1200 prompt, 340 completion\n")
+		assert.Contains(t, md, "- Cost: $0.0123\n")
+		assert.Contains(t, md, "### User\n\nWhy does it panic?\n")
+		assert.Contains(t, md, "[large.png] (image/png, 65537 bytes)")
+		assert.Contains(t, md, "### Assistant\n\nA nil map is written to.\n")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out, err := s.Export(ctx, session.ID, ExportFormatJSON)
+		require.NoError(t, err)
+
+		var doc exportDocument
+		require.NoError(t, json.Unmarshal(out, &doc))
+		assert.Equal(t, exportVersion, doc.Version)
+		assert.Equal(t, session.ID, doc.Session.ID)
+		assert.Equal(t, "Fix the parser", doc.Session.Title)
+		require.Len(t, doc.Messages, 2)
+
+		parts, err := message.UnmarshalParts(doc.Messages[0].Parts)
+		require.NoError(t, err)
+		require.Len(t, parts, 4)
+		assert.Equal(t, []byte("tiny"), parts[1].(message.BinaryContent).Data)
+		large := parts[2].(message.BinaryContent)
+		assert.Equal(t, "large.png", large.Path)
+		assert.Empty(t, large.Data)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := s.Export(ctx, session.ID, ExportFormat("pdf"))
+		assert.Error(t, err)
+	})
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	q := db.New(newTestDB(t))
+	s := NewService(q)
+	messages := message.NewService(q)
+
+	original, err := s.Create(ctx, "Original")
+	require.NoError(t, err)
+	original.PromptTokens = 10
+	original.CompletionTokens = 20
+	original.Cost = 0.5
+	original, err = s.Save(ctx, original)
+	require.NoError(t, err)
+
+	_, err = messages.Create(ctx, original.ID, message.CreateMessageParams{
+		Role: message.User,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: "first"},
+			message.ImageURLContent{URL: "https://example.com/a.png"},
+		},
+	})
+	require.NoError(t, err)
+	_, err = messages.Create(ctx, original.ID, message.CreateMessageParams{
+		Role:  message.Assistant,
+		Parts: []message.ContentPart{message.TextContent{Text: "second"}},
+		Model: "gpt-4.1",
+	})
+	require.NoError(t, err)
+
+	data, err := s.Export(ctx, original.ID, ExportFormatJSON)
+	require.NoError(t, err)
+
+	t.Run("round trips an export", func(t *testing.T) {
+		imported, err := s.Import(ctx, data)
+		require.NoError(t, err)
+		assert.NotEqual(t, original.ID, imported.ID)
+		assert.Equal(t, "Original", imported.Title)
+		assert.Equal(t, int64(10), imported.PromptTokens)
+		assert.Equal(t, int64(20), imported.CompletionTokens)
+		assert.Equal(t, 0.5, imported.Cost)
+		assert.Equal(t, int64(2), imported.MessageCount)
+
+		msgs, err := messages.List(ctx, imported.ID)
+		require.NoError(t, err)
+		require.Len(t, msgs, 2)
+		assert.Equal(t, message.User, msgs[0].Role)
+		assert.Equal(t, "first", msgs[0].Content().Text)
+		assert.Len(t, msgs[0].ImageURLContent(), 1)
+		assert.Equal(t, message.Assistant, msgs[1].Role)
+		assert.Equal(t, "second", msgs[1].Content().Text)
+		assert.Equal(t, "gpt-4.1", string(msgs[1].Model))
+	})
+
+	t.Run("rejects invalid documents", func(t *testing.T) {
+		before, err := s.List(ctx)
+		require.NoError(t, err)
+
+		invalid := []string{
+			`not json`,
+			`{"version": 99, "session": {"title": "x"}, "messages": []}`,
+			`{"version": 1, "session": {"title": "x"}, "messages": [{"role": "robot", "parts": []}]}`,
+			`{"version": 1, "session": {"title": "x"}, "messages": [{"role": "user", "parts": []}, {"role": "user", "parts": [{"type": "video", "data": {}}]}]}`,
+		}
+		for _, doc := range invalid {
+			_, err := s.Import(ctx, []byte(doc))
+			assert.Error(t, err, doc)
+		}
+
+		after, err := s.List(ctx)
+		require.NoError(t, err)
+		assert.Len(t, after, len(before))
+	})
+}
@@ -3,12 +3,30 @@ package session
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/message"
 	"github.com/opencode-ai/opencode/internal/pubsub"
 )
 
+// ErrSessionExists is returned when creating a session with an ID that is
+// already taken.
+var ErrSessionExists = errors.New("session already exists")
+
+// ErrNoSummary is returned by GetSummary for a session that has not been
+// summarized.
+var ErrNoSummary = errors.New("session has no summary")
+
+// MaxTitleLength is the most characters SetTitle keeps of a title, ellipsis
+// included.
+const MaxTitleLength = 100
+
 type Session struct {
 	ID               string
 	ParentSessionID  string
@@ -17,20 +35,45 @@ type Session struct {
 	PromptTokens     int64
 	CompletionTokens int64
 	SummaryMessageID string
+	Fingerprint      string
 	Cost             float64
 	CreatedAt        int64
 	UpdatedAt        int64
+	// LastMessageAt is when the latest message was added, or zero before the
+	// first one. Unlike UpdatedAt it isn't moved by usage or title updates.
+	LastMessageAt int64
+	// Metadata holds tags set by SetMetadata, such as a branch name or
+	// ticket ID. It is nil when the session has none.
+	Metadata map[string]string
 }
 
 type Service interface {
 	pubsub.Suscriber[Session]
 	Create(ctx context.Context, title string) (Session, error)
+	CreateForPrompt(ctx context.Context, title, prompt string) (Session, error)
+	CreateWithID(ctx context.Context, id, title string) (Session, error)
 	CreateTitleSession(ctx context.Context, parentSessionID string) (Session, error)
 	CreateTaskSession(ctx context.Context, toolCallID, parentSessionID, title string) (Session, error)
+	CreateFromTemplate(ctx context.Context, templateName string) (Session, error)
 	Get(ctx context.Context, id string) (Session, error)
+	GetSummary(ctx context.Context, id string) (message.Message, error)
 	List(ctx context.Context) ([]Session, error)
+	ListRecent(ctx context.Context, limit int) ([]Session, error)
+	MostRecent(ctx context.Context) (Session, bool, error)
 	Save(ctx context.Context, session Session) (Session, error)
+	AddUsage(ctx context.Context, id string, promptTokens, completionTokens int64, cost float64) (Session, error)
+	SetUsage(ctx context.Context, id string, promptTokens, completionTokens int64, cost float64) (Session, error)
+	Reconcile(ctx context.Context, id string) (Session, error)
 	Delete(ctx context.Context, id string) error
+	SetFingerprint(ctx context.Context, id, firstMessage string) (Session, error)
+	SetTitle(ctx context.Context, id, title string) (Session, error)
+	SetMetadata(ctx context.Context, id string, kv map[string]string) (Session, error)
+	GetMetadata(ctx context.Context, id string) (map[string]string, error)
+	ListByMetadata(ctx context.Context, key, value string) ([]Session, error)
+	FindByFingerprint(ctx context.Context, fingerprint string) ([]Session, error)
+	Export(ctx context.Context, id string, format ExportFormat) ([]byte, error)
+	Import(ctx context.Context, data []byte) (Session, error)
+	ShouldCompact(ctx context.Context, id string, model models.Model) (bool, error)
 }
 
 type service struct {
@@ -51,12 +94,53 @@ func (s *service) Create(ctx context.Context, title string) (Session, error) {
 	return session, nil
 }
 
-func (s *service) CreateTaskSession(ctx context.Context, toolCallID, parentSessionID, title string) (Session, error) {
+// CreateForPrompt creates a top-level session for a conversation that starts
+// with prompt, storing the prompt's fingerprint so FindByFingerprint can find
+// the session later.
+func (s *service) CreateForPrompt(ctx context.Context, title, prompt string) (Session, error) {
 	dbSession, err := s.q.CreateSession(ctx, db.CreateSessionParams{
+		ID:          uuid.New().String(),
+		Title:       title,
+		Fingerprint: sql.NullString{String: message.Fingerprint(prompt), Valid: true},
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.CreatedEvent, session)
+	return session, nil
+}
+
+// CreateWithID creates a top-level session with a caller supplied ID, so that
+// scripts can refer to a known session across runs. It returns
+// ErrSessionExists if the ID is already in use.
+func (s *service) CreateWithID(ctx context.Context, id, title string) (Session, error) {
+	if id == "" {
+		return Session{}, errors.New("session id must not be empty")
+	}
+	return s.createWithID(ctx, db.CreateSessionParams{
+		ID:    id,
+		Title: title,
+	})
+}
+
+func (s *service) CreateTaskSession(ctx context.Context, toolCallID, parentSessionID, title string) (Session, error) {
+	return s.createWithID(ctx, db.CreateSessionParams{
 		ID:              toolCallID,
 		ParentSessionID: sql.NullString{String: parentSessionID, Valid: true},
 		Title:           title,
 	})
+}
+
+func (s *service) createWithID(ctx context.Context, params db.CreateSessionParams) (Session, error) {
+	_, err := s.q.GetSessionByID(ctx, params.ID)
+	if err == nil {
+		return Session{}, fmt.Errorf("%w: %s", ErrSessionExists, params.ID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Session{}, err
+	}
+	dbSession, err := s.q.CreateSession(ctx, params)
 	if err != nil {
 		return Session{}, err
 	}
@@ -100,6 +184,40 @@ func (s *service) Get(ctx context.Context, id string) (Session, error) {
 	return s.fromDBItem(dbSession), nil
 }
 
+// GetSummary returns the message summarizing the session so far. It returns
+// ErrNoSummary if the session has not been summarized or its summary message
+// has since been deleted.
+func (s *service) GetSummary(ctx context.Context, id string) (message.Message, error) {
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		return message.Message{}, err
+	}
+	if session.SummaryMessageID == "" {
+		return message.Message{}, ErrNoSummary
+	}
+	dbMessage, err := s.q.GetMessage(ctx, session.SummaryMessageID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return message.Message{}, ErrNoSummary
+	}
+	if err != nil {
+		return message.Message{}, fmt.Errorf("failed to get summary message: %w", err)
+	}
+	return message.FromDBItem(dbMessage)
+}
+
+// MostRecent returns the most recently updated top-level session. The boolean
+// is false when there are no sessions yet.
+func (s *service) MostRecent(ctx context.Context) (Session, bool, error) {
+	dbSession, err := s.q.GetMostRecentSession(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Session{}, false, nil
+	}
+	if err != nil {
+		return Session{}, false, err
+	}
+	return s.fromDBItem(dbSession), true, nil
+}
+
 func (s *service) Save(ctx context.Context, session Session) (Session, error) {
 	dbSession, err := s.q.UpdateSession(ctx, db.UpdateSessionParams{
 		ID:               session.ID,
@@ -120,6 +238,114 @@ func (s *service) Save(ctx context.Context, session Session) (Session, error) {
 	return session, nil
 }
 
+// AddUsage adds to the session's token counts and cost in a single update, so
+// concurrent callers never overwrite each other's usage the way Save can.
+func (s *service) AddUsage(ctx context.Context, id string, promptTokens, completionTokens int64, cost float64) (Session, error) {
+	dbSession, err := s.q.AddSessionUsage(ctx, db.AddSessionUsageParams{
+		ID:               id,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		Cost:             cost,
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.UpdatedEvent, session)
+	return session, nil
+}
+
+// SetUsage records a response's usage in a single update: the token counts
+// are replaced by the response's, which describe the current context, while
+// its cost is added to the session's.
+func (s *service) SetUsage(ctx context.Context, id string, promptTokens, completionTokens int64, cost float64) (Session, error) {
+	dbSession, err := s.q.SetSessionUsage(ctx, db.SetSessionUsageParams{
+		ID:               id,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		Cost:             cost,
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.UpdatedEvent, session)
+	return session, nil
+}
+
+// Reconcile repairs a session whose stored totals have drifted from its
+// messages. The message count is recounted, and when any message records the
+// usage of the response that produced it, the token counts and cost are
+// rebuilt from that usage: the token counts are those of the latest response,
+// as the agent tracks them, and the cost is summed over every response. Cache
+// writes are counted in a message's input tokens, so they are priced at the
+// input rate, and the cost of task sessions run from this one isn't recorded
+// on its messages, so it is not included.
+func (s *service) Reconcile(ctx context.Context, id string) (Session, error) {
+	messages, err := s.q.ListMessagesBySession(ctx, id)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	dbSession, err := s.q.UpdateSessionMessageCount(ctx, db.UpdateSessionMessageCountParams{
+		ID:           id,
+		MessageCount: int64(len(messages)),
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	if usage, ok := messagesUsage(messages); ok {
+		dbSession, err = s.q.UpdateSession(ctx, db.UpdateSessionParams{
+			ID:               id,
+			Title:            dbSession.Title,
+			PromptTokens:     usage.promptTokens,
+			CompletionTokens: usage.completionTokens,
+			SummaryMessageID: dbSession.SummaryMessageID,
+			Cost:             usage.cost,
+		})
+		if err != nil {
+			return Session{}, err
+		}
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.UpdatedEvent, session)
+	return session, nil
+}
+
+type usageTotals struct {
+	promptTokens     int64
+	completionTokens int64
+	cost             float64
+}
+
+// messagesUsage rebuilds session usage from the messages' Finish parts. Like
+// the agent, it takes the token counts from the latest response, with cache
+// writes as prompt tokens and cache reads as completion tokens, and sums the
+// cost of all of them. It reports false when no message records any usage.
+func messagesUsage(messages []db.Message) (usageTotals, bool) {
+	var totals usageTotals
+	found := false
+	for _, msg := range messages {
+		parts, err := message.UnmarshalPartsLenient([]byte(msg.Parts))
+		if err != nil {
+			continue
+		}
+		for _, part := range parts {
+			finish, ok := part.(message.Finish)
+			if !ok || finish.InputTokens == 0 && finish.OutputTokens == 0 && finish.CachedTokens == 0 {
+				continue
+			}
+			found = true
+			totals.promptTokens = finish.InputTokens
+			totals.completionTokens = finish.OutputTokens + finish.CachedTokens
+			if model, ok := models.SupportedModels[models.ModelID(msg.Model.String)]; ok {
+				totals.cost += model.Cost(finish.InputTokens, finish.OutputTokens) +
+					model.CachedCost(0, finish.CachedTokens)
+			}
+		}
+	}
+	return totals, found
+}
+
 func (s *service) List(ctx context.Context) ([]Session, error) {
 	dbSessions, err := s.q.ListSessions(ctx)
 	if err != nil {
@@ -132,7 +358,152 @@ func (s *service) List(ctx context.Context) ([]Session, error) {
 	return sessions, nil
 }
 
+// ListRecent returns up to limit top-level sessions, most recently active
+// first, or all of them when limit is zero or less. Sessions without messages
+// count as active when they were created.
+func (s *service) ListRecent(ctx context.Context, limit int) ([]Session, error) {
+	if limit <= 0 {
+		// SQLite treats a negative limit as no limit
+		limit = -1
+	}
+	dbSessions, err := s.q.ListRecentSessions(ctx, int64(limit))
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, len(dbSessions))
+	for i, dbSession := range dbSessions {
+		sessions[i] = s.fromDBItem(dbSession)
+	}
+	return sessions, nil
+}
+
+// SetFingerprint stores a fingerprint derived from the session's first user
+// message so similar conversations can be found later.
+func (s *service) SetFingerprint(ctx context.Context, id, firstMessage string) (Session, error) {
+	dbSession, err := s.q.UpdateSessionFingerprint(ctx, db.UpdateSessionFingerprintParams{
+		ID:          id,
+		Fingerprint: sql.NullString{String: message.Fingerprint(firstMessage), Valid: true},
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.UpdatedEvent, session)
+	return session, nil
+}
+
+// SetTitle sets the session's title, leaving its usage and other fields as
+// they are. The title is put on one line with runs of whitespace collapsed to
+// a single space, and one longer than MaxTitleLength is cut short with an
+// ellipsis.
+func (s *service) SetTitle(ctx context.Context, id, title string) (Session, error) {
+	dbSession, err := s.q.UpdateSessionTitle(ctx, db.UpdateSessionTitleParams{
+		ID:    id,
+		Title: cleanTitle(title),
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.UpdatedEvent, session)
+	return session, nil
+}
+
+// cleanTitle puts a title on one line, trimmed and at most MaxTitleLength
+// characters long.
+func cleanTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	runes := []rune(title)
+	if len(runes) <= MaxTitleLength {
+		return title
+	}
+	return strings.TrimSpace(string(runes[:MaxTitleLength-1])) + "…"
+}
+
+// SetMetadata merges kv into the session's metadata: keys in kv replace the
+// stored values, a key with an empty value is removed, and other stored keys
+// are kept. A nil kv clears all of the session's metadata. The merge happens
+// in a single update, so concurrent callers setting different keys don't
+// overwrite each other.
+func (s *service) SetMetadata(ctx context.Context, id string, kv map[string]string) (Session, error) {
+	var patch sql.NullString
+	if kv != nil {
+		// in a JSON merge patch a null value removes the key
+		values := make(map[string]*string, len(kv))
+		for key, value := range kv {
+			if value != "" {
+				values[key] = &value
+			} else {
+				values[key] = nil
+			}
+		}
+		data, err := json.Marshal(values)
+		if err != nil {
+			return Session{}, fmt.Errorf("failed to encode metadata: %w", err)
+		}
+		patch = sql.NullString{String: string(data), Valid: true}
+	}
+
+	dbSession, err := s.q.UpdateSessionMetadata(ctx, db.UpdateSessionMetadataParams{
+		ID:    id,
+		Patch: patch,
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.UpdatedEvent, session)
+	return session, nil
+}
+
+// GetMetadata returns the session's metadata, which is nil when it has none.
+func (s *service) GetMetadata(ctx context.Context, id string) (map[string]string, error) {
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return session.Metadata, nil
+}
+
+// ListByMetadata returns the sessions whose metadata sets key to value, most
+// recently active first. No session matches a key that is empty or contains
+// a double quote, since neither can be looked up in the stored JSON.
+func (s *service) ListByMetadata(ctx context.Context, key, value string) ([]Session, error) {
+	if key == "" || strings.Contains(key, `"`) {
+		return []Session{}, nil
+	}
+	dbSessions, err := s.q.ListSessionsByMetadata(ctx, db.ListSessionsByMetadataParams{
+		Path:  `$."` + key + `"`,
+		Value: value,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, len(dbSessions))
+	for i, dbSession := range dbSessions {
+		sessions[i] = s.fromDBItem(dbSession)
+	}
+	return sessions, nil
+}
+
+func (s *service) FindByFingerprint(ctx context.Context, fingerprint string) ([]Session, error) {
+	dbSessions, err := s.q.ListSessionsByFingerprint(ctx, sql.NullString{String: fingerprint, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, len(dbSessions))
+	for i, dbSession := range dbSessions {
+		sessions[i] = s.fromDBItem(dbSession)
+	}
+	return sessions, nil
+}
+
 func (s service) fromDBItem(item db.Session) Session {
+	var metadata map[string]string
+	if item.Metadata.Valid {
+		// only SetMetadata writes the column, so it is always an object of strings
+		_ = json.Unmarshal([]byte(item.Metadata.String), &metadata)
+	}
 	return Session{
 		ID:               item.ID,
 		ParentSessionID:  item.ParentSessionID.String,
@@ -141,9 +512,12 @@ func (s service) fromDBItem(item db.Session) Session {
 		PromptTokens:     item.PromptTokens,
 		CompletionTokens: item.CompletionTokens,
 		SummaryMessageID: item.SummaryMessageID.String,
+		Fingerprint:      item.Fingerprint.String,
 		Cost:             item.Cost,
 		CreatedAt:        item.CreatedAt,
 		UpdatedAt:        item.UpdatedAt,
+		LastMessageAt:    item.LastMessageAt.Int64,
+		Metadata:         metadata,
 	}
 }
 
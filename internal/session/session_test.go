@@ -0,0 +1,490 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "opencode.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	goose.SetBaseFS(db.FS)
+	goose.SetLogger(goose.NopLogger())
+	require.NoError(t, goose.SetDialect("sqlite3"))
+	require.NoError(t, goose.Up(conn, "migrations"))
+	return conn
+}
+
+func insertSession(t *testing.T, conn *sql.DB, id string, parentID any, createdAt, updatedAt int64) {
+	t.Helper()
+	_, err := conn.Exec(
+		`INSERT INTO sessions (id, parent_session_id, title, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`,
+		id, parentID, id, createdAt, updatedAt,
+	)
+	require.NoError(t, err)
+}
+
+func TestMostRecent(t *testing.T) {
+	t.Run("no sessions", func(t *testing.T) {
+		s := NewService(db.New(newTestDB(t)))
+
+		_, ok, err := s.MostRecent(context.Background())
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("picks the most recently updated session", func(t *testing.T) {
+		conn := newTestDB(t)
+		insertSession(t, conn, "oldest", nil, 100, 100)
+		insertSession(t, conn, "touched", nil, 200, 500)
+		insertSession(t, conn, "newest", nil, 300, 300)
+		insertSession(t, conn, "child", "touched", 400, 900)
+		s := NewService(db.New(conn))
+
+		session, ok, err := s.MostRecent(context.Background())
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "touched", session.ID)
+	})
+
+	t.Run("breaks ties by creation time", func(t *testing.T) {
+		conn := newTestDB(t)
+		insertSession(t, conn, "first", nil, 100, 500)
+		insertSession(t, conn, "second", nil, 200, 500)
+		s := NewService(db.New(conn))
+
+		session, ok, err := s.MostRecent(context.Background())
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "second", session.ID)
+	})
+}
+
+func TestListRecent(t *testing.T) {
+	conn := newTestDB(t)
+	insertSession(t, conn, "active", nil, 100, 100)
+	insertSession(t, conn, "empty", nil, 300, 300)
+	insertSession(t, conn, "older", nil, 200, 200)
+	insertSession(t, conn, "tie-stale", nil, 50, 60)
+	insertSession(t, conn, "tie-fresh", nil, 50, 70)
+	insertSession(t, conn, "child", "active", 400, 400)
+	addMessage := func(sessionID string, createdAt int64) {
+		t.Helper()
+		_, err := conn.Exec(
+			`INSERT INTO messages (id, session_id, role, parts, created_at, updated_at) VALUES (?, ?, 'user', '[]', ?, ?)`,
+			fmt.Sprintf("%s-message-%d", sessionID, createdAt), sessionID, createdAt, createdAt,
+		)
+		require.NoError(t, err)
+	}
+	addMessage("active", 1000)
+	// a message imported with an older timestamp doesn't move activity back
+	addMessage("active", 600)
+	addMessage("older", 500)
+	addMessage("child", 2000)
+	s := NewService(db.New(conn))
+
+	sessions, err := s.ListRecent(context.Background(), 4)
+	require.NoError(t, err)
+	var ids []string
+	for _, session := range sessions {
+		ids = append(ids, session.ID)
+	}
+	assert.Equal(t, []string{"active", "older", "empty", "tie-fresh"}, ids)
+	assert.Equal(t, int64(1000), sessions[0].LastMessageAt)
+	assert.Zero(t, sessions[2].LastMessageAt)
+
+	sessions, err = s.ListRecent(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 5)
+}
+
+func TestCreateWithID(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(db.New(newTestDB(t)))
+
+	session, err := s.CreateWithID(ctx, "ci-build-42", "Nightly")
+	require.NoError(t, err)
+	assert.Equal(t, "ci-build-42", session.ID)
+	assert.Equal(t, "Nightly", session.Title)
+
+	got, err := s.Get(ctx, "ci-build-42")
+	require.NoError(t, err)
+	assert.Equal(t, session.ID, got.ID)
+
+	_, err = s.CreateWithID(ctx, "ci-build-42", "Again")
+	assert.ErrorIs(t, err, ErrSessionExists)
+
+	_, err = s.CreateWithID(ctx, "", "Empty")
+	assert.Error(t, err)
+}
+
+func TestGetSummary(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	q := db.New(newTestDB(t))
+	sessions := NewService(q)
+	messages := message.NewService(q)
+
+	sess, err := sessions.Create(ctx, "summarized")
+	require.NoError(t, err)
+	_, err = sessions.GetSummary(ctx, sess.ID)
+	assert.ErrorIs(t, err, ErrNoSummary)
+
+	var ids []string
+	for _, text := range []string{"first", "summary", "third", "fourth"} {
+		msg, err := messages.Create(ctx, sess.ID, message.CreateMessageParams{
+			Role:  message.Assistant,
+			Parts: []message.ContentPart{message.TextContent{Text: text}},
+		})
+		require.NoError(t, err)
+		ids = append(ids, msg.ID)
+	}
+
+	sess.SummaryMessageID = ids[1]
+	_, err = sessions.Save(ctx, sess)
+	require.NoError(t, err)
+
+	summary, err := sessions.GetSummary(ctx, sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "summary", summary.Content().String())
+
+	after, err := messages.ListAfter(ctx, sess.ID, summary.ID)
+	require.NoError(t, err)
+	require.Len(t, after, 2)
+	assert.Equal(t, ids[2], after[0].ID)
+	assert.Equal(t, ids[3], after[1].ID)
+
+	require.NoError(t, messages.Delete(ctx, ids[1]))
+	_, err = sessions.GetSummary(ctx, sess.ID)
+	assert.ErrorIs(t, err, ErrNoSummary)
+}
+
+func TestAddUsageConcurrent(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s := NewService(db.New(newTestDB(t)))
+	sess, err := s.Create(ctx, "usage")
+	require.NoError(t, err)
+
+	const workers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.AddUsage(ctx, sess.ID, 10, 3, 0.25)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	got, err := s.Get(ctx, sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(workers*10), got.PromptTokens)
+	assert.Equal(t, int64(workers*3), got.CompletionTokens)
+	assert.Equal(t, workers*0.25, got.Cost)
+
+	_, err = s.AddUsage(ctx, "missing", 1, 1, 1)
+	assert.Error(t, err)
+}
+
+func TestSetUsageConcurrent(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s := NewService(db.New(newTestDB(t)))
+	sess, err := s.Create(ctx, "usage")
+	require.NoError(t, err)
+
+	// responses replace the token counts while task sessions add their cost
+	const workers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*workers)
+	for range workers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := s.SetUsage(ctx, sess.ID, 1000, 50, 0.25)
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := s.AddUsage(ctx, sess.ID, 0, 0, 0.5)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	got, err := s.Get(ctx, sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), got.PromptTokens)
+	assert.Equal(t, int64(50), got.CompletionTokens)
+	assert.Equal(t, workers*0.75, got.Cost)
+
+	_, err = s.SetUsage(ctx, "missing", 1, 1, 1)
+	assert.Error(t, err)
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	s := NewService(db.New(conn))
+	sess, err := s.Create(ctx, "drifted")
+	require.NoError(t, err)
+	for i := range 3 {
+		_, err := conn.Exec(
+			`INSERT INTO messages (id, session_id, role, parts, created_at, updated_at) VALUES (?, ?, 'user', '[]', ?, ?)`,
+			fmt.Sprintf("message-%d", i), sess.ID, i, i,
+		)
+		require.NoError(t, err)
+	}
+	_, err = s.AddUsage(ctx, sess.ID, 100, 20, 0.5)
+	require.NoError(t, err)
+	_, err = conn.Exec(`UPDATE sessions SET message_count = 7 WHERE id = ?`, sess.ID)
+	require.NoError(t, err)
+
+	sess, err = s.Reconcile(ctx, sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), sess.MessageCount)
+	assert.Equal(t, int64(100), sess.PromptTokens, "token totals are kept")
+
+	got, err := s.Get(ctx, sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), got.MessageCount)
+
+	_, err = s.Reconcile(ctx, "missing")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestReconcileRebuildsUsage(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	s := NewService(db.New(conn))
+	sess, err := s.Create(ctx, "drifted")
+	require.NoError(t, err)
+
+	finishes := []message.Finish{
+		{Reason: message.FinishReasonToolUse, InputTokens: 1000, OutputTokens: 200, CachedTokens: 500},
+		{Reason: message.FinishReasonEndTurn, InputTokens: 3000, OutputTokens: 100},
+		{Reason: message.FinishReasonCanceled},
+	}
+	for i, finish := range finishes {
+		parts, err := message.MarshalParts([]message.ContentPart{message.TextContent{Text: "hi"}, finish})
+		require.NoError(t, err)
+		_, err = conn.Exec(
+			`INSERT INTO messages (id, session_id, role, parts, model, created_at, updated_at) VALUES (?, ?, 'assistant', ?, ?, ?, ?)`,
+			fmt.Sprintf("message-%d", i), sess.ID, string(parts), models.GPT41, i, i,
+		)
+		require.NoError(t, err)
+	}
+	_, err = s.AddUsage(ctx, sess.ID, 1, 1, 9)
+	require.NoError(t, err)
+
+	sess, err = s.Reconcile(ctx, sess.ID)
+	require.NoError(t, err)
+	model := models.SupportedModels[models.GPT41]
+	assert.Equal(t, int64(3), sess.MessageCount)
+	// tokens are the latest response's context, cost is the sum of all
+	assert.Equal(t, int64(3000), sess.PromptTokens)
+	assert.Equal(t, int64(100), sess.CompletionTokens)
+	assert.InDelta(t, model.Cost(4000, 300)+model.CachedCost(0, 500), sess.Cost, 1e-9)
+	assert.Equal(t, "drifted", sess.Title)
+
+	got, err := s.Get(ctx, sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sess.Cost, got.Cost)
+}
+
+func TestFingerprint(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestDB(t)
+	insertSession(t, conn, "older", nil, 100, 100)
+	insertSession(t, conn, "unrelated", nil, 200, 200)
+	s := NewService(db.New(conn))
+
+	created, err := s.CreateForPrompt(ctx, "New Session", "Fix the  flaky test")
+	require.NoError(t, err)
+	_, err = s.SetFingerprint(ctx, "older", "fix the flaky TEST")
+	require.NoError(t, err)
+	_, err = s.SetFingerprint(ctx, "unrelated", "write the docs")
+	require.NoError(t, err)
+
+	sessions, err := s.FindByFingerprint(ctx, message.Fingerprint("fix the flaky test"))
+	require.NoError(t, err)
+	var ids []string
+	for _, session := range sessions {
+		ids = append(ids, session.ID)
+	}
+	assert.ElementsMatch(t, []string{created.ID, "older"}, ids)
+
+	sessions, err = s.FindByFingerprint(ctx, message.Fingerprint("something else"))
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	// sessions created without a prompt have no fingerprint to match
+	_, err = s.Create(ctx, "blank")
+	require.NoError(t, err)
+	sessions, err = s.FindByFingerprint(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	_, err = s.SetFingerprint(ctx, "missing", "hi")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestSetTitle(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s := NewService(db.New(newTestDB(t)))
+	sess, err := s.Create(ctx, "New Session")
+	require.NoError(t, err)
+	_, err = s.AddUsage(ctx, sess.ID, 100, 20, 0.5)
+	require.NoError(t, err)
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := s.Subscribe(subCtx)
+
+	sess, err = s.SetTitle(ctx, sess.ID, "  Fix the\nlogin   bug \n")
+	require.NoError(t, err)
+	assert.Equal(t, "Fix the login bug", sess.Title)
+	assert.Equal(t, int64(100), sess.PromptTokens, "usage is kept")
+	assert.Equal(t, int64(20), sess.CompletionTokens)
+	assert.Equal(t, 0.5, sess.Cost)
+
+	event := <-events
+	assert.Equal(t, pubsub.UpdatedEvent, event.Type)
+	assert.Equal(t, "Fix the login bug", event.Payload.Title)
+
+	got, err := s.Get(ctx, sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Fix the login bug", got.Title)
+
+	sess, err = s.SetTitle(ctx, sess.ID, strings.Repeat("é", MaxTitleLength+20))
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("é", MaxTitleLength-1)+"…", sess.Title)
+
+	_, err = s.SetTitle(ctx, "missing", "title")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestSetMetadata(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s := NewService(db.New(newTestDB(t)))
+	sess, err := s.Create(ctx, "New Session")
+	require.NoError(t, err)
+	assert.Nil(t, sess.Metadata)
+
+	sess, err = s.SetMetadata(ctx, sess.ID, map[string]string{"branch": "main", "ticket": "ABC-123"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"branch": "main", "ticket": "ABC-123"}, sess.Metadata)
+
+	sess, err = s.SetMetadata(ctx, sess.ID, map[string]string{"branch": "fix-login", "ticket": "", "env": "staging"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"branch": "fix-login", "env": "staging"}, sess.Metadata, "merged, with empty values removed")
+
+	got, err := s.GetMetadata(ctx, sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sess.Metadata, got)
+
+	_, err = s.SetTitle(ctx, sess.ID, "Renamed")
+	require.NoError(t, err)
+	got, err = s.GetMetadata(ctx, sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sess.Metadata, got, "other updates keep the metadata")
+
+	sess, err = s.SetMetadata(ctx, sess.ID, map[string]string{"branch": "", "env": ""})
+	require.NoError(t, err)
+	assert.Nil(t, sess.Metadata, "removing every key leaves none")
+
+	_, err = s.SetMetadata(ctx, sess.ID, map[string]string{"branch": "main"})
+	require.NoError(t, err)
+	sess, err = s.SetMetadata(ctx, sess.ID, nil)
+	require.NoError(t, err)
+	assert.Nil(t, sess.Metadata, "nil clears the metadata")
+
+	_, err = s.SetMetadata(ctx, "missing", map[string]string{"branch": "main"})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = s.GetMetadata(ctx, "missing")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestListByMetadata(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	conn := newTestDB(t)
+	insertSession(t, conn, "old", nil, 100, 100)
+	insertSession(t, conn, "new", nil, 300, 300)
+	insertSession(t, conn, "other-ticket", nil, 200, 200)
+	insertSession(t, conn, "untagged", nil, 400, 400)
+	insertSession(t, conn, "task", "old", 250, 250)
+	s := NewService(db.New(conn))
+	for id, metadata := range map[string]map[string]string{
+		"old":          {"ticket": "ABC-123"},
+		"new":          {"ticket": "ABC-123", "branch": "main"},
+		"other-ticket": {"ticket": "ABC-999"},
+		"task":         {"ticket": "ABC-123", "dotted.key": "yes"},
+	} {
+		_, err := s.SetMetadata(ctx, id, metadata)
+		require.NoError(t, err)
+	}
+	ids := func(sessions []Session) []string {
+		ids := []string{}
+		for _, session := range sessions {
+			ids = append(ids, session.ID)
+		}
+		return ids
+	}
+
+	sessions, err := s.ListByMetadata(ctx, "ticket", "ABC-123")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new", "task", "old"}, ids(sessions))
+	assert.Equal(t, "main", sessions[0].Metadata["branch"])
+
+	sessions, err = s.ListByMetadata(ctx, "dotted.key", "yes")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"task"}, ids(sessions))
+
+	for _, key := range []string{"missing", "", `ti"cket`} {
+		sessions, err = s.ListByMetadata(ctx, key, "ABC-123")
+		require.NoError(t, err, key)
+		assert.Empty(t, sessions, key)
+		assert.NotNil(t, sessions, key)
+	}
+	sessions, err = s.ListByMetadata(ctx, "ticket", "XYZ")
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
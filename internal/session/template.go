@@ -0,0 +1,62 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+)
+
+// CreateFromTemplate creates a session seeded with the messages of the named
+// template from the config. The session is titled after the template unless
+// the template sets its own title.
+func (s *service) CreateFromTemplate(ctx context.Context, templateName string) (Session, error) {
+	cfg := config.Get()
+	if cfg == nil {
+		return Session{}, fmt.Errorf("config not loaded")
+	}
+	template, ok := cfg.Templates[templateName]
+	if !ok {
+		return Session{}, fmt.Errorf("unknown session template: %s", templateName)
+	}
+	title := template.Title
+	if title == "" {
+		title = templateName
+	}
+
+	dbSession, err := s.q.CreateSession(ctx, db.CreateSessionParams{
+		ID:    uuid.New().String(),
+		Title: title,
+	})
+	if err != nil {
+		return Session{}, err
+	}
+
+	for _, msg := range template.Messages {
+		role := message.MessageRole(msg.Role)
+		finish := message.Finish{Reason: message.FinishReasonEndTurn}
+		if role != message.Assistant {
+			finish.Reason = "stop"
+		}
+		parts := []message.ContentPart{message.TextContent{Text: msg.Content}, finish}
+		if err := s.insertMessage(ctx, dbSession.ID, role, "", parts); err != nil {
+			if delErr := s.q.DeleteSession(ctx, dbSession.ID); delErr != nil {
+				err = errors.Join(err, delErr)
+			}
+			return Session{}, fmt.Errorf("failed to seed session from template %s: %w", templateName, err)
+		}
+	}
+
+	dbSession, err = s.q.GetSessionByID(ctx, dbSession.ID)
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.CreatedEvent, session)
+	return session, nil
+}
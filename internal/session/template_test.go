@@ -0,0 +1,53 @@
+package session
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateFromTemplate(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	config.Get().Templates = map[string]config.SessionTemplate{
+		"review": {
+			Title: "Code review",
+			Messages: []config.TemplateMessage{
+				{Role: "user", Content: "You are reviewing Go code. Be terse."},
+				{Role: "assistant", Content: "Understood."},
+			},
+		},
+		"untitled": {
+			Messages: []config.TemplateMessage{{Role: "user", Content: "Hello"}},
+		},
+	}
+
+	ctx := context.Background()
+	q := db.New(newTestDB(t))
+	s := NewService(q)
+
+	session, err := s.CreateFromTemplate(ctx, "review")
+	require.NoError(t, err)
+	assert.Equal(t, "Code review", session.Title)
+	assert.Equal(t, int64(2), session.MessageCount)
+
+	msgs, err := message.NewService(q).List(ctx, session.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, message.User, msgs[0].Role)
+	assert.Equal(t, "You are reviewing Go code. Be terse.", msgs[0].Content().Text)
+	assert.Equal(t, message.Assistant, msgs[1].Role)
+	assert.Equal(t, message.FinishReasonEndTurn, msgs[1].FinishReason())
+
+	session, err = s.CreateFromTemplate(ctx, "untitled")
+	require.NoError(t, err)
+	assert.Equal(t, "untitled", session.Title)
+
+	_, err = s.CreateFromTemplate(ctx, "missing")
+	assert.Error(t, err)
+}
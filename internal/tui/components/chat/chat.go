@@ -2,7 +2,6 @@ package chat
 
 import (
 	"fmt"
-	"sort"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
@@ -49,19 +48,11 @@ func lspsConfigured(width int) string {
 		Bold(true).
 		Render(title)
 
-	// Get LSP names and sort them for consistent ordering
-	var lspNames []string
-	for name := range cfg.LSP {
-		lspNames = append(lspNames, name)
-	}
-	sort.Strings(lspNames)
-
 	var lspViews []string
-	for _, name := range lspNames {
-		lsp := cfg.LSP[name]
+	for _, lsp := range cfg.LSPServers() {
 		lspName := baseStyle.
 			Foreground(t.Text()).
-			Render(fmt.Sprintf("• %s", name))
+			Render(fmt.Sprintf("• %s", lsp.Name))
 
 		cmd := lsp.Command
 		cmd = ansi.Truncate(cmd, width-lipgloss.Width(lspName)-3, "…")
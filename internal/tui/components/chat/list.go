@@ -160,6 +160,23 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 		}
+	case pubsub.Event[message.MessageDelta]:
+		if msg.Payload.SessionID != m.session.ID {
+			break
+		}
+		for i := range m.messages {
+			if m.messages[i].ID == msg.Payload.MessageID {
+				if !msg.Payload.Apply(&m.messages[i]) {
+					break
+				}
+				delete(m.cachedContent, msg.Payload.MessageID)
+				m.renderView()
+				if i == len(m.messages)-1 {
+					m.viewport.GotoBottom()
+				}
+				break
+			}
+		}
 	}
 
 	spinner, cmd := m.spinner.Update(msg)
@@ -442,6 +442,12 @@ func renderToolResponse(toolCall message.ToolCall, response message.ToolResult,
 	t := theme.CurrentTheme()
 	baseStyle := styles.BaseStyle()
 
+	if response.Canceled {
+		return baseStyle.
+			Width(width).
+			Foreground(t.TextMuted()).
+			Render("Canceled")
+	}
 	if response.IsError {
 		errContent := fmt.Sprintf("Error: %s", strings.ReplaceAll(response.Content, "\n", " "))
 		errContent = ansi.Truncate(errContent, width-1, "...")
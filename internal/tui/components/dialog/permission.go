@@ -225,8 +225,8 @@ func (p *permissionDialogCmp) renderHeader() string {
 		baseStyle.Render(strings.Repeat(" ", p.width)),
 	}
 
-	// Add tool-specific header information
-	switch p.permission.ToolName {
+	// Add tool-specific header information, which a batch doesn't have
+	switch p.toolName() {
 	case tools.BashToolName:
 		headerParts = append(headerParts, baseStyle.Foreground(t.TextMuted()).Width(p.width).Bold(true).Render("Command"))
 	case tools.EditToolName:
@@ -267,6 +267,15 @@ func (p *permissionDialogCmp) renderHeader() string {
 	return lipgloss.NewStyle().Background(t.Background()).Render(lipgloss.JoinVertical(lipgloss.Left, headerParts...))
 }
 
+// toolName is the tool asking for permission, or empty for a batch of
+// requests, which is shown request by request.
+func (p *permissionDialogCmp) toolName() string {
+	if _, ok := p.permission.Params.(permission.BatchPermissionParams); ok {
+		return ""
+	}
+	return p.permission.ToolName
+}
+
 func (p *permissionDialogCmp) renderBashContent() string {
 	t := theme.CurrentTheme()
 	baseStyle := styles.BaseStyle()
@@ -350,6 +359,52 @@ func (p *permissionDialogCmp) renderFetchContent() string {
 	return ""
 }
 
+// renderBatchContent shows each request of a batch under its description,
+// with the diff of the change for requests to change a file.
+func (p *permissionDialogCmp) renderBatchContent() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	if pr, ok := p.permission.Params.(permission.BatchPermissionParams); ok {
+		// Use the cache for diff rendering
+		content := p.GetOrSetDiff(p.permission.ID, func() (string, error) {
+			sections := make([]string, 0, 2*len(pr.Requests))
+			for _, request := range pr.Requests {
+				sections = append(sections, baseStyle.
+					Foreground(t.TextMuted()).
+					Width(p.contentViewPort.Width).
+					Bold(true).
+					Render(request.Description))
+				if requestDiff := batchRequestDiff(request); requestDiff != "" {
+					formatted, err := diff.FormatDiff(requestDiff, diff.WithTotalWidth(p.contentViewPort.Width))
+					if err != nil {
+						return "", err
+					}
+					sections = append(sections, formatted)
+				}
+				sections = append(sections, baseStyle.Render(strings.Repeat(" ", p.contentViewPort.Width)))
+			}
+			return lipgloss.JoinVertical(lipgloss.Left, sections...), nil
+		})
+
+		p.contentViewPort.SetContent(content)
+		return p.styleViewport()
+	}
+	return ""
+}
+
+// batchRequestDiff is the diff of a batched request to change a file, or
+// empty for other requests.
+func batchRequestDiff(request permission.PermissionRequest) string {
+	switch pr := request.Params.(type) {
+	case tools.EditPermissionsParams:
+		return pr.Diff
+	case tools.WritePermissionsParams:
+		return pr.Diff
+	}
+	return ""
+}
+
 func (p *permissionDialogCmp) renderDefaultContent() string {
 	t := theme.CurrentTheme()
 	baseStyle := styles.BaseStyle()
@@ -403,7 +458,9 @@ func (p *permissionDialogCmp) render() string {
 
 	// Render content based on tool type
 	var contentFinal string
-	switch p.permission.ToolName {
+	switch p.toolName() {
+	case "":
+		contentFinal = p.renderBatchContent()
 	case tools.BashToolName:
 		contentFinal = p.renderBashContent()
 	case tools.EditToolName:
@@ -452,7 +509,10 @@ func (p *permissionDialogCmp) SetSize() tea.Cmd {
 	if p.permission.ID == "" {
 		return nil
 	}
-	switch p.permission.ToolName {
+	switch p.toolName() {
+	case "":
+		p.width = int(float64(p.windowSize.Width) * 0.8)
+		p.height = int(float64(p.windowSize.Height) * 0.8)
 	case tools.BashToolName:
 		p.width = int(float64(p.windowSize.Width) * 0.4)
 		p.height = int(float64(p.windowSize.Height) * 0.3)
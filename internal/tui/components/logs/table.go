@@ -2,6 +2,7 @@ package logs
 
 import (
 	"encoding/json"
+	"fmt"
 	"slices"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -106,11 +107,15 @@ func (i *tableCmp) setRows() {
 	for _, log := range logs {
 		bm, _ := json.Marshal(log.Attributes)
 
+		message := log.Message
+		if log.Count > 1 {
+			message = fmt.Sprintf("%s (x%d)", message, log.Count)
+		}
 		row := table.Row{
 			log.ID,
 			log.Time.Format("15:04:05"),
 			log.Level,
-			log.Message,
+			message,
 			string(bm),
 		}
 		rows = append(rows, row)
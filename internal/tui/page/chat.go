@@ -155,7 +155,7 @@ func (p *chatPage) clearSidebar() tea.Cmd {
 func (p *chatPage) sendMessage(text string, attachments []message.Attachment) tea.Cmd {
 	var cmds []tea.Cmd
 	if p.session.ID == "" {
-		session, err := p.app.Sessions.Create(context.Background(), "New Session")
+		session, err := p.app.Sessions.CreateForPrompt(context.Background(), "New Session", text)
 		if err != nil {
 			return util.ReportError(err)
 		}
@@ -273,6 +273,11 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// Permission
 	case pubsub.Event[permission.PermissionRequest]:
+		if msg.Type == pubsub.DeletedEvent {
+			// the request was withdrawn before it was answered
+			a.showPermissions = false
+			return a, nil
+		}
 		a.showPermissions = true
 		return a, a.permissions.SetPermissions(msg.Payload)
 	case dialog.PermissionResponseMsg:
@@ -333,11 +338,14 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			a.isCompacting = false
 			return a, util.ReportInfo("Session summarization complete")
 		} else if payload.Done && payload.Type == agent.AgentEventTypeResponse && a.selectedSession.ID != "" {
-			model := a.app.CoderAgent.Model()
-			contextWindow := model.ContextWindow
-			tokens := a.selectedSession.CompletionTokens + a.selectedSession.PromptTokens
-			if (tokens >= int64(float64(contextWindow)*0.95)) && config.Get().AutoCompact {
-				return a, util.CmdHandler(startCompactSessionMsg{})
+			if config.Get().AutoCompact {
+				shouldCompact, err := a.app.Sessions.ShouldCompact(context.Background(), a.selectedSession.ID, a.app.CoderAgent.Model())
+				if err != nil {
+					return a, util.ReportError(err)
+				}
+				if shouldCompact {
+					return a, util.CmdHandler(startCompactSessionMsg{})
+				}
 			}
 		}
 		// Continue listening for events
@@ -477,7 +485,7 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, keys.SwitchSession):
 			if a.currentPage == page.ChatPage && !a.showQuit && !a.showPermissions && !a.showCommandDialog {
 				// Load sessions and show the dialog
-				sessions, err := a.app.Sessions.List(context.Background())
+				sessions, err := a.app.Sessions.ListRecent(context.Background(), 0)
 				if err != nil {
 					return a, util.ReportError(err)
 				}
@@ -0,0 +1,225 @@
+package version
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// checkTimeout bounds the release lookup so it never holds up startup.
+	checkTimeout = 3 * time.Second
+	// cacheTTL is how long a looked up release is reused before asking again.
+	cacheTTL = 6 * time.Hour
+)
+
+var (
+	latestReleaseURL = "https://api.github.com/repos/opencode-ai/opencode/releases/latest"
+
+	// cacheFile returns where the last looked up release is remembered across
+	// invocations. An empty path disables the on-disk cache.
+	cacheFile = func() string {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return ""
+		}
+		return filepath.Join(dir, "opencode", "latest-release.json")
+	}
+
+	cacheMu sync.Mutex
+)
+
+type latestCache struct {
+	Tag       string    `json:"tag"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// CheckLatest looks up the newest published release and reports whether it is
+// newer than the running Version. The lookup is cached for a few hours and
+// gives up after a short timeout; callers should treat an error as "unknown"
+// rather than a reason to stop.
+func CheckLatest(ctx context.Context) (latest string, hasUpdate bool, err error) {
+	latest, err = latestRelease(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	if Version == "unknown" {
+		return latest, false, fmt.Errorf("current version is unknown")
+	}
+	cmp, err := compareVersions(latest, Version)
+	if err != nil {
+		return latest, false, err
+	}
+	return latest, cmp > 0, nil
+}
+
+func latestRelease(ctx context.Context) (string, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	path := cacheFile()
+	if cached, ok := readCache(path); ok {
+		return cached, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestReleaseURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to check latest release: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to check latest release: %s", resp.Status)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", fmt.Errorf("failed to decode latest release: %w", err)
+	}
+	if release.TagName == "" {
+		return "", fmt.Errorf("latest release has no tag")
+	}
+
+	writeCache(path, release.TagName)
+	return release.TagName, nil
+}
+
+func readCache(path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	var cached latestCache
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Tag == "" {
+		return "", false
+	}
+	if time.Since(cached.CheckedAt) > cacheTTL {
+		return "", false
+	}
+	return cached.Tag, true
+}
+
+// writeCache remembers the tag on a best-effort basis; failing to cache only
+// means the next invocation asks again.
+func writeCache(path, tag string) {
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(latestCache{Tag: tag, CheckedAt: time.Now()})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, data, 0o644)
+}
+
+// compareVersions compares two semantic versions, with or without a leading
+// "v", returning -1, 0 or 1. Build metadata is ignored and a pre-release sorts
+// before the release it precedes.
+func compareVersions(a, b string) (int, error) {
+	av, apre, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	bv, bpre, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range av {
+		if av[i] != bv[i] {
+			if av[i] < bv[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+
+	switch {
+	case apre == bpre:
+		return 0, nil
+	case apre == "":
+		return 1, nil
+	case bpre == "":
+		return -1, nil
+	}
+	return comparePrerelease(apre, bpre), nil
+}
+
+func parseVersion(v string) ([3]int, string, error) {
+	var parts [3]int
+	s := strings.TrimPrefix(strings.TrimSpace(v), "v")
+	s, _, _ = strings.Cut(s, "+")
+	s, pre, _ := strings.Cut(s, "-")
+
+	fields := strings.Split(s, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, "", fmt.Errorf("invalid version %q", v)
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, "", fmt.Errorf("invalid version %q", v)
+		}
+		parts[i] = n
+	}
+	return parts, pre, nil
+}
+
+// comparePrerelease orders dot separated pre-release identifiers, comparing
+// numeric identifiers numerically and everything else lexically.
+func comparePrerelease(a, b string) int {
+	aids := strings.Split(a, ".")
+	bids := strings.Split(b, ".")
+	for i := 0; i < len(aids) && i < len(bids); i++ {
+		an, aerr := strconv.Atoi(aids[i])
+		bn, berr := strconv.Atoi(bids[i])
+		switch {
+		case aerr == nil && berr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case aerr == nil:
+			return -1
+		case berr == nil:
+			return 1
+		default:
+			if c := strings.Compare(aids[i], bids[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(aids) < len(bids):
+		return -1
+	case len(aids) > len(bids):
+		return 1
+	}
+	return 0
+}
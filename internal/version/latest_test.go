@@ -0,0 +1,73 @@
+package version
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "1.2.3", 0},
+		{"v1.2.4", "v1.2.3", 1},
+		{"v1.10.0", "v1.9.9", 1},
+		{"v0.9.0", "v1.0.0", -1},
+		{"v1.0.0", "v1.0.0-rc.1", 1},
+		{"v1.0.0-rc.2", "v1.0.0-rc.10", -1},
+		{"v1.0.0-alpha", "v1.0.0-beta", -1},
+		{"v1.0.0+build.5", "v1.0.0", 0},
+	}
+	for _, tt := range tests {
+		got, err := compareVersions(tt.a, tt.b)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, "%s vs %s", tt.a, tt.b)
+	}
+
+	_, err := compareVersions("latest", "v1.0.0")
+	assert.Error(t, err)
+}
+
+func TestCheckLatest(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(`{"tag_name": "v0.2.0"}`))
+	}))
+	defer server.Close()
+
+	cache := filepath.Join(t.TempDir(), "latest-release.json")
+	originalURL, originalCache, originalVersion := latestReleaseURL, cacheFile, Version
+	t.Cleanup(func() {
+		latestReleaseURL, cacheFile, Version = originalURL, originalCache, originalVersion
+	})
+	latestReleaseURL = server.URL
+	cacheFile = func() string { return cache }
+
+	Version = "v0.1.5"
+	latest, hasUpdate, err := CheckLatest(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "v0.2.0", latest)
+	assert.True(t, hasUpdate)
+
+	Version = "v0.2.0"
+	_, hasUpdate, err = CheckLatest(context.Background())
+	require.NoError(t, err)
+	assert.False(t, hasUpdate)
+	assert.Equal(t, int32(1), hits.Load(), "second check should be served from the cache")
+
+	Version = "unknown"
+	_, hasUpdate, err = CheckLatest(context.Background())
+	assert.Error(t, err)
+	assert.False(t, hasUpdate)
+}
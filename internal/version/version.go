@@ -1,9 +1,38 @@
 package version
 
-import "runtime/debug"
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
 
 // Build-time parameters set via -ldflags
-var Version = "unknown"
+var (
+	Version   = "unknown"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// BuildInfo describes the running binary.
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	BuildDate string
+}
+
+// String formats the build info as "v1.2.3 (abc1234, 2024-01-02)".
+func (b BuildInfo) String() string {
+	return fmt.Sprintf("%s (%s, %s)", b.Version, b.Commit, b.BuildDate)
+}
+
+// Info returns the version, commit and build date of the running binary.
+func Info() BuildInfo {
+	return BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+	}
+}
 
 // A user may install pug using `go install github.com/opencode-ai/opencode@latest`.
 // without -ldflags, in which case the version above is unset. As a workaround
@@ -15,6 +44,25 @@ func init() {
 		// < go v1.18
 		return
 	}
+	applyBuildInfo(info)
+}
+
+func applyBuildInfo(info *debug.BuildInfo) {
+	// The VCS settings are stamped by `go build` inside a checkout, so they
+	// fill in whatever -ldflags left unset.
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if Commit == "unknown" && setting.Value != "" {
+				Commit = shortCommit(setting.Value)
+			}
+		case "vcs.time":
+			if BuildDate == "unknown" && setting.Value != "" {
+				BuildDate = shortDate(setting.Value)
+			}
+		}
+	}
+
 	mainVersion := info.Main.Version
 	if mainVersion == "" || mainVersion == "(devel)" {
 		// bin not built using `go install`
@@ -23,3 +71,18 @@ func init() {
 	// bin built using `go install`
 	Version = mainVersion
 }
+
+func shortCommit(revision string) string {
+	if len(revision) > 7 {
+		return revision[:7]
+	}
+	return revision
+}
+
+func shortDate(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return t.UTC().Format(time.DateOnly)
+}
@@ -0,0 +1,44 @@
+package version
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfo(t *testing.T) {
+	originalVersion, originalCommit, originalDate := Version, Commit, BuildDate
+	t.Cleanup(func() {
+		Version, Commit, BuildDate = originalVersion, originalCommit, originalDate
+	})
+
+	t.Run("reads vcs settings", func(t *testing.T) {
+		Version, Commit, BuildDate = "unknown", "unknown", "unknown"
+		applyBuildInfo(&debug.BuildInfo{
+			Main: debug.Module{Version: "v1.2.3"},
+			Settings: []debug.BuildSetting{
+				{Key: "vcs.revision", Value: "abc1234def5678"},
+				{Key: "vcs.time", Value: "2024-01-02T15:04:05Z"},
+			},
+		})
+		assert.Equal(t, "v1.2.3 (abc1234, 2024-01-02)", Info().String())
+	})
+
+	t.Run("keeps ldflags values", func(t *testing.T) {
+		Version, Commit, BuildDate = "v2.0.0", "fedcba9", "2025-05-05"
+		applyBuildInfo(&debug.BuildInfo{
+			Main: debug.Module{Version: "(devel)"},
+			Settings: []debug.BuildSetting{
+				{Key: "vcs.revision", Value: "abc1234def5678"},
+			},
+		})
+		assert.Equal(t, BuildInfo{Version: "v2.0.0", Commit: "fedcba9", BuildDate: "2025-05-05"}, Info())
+	})
+
+	t.Run("falls back to unknown", func(t *testing.T) {
+		Version, Commit, BuildDate = "unknown", "unknown", "unknown"
+		applyBuildInfo(&debug.BuildInfo{})
+		assert.Equal(t, "unknown (unknown, unknown)", Info().String())
+	})
+}